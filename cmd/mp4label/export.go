@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// 运行 export 子命令：将标注文件导出为 WebVTT 章节或 SRT 字幕
+func runExport() {
+	cmd := flag.NewFlagSet("export", flag.ExitOnError)
+	format := cmd.String("format", annotation.ChapterFormatWebVTT, "导出格式 (vtt|srt)")
+	videoPath := cmd.String("video", "", "对应的视频文件，用于读取时长（最后一个章节持续到视频结束）")
+	duration := cmd.Duration("duration", 0, "视频时长（如 3m25s），未指定 -video 时使用")
+	output := cmd.String("o", "", "输出文件（默认输出到标准输出）")
+	cmd.Parse(os.Args[2:])
+
+	if cmd.NArg() != 1 {
+		fmt.Println("使用方式:")
+		fmt.Println("  mp4label export [-format vtt|srt] [-video clip.mp4 | -duration 3m25s] [-o out.vtt] <标注文件>")
+		os.Exit(1)
+	}
+
+	ann, err := annotation.ParseFile(cmd.Arg(0))
+	if err != nil {
+		log.Fatalf("读取标注失败: %v", err)
+	}
+
+	videoDuration := *duration
+	if *videoPath != "" {
+		d, err := video.ReadDuration(*videoPath)
+		if err != nil {
+			log.Fatalf("读取视频时长失败: %v", err)
+		}
+		videoDuration = d
+	}
+
+	if err := exportChapters(*output, ann, *format, videoDuration.Truncate(time.Millisecond)); err != nil {
+		log.Fatalf("导出失败: %v", err)
+	}
+}
+
+// exportChapters 将章节写入 output（为空时输出到标准输出）；失败时删除写了一半的输出文件
+func exportChapters(output string, ann *annotation.Annotation, format string, videoDuration time.Duration) error {
+	if output == "" {
+		return annotation.EncodeChapters(os.Stdout, ann, format, videoDuration)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	err = annotation.EncodeChapters(file, ann, format, videoDuration)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+	}
+	return err
+}
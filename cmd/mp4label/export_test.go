@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+func TestExportChapters(t *testing.T) {
+	ann, err := annotation.ParseLines([]string{"Title", "", "1) 00:01 first", "2) 00:05 second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	output := filepath.Join(dir, "intro.vtt")
+	if err := exportChapters(output, ann, annotation.ChapterFormatWebVTT, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "WEBVTT") || !strings.Contains(string(data), "second") {
+		t.Errorf("exported chapters = %q", data)
+	}
+
+	// 导出失败时不留下不完整的输出文件
+	output = filepath.Join(dir, "intro.txt")
+	if err := exportChapters(output, ann, "txt", 10*time.Second); err == nil {
+		t.Error("export with an unsupported format succeeded")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("output file after a failed export: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// filterFlag 收集可重复的 -filter 参数，按出现顺序求值（语义同任务文件中的规则，见 video.Filter）
+type filterFlag []string
+
+func (f *filterFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *filterFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// addFilterFlag 为子命令注册 -filter 参数
+func addFilterFlag(cmd *flag.FlagSet) *filterFlag {
+	f := &filterFlag{}
+	cmd.Var(f, "filter", "包含/排除规则，可重复，如 -filter '2026-09-*/**' -filter '!*_test'（! 排除，re: 正则，glob: 前缀可省略）")
+	return f
+}
+
+// parse 解析规则，无效时退出
+func (f *filterFlag) parse() *video.Filter {
+	filter, err := video.ParseFilter(*f)
+	if err != nil {
+		log.Fatalf("无效的 -filter: %v", err)
+	}
+	return filter
+}
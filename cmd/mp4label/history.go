@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/config"
+)
+
+// 运行 history 子命令：查看和清理输出目录中的标注修订历史
+func runHistory() {
+	if len(os.Args) < 3 {
+		printHistoryUsage()
+		os.Exit(2)
+	}
+
+	switch os.Args[2] {
+	case "list":
+		runHistoryList()
+	case "prune":
+		runHistoryPrune()
+	default:
+		fmt.Printf("未知的 history 子命令: %s\n\n", os.Args[2])
+		printHistoryUsage()
+		os.Exit(2)
+	}
+}
+
+// 打印 history 子命令的使用说明
+func printHistoryUsage() {
+	fmt.Println("使用方式:")
+	fmt.Println("  mp4label history list [-output 输出目录] <标注 key>")
+	fmt.Println("  mp4label history prune [-output 输出目录] (-keep 个数 | -older-than 时长) [-filter 规则]... [-dry-run]")
+}
+
+// 运行 history list：列出一个标注的所有修订
+func runHistoryList() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	cmd := flag.NewFlagSet("history list", flag.ExitOnError)
+	outputDir := cmd.String("output", cfg.OutputDir, "输出（标注）目录")
+	cmd.Parse(os.Args[3:])
+	if *outputDir == "" || cmd.NArg() != 1 {
+		printHistoryUsage()
+		os.Exit(2)
+	}
+	key := strings.TrimSuffix(cmd.Arg(0), "/")
+	if !annotation.ValidKey(key) {
+		log.Fatalf("无效的标注 key: %s", key)
+	}
+
+	revisions, err := annotation.ListRevisions(annotation.HistoryPath(*outputDir, key))
+	if err != nil {
+		log.Fatalf("读取修订历史失败: %v", err)
+	}
+	for _, r := range revisions {
+		annotator := r.Annotator
+		if annotator == "" {
+			annotator = "-"
+		}
+		action := r.Action
+		if r.RevertedFrom > 0 {
+			action = fmt.Sprintf("%s -> %d", action, r.RevertedFrom)
+		}
+		fmt.Printf("%6d  %s  %-12s %s\n", r.Rev, r.Time.Local().Format("2006-01-02 15:04:05"), action, annotator)
+	}
+	fmt.Printf("%d 个修订\n", len(revisions))
+}
+
+// 运行 history prune：删除超出保留数量或早于指定时间的修订；两者都指定时只删除同时满足的修订
+// 每个标注最新的修订总是保留
+func runHistoryPrune() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	cmd := flag.NewFlagSet("history prune", flag.ExitOnError)
+	outputDir := cmd.String("output", cfg.OutputDir, "输出（标注）目录")
+	keep := cmd.Int("keep", 0, "每个标注保留最新的修订个数")
+	olderThan := cmd.String("older-than", "", "只删除早于该时长的修订，如 720h、30d")
+	dryRun := cmd.Bool("dry-run", false, "只显示将要删除的修订，不删除文件")
+	filter := addFilterFlag(cmd)
+	cmd.Parse(os.Args[3:])
+
+	if *outputDir == "" || *keep < 0 || (*keep == 0 && *olderThan == "") || cmd.NArg() != 0 {
+		printHistoryUsage()
+		os.Exit(2)
+	}
+	opts := annotation.PruneOptions{Keep: *keep, DryRun: *dryRun}
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			log.Fatalf("无效的 -older-than: %v", err)
+		}
+		opts.Before = time.Now().Add(-age)
+	}
+	rules := filter.parse()
+
+	keys, err := annotation.HistoryKeys(*outputDir)
+	if err != nil {
+		log.Fatalf("读取修订历史失败: %v", err)
+	}
+	matched, total := 0, 0
+	for _, key := range keys {
+		if !rules.Match(key, key) {
+			continue
+		}
+		matched++
+		removed, err := annotation.PruneRevisions(annotation.HistoryPath(*outputDir, key), opts)
+		if len(removed) > 0 {
+			fmt.Printf("%s: %d 个修订 (%d-%d)\n", key, len(removed), removed[0], removed[len(removed)-1])
+		}
+		total += len(removed)
+		if err != nil {
+			log.Fatalf("清理 %s 失败: %v", key, err)
+		}
+	}
+
+	verb := "已删除"
+	if *dryRun {
+		verb = "待删除"
+	}
+	fmt.Printf("%d 个标注，%s %d 个修订\n", matched, verb, total)
+}
+
+// parseAge 解析时长，除 time.ParseDuration 支持的单位外还支持天（如 30d）
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
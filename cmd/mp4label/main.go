@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/xd/mp4label/pkg/server"
 )
@@ -27,6 +31,20 @@ func main() {
 	switch os.Args[1] {
 	case "web":
 		runWebServer()
+	case "manifest":
+		runManifest()
+	case "export":
+		runExport()
+	case "validate":
+		runValidate()
+	case "migrate":
+		runMigrate()
+	case "tasks":
+		runTasks()
+	case "users":
+		runUsers()
+	case "history":
+		runHistory()
 	case "version", "--version", "-v":
 		printVersion()
 	case "help", "--help", "-h":
@@ -50,6 +68,14 @@ func printUsage() {
 	fmt.Printf("版本: %s\n\n", version)
 	fmt.Println("使用方式:")
 	fmt.Println("  mp4label web [选项]    启动 Web 服务器")
+	fmt.Println("  mp4label manifest ...  标注目录与 JSONL 清单互相转换")
+	fmt.Println("  mp4label export ...    导出 WebVTT 章节 / SRT 字幕")
+	fmt.Println("  mp4label validate ...  批量校验标注目录，有错误时返回非零状态")
+	fmt.Println("  mp4label migrate ...   将平铺的旧标注文件迁移到与视频目录相同的结构")
+	fmt.Println("  mp4label tasks check   检查任务文件：缺失、重复、大小写不一致的视频名称")
+	fmt.Println("  mp4label tasks split   将视频拆分为多个任务文件，按数量或时长均衡分配给标注人员")
+	fmt.Println("  mp4label users ...     管理多用户模式的本地用户")
+	fmt.Println("  mp4label history ...   查看和清理标注的修订历史")
 	fmt.Println("  mp4label version       显示版本信息")
 	fmt.Println("  mp4label help          显示此帮助信息")
 	fmt.Println()
@@ -60,6 +86,15 @@ func printUsage() {
 	fmt.Println("  mp4label web           # 在默认端口 8080 启动")
 	fmt.Println("  mp4label web -port 3000  # 在端口 3000 启动")
 	fmt.Println("  mp4label version       # 显示版本")
+	fmt.Println("  mp4label manifest export -dir ./output -o dataset.jsonl")
+	fmt.Println("  mp4label manifest import -dir ./output -format json dataset.jsonl")
+	fmt.Println("  mp4label export -format srt -video clip.mp4 -o clip.srt output/clip.txt")
+	fmt.Println("  mp4label validate -format json ./output ./pre-annotations")
+	fmt.Println("  mp4label migrate -dry-run")
+	fmt.Println("  mp4label tasks check -format json task.csv")
+	fmt.Println("  mp4label tasks split -assignees alice,bob,carol -by duration -overlap 10 -unannotated -o tasks")
+	fmt.Println("  echo 'secret-password' | mp4label users add -name Alice -admin alice")
+	fmt.Println("  mp4label history prune -keep 20 -older-than 30d")
 }
 
 // 运行 Web 服务器
@@ -83,8 +118,23 @@ func runWebServer() {
 	fmt.Println("按 Ctrl+C 停止服务器")
 	fmt.Println()
 
-	// 启动服务器
-	if err := srv.Start(*port); err != nil {
+	// 启动服务器，收到 Ctrl+C 或 SIGTERM 时等待正在处理的请求完成，再提交等待中的 git 修改
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Start(*port) }()
+	select {
+	case err := <-errc:
 		log.Fatalf("服务器启动失败: %v", err)
+	case <-ctx.Done():
 	}
+	stop()
+
+	log.Printf("正在停止服务器...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("等待请求完成超时: %v", err)
+	}
+	srv.Close()
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+// 运行 manifest 子命令：在标注目录与 JSONL 清单之间转换
+func runManifest() {
+	if len(os.Args) < 3 {
+		printManifestUsage()
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "export":
+		runManifestExport(os.Args[3:])
+	case "import":
+		runManifestImport(os.Args[3:])
+	default:
+		fmt.Printf("未知 manifest 命令: %s\n\n", os.Args[2])
+		printManifestUsage()
+		os.Exit(1)
+	}
+}
+
+func printManifestUsage() {
+	fmt.Println("使用方式:")
+	fmt.Println("  mp4label manifest export -dir <标注目录> [-filter 规则]... [-o dataset.jsonl]")
+	fmt.Println("  mp4label manifest import -dir <输出目录> [-format txt|json] <dataset.jsonl>")
+}
+
+// 将目录中的全部标注导出为一个 JSONL 清单
+func runManifestExport(args []string) {
+	cmd := flag.NewFlagSet("manifest export", flag.ExitOnError)
+	dir := cmd.String("dir", "", "标注目录")
+	output := cmd.String("o", "", "输出文件（默认输出到标准输出）")
+	filterFlag := addFilterFlag(cmd)
+	cmd.Parse(args)
+	filter := filterFlag.parse()
+
+	if *dir == "" {
+		printManifestUsage()
+		os.Exit(1)
+	}
+
+	entries, err := annotation.CollectManifest(*dir)
+	if err != nil {
+		log.Fatalf("读取标注目录失败: %v", err)
+	}
+	if filter != nil {
+		kept := entries[:0]
+		for _, e := range entries {
+			if filter.Match(e.Stem, e.Stem) {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("创建输出文件失败: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := annotation.WriteManifest(w, entries); err != nil {
+		log.Fatalf("写入清单失败: %v", err)
+	}
+	if *output != "" {
+		fmt.Printf("已导出 %d 个标注到 %s\n", len(entries), *output)
+	}
+}
+
+// 将 JSONL 清单拆分为每个视频一个标注文件
+func runManifestImport(args []string) {
+	cmd := flag.NewFlagSet("manifest import", flag.ExitOnError)
+	dir := cmd.String("dir", "", "输出目录")
+	format := cmd.String("format", "txt", "标注文件格式 (txt|json)")
+	cmd.Parse(args)
+
+	if *dir == "" || cmd.NArg() != 1 {
+		printManifestUsage()
+		os.Exit(1)
+	}
+
+	file, err := os.Open(cmd.Arg(0))
+	if err != nil {
+		log.Fatalf("打开清单失败: %v", err)
+	}
+	defer file.Close()
+
+	entries, err := annotation.ReadManifest(file)
+	if err != nil {
+		log.Fatalf("读取清单失败: %v", err)
+	}
+
+	if err := annotation.SaveManifest(entries, *dir, *format); err != nil {
+		log.Fatalf("保存标注失败: %v", err)
+	}
+	fmt.Printf("已导入 %d 个标注到 %s\n", len(entries), *dir)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// 运行 migrate 子命令：将平铺在输出目录中的旧标注文件移动到镜像视频目录结构的位置
+// 默认使用配置文件中的视频目录和输出目录
+func runMigrate() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	cmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	videoDir := cmd.String("videos", cfg.VideoDir, "视频目录")
+	outputDir := cmd.String("output", cfg.OutputDir, "输出（标注）目录")
+	dryRun := cmd.Bool("dry-run", false, "只显示将要执行的操作，不移动文件")
+	filter := addFilterFlag(cmd)
+	cmd.Parse(os.Args[2:])
+
+	if *videoDir == "" || *outputDir == "" {
+		fmt.Println("使用方式:")
+		fmt.Println("  mp4label migrate [-videos 视频目录] [-output 输出目录] [-filter 规则]... [-dry-run]")
+		os.Exit(2)
+	}
+
+	// 扫描全部视频以正确判断同名冲突，规则只限定要迁移的视频
+	videos, err := video.ScanVideosWithOptions(*videoDir, "", cfg.ScanOptions())
+	if err != nil {
+		log.Fatalf("扫描视频目录失败: %v", err)
+	}
+
+	actions, err := video.MigrateFlatAnnotations(videos, *outputDir, filter.parse(), *dryRun)
+	for _, a := range actions {
+		switch {
+		case a.Skipped != "":
+			fmt.Printf("跳过 %s: %s\n", a.From, a.Skipped)
+		case *dryRun:
+			fmt.Printf("将移动 %s -> %s\n", a.From, a.To)
+		default:
+			fmt.Printf("已移动 %s -> %s\n", a.From, a.To)
+		}
+	}
+	if err != nil {
+		log.Fatalf("迁移失败: %v", err)
+	}
+
+	skipped := 0
+	for _, a := range actions {
+		if a.Skipped != "" {
+			skipped++
+		}
+	}
+	verb := "已迁移"
+	if *dryRun {
+		verb = "待迁移"
+	}
+	fmt.Printf("%d 个文件%s，%d 个跳过\n", len(actions)-skipped, verb, skipped)
+	if skipped > 0 {
+		os.Exit(1)
+	}
+}
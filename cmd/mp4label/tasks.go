@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// 运行 tasks 子命令：任务文件相关工具
+func runTasks() {
+	if len(os.Args) < 3 {
+		printTasksUsage()
+		os.Exit(2)
+	}
+
+	switch os.Args[2] {
+	case "check":
+		runTasksCheck()
+	case "split":
+		runTasksSplit()
+	default:
+		fmt.Printf("未知的 tasks 子命令: %s\n\n", os.Args[2])
+		printTasksUsage()
+		os.Exit(2)
+	}
+}
+
+// 打印 tasks 子命令的使用说明
+func printTasksUsage() {
+	fmt.Println("使用方式:")
+	fmt.Println("  mp4label tasks check [-videos 视频目录] [-filter 规则]... [-format text|json] [任务文件]")
+	fmt.Println("  mp4label tasks split (-n 份数 | -assignees 名单) [-by count|duration] [-seed 种子] [-overlap 百分比]")
+	fmt.Println("                       [-unannotated] [-task 任务文件] [-filter 规则]... [-format txt|csv|tsv|json] [-o 目录] [-prefix 前缀] [-force]")
+}
+
+// 运行 tasks check：列出任务文件中没有对应视频、重复以及大小写不一致的项，有问题时返回非零状态
+// 默认使用配置文件中的视频目录和任务文件
+func runTasksCheck() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	cmd := flag.NewFlagSet("tasks check", flag.ExitOnError)
+	videoDir := cmd.String("videos", cfg.VideoDir, "视频目录")
+	format := cmd.String("format", "text", "报告格式 (text|json)")
+	filter := addFilterFlag(cmd)
+	cmd.Parse(os.Args[3:])
+	filter.parse()
+
+	taskFile := cfg.TaskFile
+	if cmd.NArg() > 0 {
+		taskFile = cmd.Arg(0)
+	}
+	if *videoDir == "" || taskFile == "" || (*format != "text" && *format != "json") {
+		printTasksUsage()
+		os.Exit(2)
+	}
+
+	opts := cfg.ScanOptions()
+	opts.Filter = *filter
+	result, err := video.Scan(*videoDir, taskFile, opts)
+	if err != nil {
+		log.Fatalf("扫描失败: %v", err)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{
+			"task_file":     taskFile,
+			"videos":        len(result.Videos),
+			"task_warnings": result.TaskWarnings,
+		})
+	} else {
+		for _, w := range result.TaskWarnings {
+			fmt.Printf("%s:%d: %s: %s\n", taskFile, w.Line, w.Kind, w.Message)
+		}
+		fmt.Printf("匹配到 %d 个视频，%d 个问题\n", len(result.Videos), len(result.TaskWarnings))
+	}
+
+	if len(result.TaskWarnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// 运行 tasks split：将视频目录（可用任务文件和规则限定范围）拆分为多个任务文件，分配给多名标注人员
+// 相同的视频集合和种子总是得到相同的拆分结果；-overlap 指定同时分给两人的视频比例，用于计算标注一致性
+func runTasksSplit() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	cmd := flag.NewFlagSet("tasks split", flag.ExitOnError)
+	videoDir := cmd.String("videos", cfg.VideoDir, "视频目录")
+	outputDir := cmd.String("output", cfg.OutputDir, "输出（标注）目录，用于 -unannotated")
+	taskFile := cmd.String("task", "", "只拆分该任务文件中的视频，原有的优先级、截止日期和说明会保留")
+	parts := cmd.Int("n", 0, "拆分份数")
+	assignees := cmd.String("assignees", "", "标注人员，逗号分隔；份数为人数，写入 assignee 列并作为文件名")
+	by := cmd.String("by", video.SplitByCount, "均衡方式 (count|duration)")
+	seed := cmd.Int64("seed", 1, "随机种子")
+	overlap := cmd.Float64("overlap", 0, "同时分给两人的视频百分比（0-100）")
+	unannotated := cmd.Bool("unannotated", false, "只拆分还没有标注的视频")
+	format := cmd.String("format", "", "任务文件格式 (txt|csv|tsv|json)，默认有 -assignees 时为 csv，否则为 txt")
+	outDir := cmd.String("o", ".", "任务文件写入目录")
+	prefix := cmd.String("prefix", "task", "任务文件名前缀")
+	force := cmd.Bool("force", false, "覆盖已存在的任务文件")
+	filter := addFilterFlag(cmd)
+	cmd.Parse(os.Args[3:])
+	filter.parse()
+
+	var names []string
+	for _, name := range strings.Split(*assignees, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		if *parts != 0 && *parts != len(names) {
+			log.Fatalf("-n (%d) 与 -assignees 人数 (%d) 不一致", *parts, len(names))
+		}
+		*parts = len(names)
+	}
+	if *format == "" {
+		*format = "txt"
+		if len(names) > 0 {
+			*format = "csv"
+		}
+	}
+	if *videoDir == "" || *parts < 1 || (*by != video.SplitByCount && *by != video.SplitByDuration) ||
+		(*format != "txt" && *format != "csv" && *format != "tsv" && *format != "json") {
+		printTasksUsage()
+		os.Exit(2)
+	}
+	if *unannotated && *outputDir == "" {
+		log.Fatalf("-unannotated 需要输出目录（-output 或配置文件）")
+	}
+
+	// 文件名：有标注人员时为 前缀-姓名，否则为 前缀-序号
+	width := len(fmt.Sprint(*parts))
+	files := make([]string, *parts)
+	for i := range files {
+		name := fmt.Sprintf("%0*d", width, i+1)
+		if len(names) > 0 {
+			name = names[i]
+		}
+		files[i] = filepath.Join(*outDir, fmt.Sprintf("%s-%s.%s", *prefix, name, *format))
+		if _, err := os.Stat(files[i]); err == nil && !*force {
+			log.Fatalf("%s 已存在，使用 -force 覆盖", files[i])
+		}
+	}
+
+	opts := cfg.ScanOptions()
+	opts.Filter = *filter
+	var videos []video.VideoInfo
+	if *by == video.SplitByDuration {
+		// 时长与 Web 服务共用扫描索引，未变化的视频无需重新读取
+		var index *video.ScanIndex
+		if cacheDir, err := config.GetCacheDir(); err == nil {
+			index = video.LoadScanIndex(filepath.Join(cacheDir, "scan-index.json"))
+		} else {
+			index = video.LoadScanIndex("")
+		}
+		result, _, err := index.Scan(*videoDir, *taskFile, opts)
+		if err != nil {
+			log.Fatalf("扫描失败: %v", err)
+		}
+		videos = result.Videos
+	} else {
+		videos, err = video.ScanVideosWithOptions(*videoDir, *taskFile, opts)
+		if err != nil {
+			log.Fatalf("扫描失败: %v", err)
+		}
+	}
+
+	if *unannotated {
+		video.MatchAnnotations(videos, "", *outputDir)
+		pending := videos[:0]
+		for _, v := range videos {
+			if !v.HasAnnotation {
+				pending = append(pending, v)
+			}
+		}
+		videos = pending
+	}
+	for _, v := range videos {
+		if *by == video.SplitByDuration && v.ProbeError != "" {
+			fmt.Printf("警告: 无法读取 %s 的时长，按 0 计算: %s\n", v.RelPath, v.ProbeError)
+		}
+	}
+
+	result, err := video.SplitTasks(videos, video.SplitOptions{Parts: *parts, By: *by, Seed: *seed, Overlap: *overlap})
+	if err != nil {
+		log.Fatalf("拆分失败: %v", err)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("创建目录失败: %v", err)
+	}
+	for i, part := range result {
+		entries := make([]video.TaskEntry, len(part.Videos))
+		for j, v := range part.Videos {
+			entries[j] = video.TaskEntry{Video: v.Key, TaskInfo: v.TaskInfo}
+			if len(names) > 0 {
+				entries[j].Assignee = names[i]
+			}
+		}
+		if err := video.WriteTaskFile(files[i], entries); err != nil {
+			log.Fatalf("写入 %s 失败: %v", files[i], err)
+		}
+		fmt.Printf("%s: %d 个视频", files[i], len(part.Videos))
+		if *by == video.SplitByDuration {
+			fmt.Printf("，总时长 %s", (time.Duration(part.DurationMs) * time.Millisecond).Round(time.Second))
+		}
+		if part.Overlap > 0 {
+			fmt.Printf("，其中 %d 个重叠", part.Overlap)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("共拆分 %d 个视频为 %d 份\n", len(videos), len(result))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/config"
+)
+
+// 运行 users 子命令：管理多用户模式（auth.mode 为 local）的本地用户文件
+func runUsers() {
+	if len(os.Args) < 3 {
+		printUsersUsage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	path, err := cfg.Auth.UsersPath()
+	if err != nil {
+		log.Fatalf("获取用户文件路径失败: %v", err)
+	}
+	store, err := auth.LoadUserStore(path)
+	if err != nil {
+		log.Fatalf("加载用户文件失败: %v", err)
+	}
+
+	switch os.Args[2] {
+	case "list":
+		users := store.Users()
+		for _, u := range users {
+			role := "标注人员"
+			if u.Admin {
+				role = "管理员"
+			}
+			fmt.Printf("%-20s %-20s %s\n", u.ID, u.Name, role)
+		}
+		fmt.Printf("%d 个用户（%s）\n", len(users), path)
+		return
+	case "add", "update":
+		cmd := flag.NewFlagSet("users "+os.Args[2], flag.ExitOnError)
+		name := cmd.String("name", "", "显示名称")
+		admin := cmd.Bool("admin", false, "设为管理员（可以修改配置）")
+		cmd.Parse(os.Args[3:])
+		if cmd.NArg() != 1 {
+			printUsersUsage()
+			os.Exit(2)
+		}
+		id := cmd.Arg(0)
+
+		exists := false
+		for _, u := range store.Users() {
+			exists = exists || u.ID == id
+		}
+		if os.Args[2] == "add" && exists {
+			log.Fatalf("用户 %s 已存在，修改请使用 mp4label users update", id)
+		}
+		if os.Args[2] == "update" && !exists {
+			log.Fatalf("用户 %s 不存在", id)
+		}
+
+		// update 时密码留空表示不修改
+		password := readPassword(os.Args[2] == "update")
+		if err := store.SetUser(id, *name, password, *admin); err != nil {
+			log.Fatalf("保存用户失败: %v", err)
+		}
+	case "remove":
+		if len(os.Args) != 4 {
+			printUsersUsage()
+			os.Exit(2)
+		}
+		if err := store.Remove(os.Args[3]); err != nil {
+			log.Fatalf("删除用户失败: %v", err)
+		}
+	default:
+		fmt.Printf("未知的 users 子命令: %s\n\n", os.Args[2])
+		printUsersUsage()
+		os.Exit(2)
+	}
+
+	if err := store.Save(); err != nil {
+		log.Fatalf("保存用户文件失败: %v", err)
+	}
+	fmt.Printf("已更新 %s\n", path)
+	if cfg.Auth.Mode != config.AuthLocal {
+		fmt.Println(`提示: 配置文件中 auth.mode 不是 "local"，Web 服务不会使用这些用户`)
+	}
+}
+
+// 打印 users 子命令的使用说明
+func printUsersUsage() {
+	fmt.Println("使用方式:")
+	fmt.Println("  mp4label users list")
+	fmt.Println("  mp4label users add [-name 显示名称] [-admin] <登录名>      # 从标准输入读取密码")
+	fmt.Println("  mp4label users update [-name 显示名称] [-admin] <登录名>   # 密码留空表示不修改")
+	fmt.Println("  mp4label users remove <登录名>")
+}
+
+// readPassword 从标准输入读取一行密码（可以通过管道传入）
+func readPassword(optional bool) string {
+	if optional {
+		fmt.Fprint(os.Stderr, "新密码（留空不修改）: ")
+	} else {
+		fmt.Fprintf(os.Stderr, "密码（至少 %d 个字符）: ", auth.MinPasswordLength)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" && !optional {
+		log.Fatalf("读取密码失败: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// validateProblem 表示批量校验中发现的一个问题
+type validateProblem struct {
+	File     string              `json:"file"`
+	Line     int                 `json:"line,omitempty"`   // 0 表示文件级问题
+	Column   int                 `json:"column,omitempty"` // 0 表示整行
+	Severity annotation.Severity `json:"severity"`
+	Message  string              `json:"message"`
+}
+
+// validateSummary 表示批量校验的汇总结果
+type validateSummary struct {
+	Files    int               `json:"files"`
+	Errors   int               `json:"errors"`
+	Warnings int               `json:"warnings"`
+	Problems []validateProblem `json:"problems"`
+}
+
+func (s *validateSummary) add(p validateProblem) {
+	if p.Severity == annotation.SeverityError {
+		s.Errors++
+	} else {
+		s.Warnings++
+	}
+	s.Problems = append(s.Problems, p)
+}
+
+// 运行 validate 子命令：批量校验目录中的所有标注文件，存在错误时以非零状态退出
+func runValidate() {
+	cmd := flag.NewFlagSet("validate", flag.ExitOnError)
+	format := cmd.String("format", "text", "报告格式 (text|json)")
+	allowOverlap := cmd.Bool("allow-overlap", false, "允许带结束时间的步骤区间重叠")
+	strict := cmd.Bool("strict", false, "将警告视为错误")
+	order := cmd.String("order", "strict", "时间戳顺序规则 (strict|non-strict|off)")
+	orderSeverity := cmd.String("order-severity", "warning", "违反顺序规则的严重程度 (warning|error)")
+	minGap := cmd.Duration("min-gap", 0, "相邻步骤最小间隔（如 500ms），0 表示不检查")
+	minGapSeverity := cmd.String("min-gap-severity", "warning", "间隔过小的严重程度 (warning|error)")
+	duplicates := cmd.String("duplicates", "warning", "重复步骤描述的严重程度 (off|warning|error)")
+	pastEnd := cmd.String("past-end-severity", "error", "步骤超出视频时长的严重程度 (off|warning|error)，需配合 -videos")
+	sceneDistance := cmd.Duration("scene-distance", 0, "步骤与最近场景切换点的最大距离（如 2s），0 表示不检查，需配合 -videos")
+	sceneSeverity := cmd.String("scene-severity", "warning", "远离场景切换点的严重程度 (warning|error)")
+	videoDir := cmd.String("videos", "", "视频目录；提供时按同名视频的实际时长检查步骤时间")
+	videoExts := cmd.String("video-ext", strings.Join(video.DefaultExtensions, ","), "-videos 目录中扫描的视频扩展名，逗号分隔（如 .mp4,.mov）")
+	filterFlag := addFilterFlag(cmd)
+	cmd.Parse(os.Args[2:])
+	filter := filterFlag.parse()
+
+	cfg := config.Config{
+		AllowOverlappingSteps: *allowOverlap,
+		Validation: config.ValidationRules{
+			TimestampOrder:        *order,
+			OrderSeverity:         *orderSeverity,
+			MinGapMs:              int(minGap.Milliseconds()),
+			MinGapSeverity:        *minGapSeverity,
+			DuplicateDescriptions: *duplicates,
+			PastEndSeverity:       *pastEnd,
+			SceneChangeDistanceMs: int(sceneDistance.Milliseconds()),
+			SceneChangeSeverity:   *sceneSeverity,
+		},
+	}
+	rulesErr := cfg.Validation.Validate()
+
+	if cmd.NArg() == 0 || (*format != "text" && *format != "json") || rulesErr != nil {
+		if rulesErr != nil {
+			fmt.Printf("%v\n\n", rulesErr)
+		}
+		fmt.Println("使用方式:")
+		fmt.Println("  mp4label validate [-format text|json] [-allow-overlap] [-strict] [-videos 目录] [-filter 规则]... [规则选项] <目录或文件>...")
+		fmt.Println("规则选项: -order -order-severity -min-gap -min-gap-severity -duplicates -past-end-severity -scene-distance -scene-severity")
+		os.Exit(2)
+	}
+
+	opts := cfg.ValidateOptions()
+	summary := &validateSummary{Problems: []validateProblem{}}
+
+	// 关键帧与 Web 服务共用磁盘缓存
+	var keyframeCache *video.KeyframeCache
+	if cacheDir, err := config.GetCacheDir(); err == nil {
+		keyframeCache = video.NewKeyframeCache(filepath.Join(cacheDir, "keyframes"))
+	}
+
+	// 标注 key -> 视频路径，用于读取时长；没有同名冲突时也可按平铺的 stem 匹配
+	videoPaths := make(map[string]string)
+	if *videoDir != "" {
+		videos, err := video.ScanVideosWithOptions(*videoDir, "", video.ScanOptions{Extensions: strings.Split(*videoExts, ",")})
+		if err != nil {
+			log.Fatalf("扫描视频目录失败: %v", err)
+		}
+		for _, v := range videos {
+			for _, key := range v.AnnotationKeys() {
+				videoPaths[key] = v.Path
+			}
+		}
+	}
+
+	for _, root := range cmd.Args() {
+		files, err := collectAnnotationFiles(root)
+		if err != nil {
+			log.Fatalf("读取 %s 失败: %v", root, err)
+		}
+		for _, file := range files {
+			key := annotationKey(root, file)
+			if !filter.Match(key, key) {
+				continue
+			}
+			summary.Files++
+			fileOpts := opts
+			if videoPath, ok := videoPaths[key]; ok {
+				duration, err := video.ReadDuration(videoPath)
+				if err != nil {
+					summary.add(validateProblem{File: file, Severity: annotation.SeverityWarning, Message: fmt.Sprintf("cannot read duration of %s: %v", videoPath, err)})
+				}
+				fileOpts.VideoDuration = duration
+				if opts.SceneChangeDistance > 0 {
+					keyframes, err := keyframeCache.Get(videoPath)
+					if err != nil {
+						summary.add(validateProblem{File: file, Severity: annotation.SeverityWarning, Message: fmt.Sprintf("cannot read keyframes of %s: %v", videoPath, err)})
+					}
+					fileOpts.SceneChanges = video.SceneChanges(keyframes)
+				}
+			} else if *videoDir != "" {
+				summary.add(validateProblem{File: file, Severity: annotation.SeverityWarning, Message: "no matching video found"})
+			}
+			validateFile(file, fileOpts, summary)
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(summary)
+	} else {
+		for _, p := range summary.Problems {
+			fmt.Println(formatProblem(p))
+		}
+		fmt.Printf("%d 个文件，%d 个错误，%d 个警告\n", summary.Files, summary.Errors, summary.Warnings)
+	}
+
+	if summary.Errors > 0 || (*strict && summary.Warnings > 0) {
+		os.Exit(1)
+	}
+}
+
+// collectAnnotationFiles 递归收集目录中所有已注册格式的标注文件（跳过修订历史和 .git 目录）；root 为文件时直接返回
+func collectAnnotationFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel, err := filepath.Rel(root, path); err == nil && annotation.SkipDir(filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if annotation.IsAnnotationFile(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// annotationKey 返回标注文件相对 root 目录的 key（不含扩展名，以 / 分隔）
+func annotationKey(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == "." {
+		rel = filepath.Base(file)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+// validateFile 解析并校验单个文件，将发现的问题加入汇总
+func validateFile(file string, opts annotation.ValidateOptions, summary *validateSummary) {
+	ann, diags, err := annotation.ParseFileWithOptions(file, annotation.ParseOptions{})
+	for _, d := range diags {
+		summary.add(validateProblem{
+			File:     file,
+			Line:     d.Line,
+			Column:   d.Column,
+			Severity: d.Severity,
+			Message:  d.Reason,
+		})
+	}
+	if err != nil {
+		summary.add(validateProblem{File: file, Severity: annotation.SeverityError, Message: err.Error()})
+		return
+	}
+
+	report := annotation.Validate(ann, opts)
+	for _, issue := range append(report.Errors, report.Warnings...) {
+		p := validateProblem{File: file, Severity: issue.Severity, Message: issue.Message}
+		if issue.Index >= 0 {
+			p.Line = ann.Steps[issue.Index].Line
+			p.Message = fmt.Sprintf("step %d: %s", issue.Index+1, issue.Message)
+		}
+		summary.add(p)
+	}
+}
+
+// formatProblem 格式化为 file:line:column: severity: message
+func formatProblem(p validateProblem) string {
+	location := p.File
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, p.Line)
+		if p.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, p.Column)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s", location, p.Severity, p.Message)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+func TestValidateFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ok.txt":          "Title\n\n1) 00:01 a\n2) 00:02 b\n",
+		"course/bad.txt":  "Title\n\n1) 00:01 a\nx) 00:02 b\n2) 00:01 a\n",
+		"course/bad.json": `{"title":"","is_tutorial":true,"steps":[]}`,
+		"notes.md":        "ignored",
+		// 修订历史和 git 仓库中的文件不是标注
+		".mp4label/history/ok/notes.txt": "x) broken",
+		"course/.git/HEAD.txt":           "x) broken",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := collectAnnotationFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, file := range found {
+		keys = append(keys, annotationKey(dir, file))
+	}
+	if want := []string{"course/bad", "course/bad", "ok"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	if key := annotationKey(filepath.Join(dir, "ok.txt"), filepath.Join(dir, "ok.txt")); key != "ok" {
+		t.Errorf("annotationKey of a single file = %q, want ok", key)
+	}
+
+	summary := &validateSummary{Problems: []validateProblem{}}
+	for _, file := range found {
+		validateFile(file, annotation.DefaultValidateOptions(), summary)
+	}
+	var got []string
+	for _, p := range summary.Problems {
+		rel, _ := filepath.Rel(dir, p.File)
+		p.File = filepath.ToSlash(rel)
+		got = append(got, formatProblem(p))
+	}
+	want := []string{
+		"course/bad.json: error: tutorial title cannot be empty",
+		"course/bad.json: error: at least one step is required",
+		"course/bad.txt:4:1: error: missing step number, expected format 'N) mm:ss.SSS description'",
+		"course/bad.txt:5: warning: step 2: timestamp 00:01.000 duplicates step 1",
+		"course/bad.txt:5: warning: step 2: description duplicates step 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems =\n%q\nwant\n%q", got, want)
+	}
+	if summary.Errors != 3 || summary.Warnings != 2 {
+		t.Errorf("summary = %d errors, %d warnings, want 3 and 2", summary.Errors, summary.Warnings)
+	}
+}
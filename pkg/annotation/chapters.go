@@ -0,0 +1,105 @@
+package annotation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 章节导出格式
+const (
+	ChapterFormatWebVTT = "vtt"
+	ChapterFormatSRT    = "srt"
+)
+
+// ErrDurationRequired 表示最后一个步骤没有结束时间，且视频时长未知（视频不存在或无法读取）
+var ErrDurationRequired = errors.New("video duration is required to close the final step")
+
+// chapterCue 表示一个章节字幕条目
+type chapterCue struct {
+	Start, End time.Duration
+	Text       string
+}
+
+// chapterCues 以步骤区间生成字幕条目
+// 每个步骤从其开始时间持续到结束时间（显式结束时间或下一步骤的开始），最后一步持续到视频结束
+func chapterCues(ann *Annotation, videoDuration time.Duration) ([]chapterCue, error) {
+	if !ann.IsTutorial {
+		return nil, nil
+	}
+
+	segments := ann.Segments(videoDuration)
+	cues := make([]chapterCue, 0, len(segments))
+	for i, seg := range segments {
+		if seg.End <= seg.Start {
+			if i == len(segments)-1 && videoDuration <= 0 {
+				return nil, ErrDurationRequired
+			}
+			return nil, fmt.Errorf("step %d has an empty interval (%s-%s)", ann.Steps[i].Number, seg.Start, seg.End)
+		}
+		cues = append(cues, chapterCue{
+			Start: seg.Start.Duration(),
+			End:   seg.End.Duration(),
+			Text:  strings.TrimSpace(ann.Steps[i].Description),
+		})
+	}
+	return cues, nil
+}
+
+// EncodeWebVTT 将标注导出为 WebVTT 章节轨道
+func EncodeWebVTT(w io.Writer, ann *Annotation, videoDuration time.Duration) error {
+	cues, err := chapterCues(ann, videoDuration)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT")
+	if title := strings.TrimSpace(ann.Title); title != "" {
+		bw.WriteString(" - " + title)
+	}
+	bw.WriteString("\n")
+
+	for i, cue := range cues {
+		fmt.Fprintf(bw, "\n%d\n%s --> %s\n%s\n", i+1, formatCueTime(cue.Start, '.'), formatCueTime(cue.End, '.'), cue.Text)
+	}
+	return bw.Flush()
+}
+
+// EncodeSRT 将标注导出为 SRT 字幕文件
+func EncodeSRT(w io.Writer, ann *Annotation, videoDuration time.Duration) error {
+	cues, err := chapterCues(ann, videoDuration)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n", i+1, formatCueTime(cue.Start, ','), formatCueTime(cue.End, ','), cue.Text)
+	}
+	return bw.Flush()
+}
+
+// EncodeChapters 按格式名（vtt 或 srt）导出章节
+func EncodeChapters(w io.Writer, ann *Annotation, format string, videoDuration time.Duration) error {
+	switch strings.ToLower(format) {
+	case ChapterFormatWebVTT:
+		return EncodeWebVTT(w, ann, videoDuration)
+	case ChapterFormatSRT:
+		return EncodeSRT(w, ann, videoDuration)
+	default:
+		return fmt.Errorf("unsupported chapter format: %s", format)
+	}
+}
+
+// formatCueTime 格式化为 hh:mm:ss.ttt（WebVTT）或 hh:mm:ss,ttt（SRT）
+func formatCueTime(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, (ms%3600000)/60000, (ms%60000)/1000, sep, ms%1000)
+}
@@ -0,0 +1,74 @@
+package annotation
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeChapters(t *testing.T) {
+	ann, err := ParseLines([]string{"Title", "1) 00:01 first", "2) 00:05.250-00:08 second", "3) 59:59.500 third"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"vtt", "WEBVTT - Title\n\n" +
+			"1\n00:00:01.000 --> 00:00:05.250\nfirst\n\n" +
+			"2\n00:00:05.250 --> 00:00:08.000\nsecond\n\n" +
+			"3\n00:59:59.500 --> 01:00:00.000\nthird\n"},
+		{"SRT", "1\n00:00:01,000 --> 00:00:05,250\nfirst\n\n" +
+			"2\n00:00:05,250 --> 00:00:08,000\nsecond\n\n" +
+			"3\n00:59:59,500 --> 01:00:00,000\nthird\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeChapters(&buf, ann, tt.format, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeChaptersErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		format   string
+		duration time.Duration
+		want     string
+	}{
+		{"unknown format", []string{"Title", "1) 00:01 first"}, "ass", time.Minute, "unsupported chapter format"},
+		{"no duration", []string{"Title", "1) 00:01 first"}, "vtt", 0, "video duration is required"},
+		{"empty interval", []string{"Title", "1) 00:01 first", "2) 00:01 second"}, "srt", time.Minute, "step 1 has an empty interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ann, err := ParseLines(tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = EncodeChapters(&bytes.Buffer{}, ann, tt.format, tt.duration)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("EncodeChapters error = %v, want %q", err, tt.want)
+			}
+			if (tt.duration == 0) != errors.Is(err, ErrDurationRequired) {
+				t.Errorf("EncodeChapters error = %v, ErrDurationRequired only without a duration", err)
+			}
+		})
+	}
+
+	// 非教学视频导出空的章节文件
+	var buf bytes.Buffer
+	if err := EncodeWebVTT(&buf, &Annotation{}, 0); err != nil || buf.String() != "WEBVTT\n" {
+		t.Errorf("EncodeWebVTT(not tutorial) = %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,120 @@
+package annotation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Codec 负责在某种文件格式与 Annotation 之间转换
+type Codec interface {
+	// Encode 将标注写入 w
+	Encode(w io.Writer, ann *Annotation) error
+	// Decode 从 r 读取标注，同时返回诊断信息
+	Decode(r io.Reader, opts ParseOptions) (*Annotation, Diagnostics, error)
+}
+
+// 已注册的编解码器，键为小写扩展名（含点）
+var codecs = map[string]Codec{}
+
+func init() {
+	RegisterCodec(".txt", TextCodec{})
+	RegisterCodec(".json", JSONCodec{})
+}
+
+// RegisterCodec 注册扩展名对应的编解码器，重复注册会覆盖
+func RegisterCodec(ext string, c Codec) {
+	codecs[normalizeExt(ext)] = c
+}
+
+// LookupCodec 按扩展名（如 ".json" 或 "json"）查找编解码器
+func LookupCodec(ext string) (Codec, bool) {
+	c, ok := codecs[normalizeExt(ext)]
+	return c, ok
+}
+
+// CodecForPath 根据文件扩展名选择编解码器，未知扩展名使用文本格式
+func CodecForPath(path string) Codec {
+	if c, ok := LookupCodec(filepath.Ext(path)); ok {
+		return c
+	}
+	return TextCodec{}
+}
+
+// Extensions 返回所有已注册的标注文件扩展名（已排序，".txt" 优先）
+func Extensions() []string {
+	exts := make([]string, 0, len(codecs))
+	for ext := range codecs {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if exts[i] == ".txt" || exts[j] == ".txt" {
+			return exts[i] == ".txt"
+		}
+		return exts[i] < exts[j]
+	})
+	return exts
+}
+
+// IsAnnotationFile 判断文件名是否为已注册格式的标注文件
+func IsAnnotationFile(name string) bool {
+	_, ok := LookupCodec(filepath.Ext(name))
+	return ok
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// TextCodec 原有的 .txt 文本格式
+type TextCodec struct{}
+
+// Encode 实现 Codec
+func (TextCodec) Encode(w io.Writer, ann *Annotation) error {
+	_, err := io.WriteString(w, ann.Format())
+	return err
+}
+
+// Decode 实现 Codec
+func (TextCodec) Decode(r io.Reader, opts ParseOptions) (*Annotation, Diagnostics, error) {
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParseLinesWithOptions(lines, opts)
+}
+
+// JSONCodec 每个视频一个 JSON 文件，结构与 API 返回的 Annotation 相同
+type JSONCodec struct{}
+
+// Encode 实现 Codec
+func (JSONCodec) Encode(w io.Writer, ann *Annotation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ann)
+}
+
+// Decode 实现 Codec
+func (JSONCodec) Decode(r io.Reader, opts ParseOptions) (*Annotation, Diagnostics, error) {
+	var ann Annotation
+	if err := json.NewDecoder(r).Decode(&ann); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON annotation: %w", err)
+	}
+	if ann.Steps == nil {
+		ann.Steps = []Step{}
+	}
+	return &ann, Diagnostics{}, nil
+}
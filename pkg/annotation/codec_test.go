@@ -0,0 +1,195 @@
+package annotation
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAnnotation 返回带结束时间和帧序号的测试标注
+func testAnnotation() *Annotation {
+	end, frame := NewTimestamp(5*time.Second), 120
+	return &Annotation{
+		Title:      "Title",
+		IsTutorial: true,
+		Steps: []Step{
+			{Number: 1, Timestamp: NewTimestamp(time.Second), End: &end, Frame: &frame, Description: "first"},
+			{Number: 2, Timestamp: NewTimestamp(time.Hour + 500*time.Millisecond), Description: "second"},
+		},
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, ext := range Extensions() {
+		t.Run(ext, func(t *testing.T) {
+			codec, ok := LookupCodec(ext)
+			if !ok {
+				t.Fatalf("no codec for %s", ext)
+			}
+			want := testAnnotation()
+			var buf bytes.Buffer
+			if err := codec.Encode(&buf, want); err != nil {
+				t.Fatal(err)
+			}
+			got, diags, err := codec.Decode(&buf, ParseOptions{Strict: true})
+			if err != nil {
+				t.Fatalf("Decode: %v (%v)", err, diags)
+			}
+			// 行号只在文本格式中有意义
+			for i := range got.Steps {
+				got.Steps[i].Line = 0
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLookupCodec(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want Codec
+	}{
+		{".txt", TextCodec{}},
+		{"json", JSONCodec{}},
+		{".JSON", JSONCodec{}},
+		{".srt", nil},
+	}
+	for _, tt := range tests {
+		c, ok := LookupCodec(tt.ext)
+		if ok != (tt.want != nil) || c != tt.want {
+			t.Errorf("LookupCodec(%q) = %T, %v", tt.ext, c, ok)
+		}
+	}
+	if _, ok := CodecForPath("video.unknown").(TextCodec); !ok {
+		t.Error("CodecForPath should fall back to the text format")
+	}
+	if exts := Extensions(); len(exts) == 0 || exts[0] != ".txt" {
+		t.Errorf("Extensions() = %v, want .txt first", exts)
+	}
+}
+
+func TestJSONCodecInvalid(t *testing.T) {
+	for _, in := range []string{`{`, `{"steps":[{"timestamp":"1:2"}]}`} {
+		if _, _, err := (JSONCodec{}).Decode(strings.NewReader(in), ParseOptions{}); err == nil {
+			t.Errorf("Decode(%s) succeeded, want error", in)
+		}
+	}
+	ann, _, err := (JSONCodec{}).Decode(strings.NewReader(`{"title":"x","is_tutorial":true}`), ParseOptions{})
+	if err != nil || ann.Steps == nil {
+		t.Errorf("Decode without steps = %+v, %v; want empty steps", ann, err)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	entries := []ManifestEntry{
+		{Stem: "intro", Annotation: testAnnotation()},
+		{Stem: "course/other", Annotation: &Annotation{IsTutorial: false, Steps: []Step{}}},
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(entries) {
+		t.Errorf("manifest has %d lines, want %d", n, len(entries))
+	}
+
+	got, err := ReadManifest(strings.NewReader("\n" + buf.String() + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadManifest = %+v, want %+v", got, entries)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"invalid json", "{\"stem\":\"a\"}\n{", "line 2: failed to parse manifest entry"},
+		{"missing stem", `{"title":"x"}`, "line 1: manifest entry has no stem"},
+		{"invalid timestamp", `{"stem":"a","steps":[{"timestamp":"x"}]}`, "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadManifest(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadManifest error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectAndSaveManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"intro.txt":       "Intro\n\n1) 00:01 first\n",
+		"intro.json":      `{"title":"ignored","is_tutorial":true,"steps":[]}`,
+		"course/a.json":   `{"title":"A","is_tutorial":true,"steps":[{"number":1,"timestamp":"00:02","description":"x"}]}`,
+		"course/notes.md": "not an annotation",
+		"other/skip.txt":  "[not tutorial]\n",
+		// 修订历史和 git 仓库中的文件不是标注
+		".mp4label/history/intro/notes.txt": "Old\n\n1) 00:01 old\n",
+		".git/info/refs.json":               "not json",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := CollectManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stems []string
+	for _, e := range entries {
+		stems = append(stems, e.Stem)
+	}
+	// 同一 key 同时存在 .txt 和 .json 时使用 .txt
+	if want := []string{"course/a", "intro", "other/skip"}; !reflect.DeepEqual(stems, want) {
+		t.Fatalf("stems = %v, want %v", stems, want)
+	}
+	if entries[1].Title != "Intro" {
+		t.Errorf("intro title = %q, want Intro", entries[1].Title)
+	}
+
+	out := t.TempDir()
+	if err := SaveManifest(entries, out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := CollectManifest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range entries {
+		for j := range entries[i].Steps {
+			entries[i].Steps[j].Line = 0
+		}
+	}
+	if !reflect.DeepEqual(again, entries) {
+		t.Errorf("saved manifest = %+v, want %+v", again, entries)
+	}
+
+	for _, stem := range []string{"../escape", "/abs", "a//b", `a\b`} {
+		bad := []ManifestEntry{{Stem: stem, Annotation: testAnnotation()}}
+		if err := SaveManifest(bad, out, ".txt"); err == nil {
+			t.Errorf("SaveManifest with stem %q succeeded", stem)
+		}
+	}
+	if err := SaveManifest(entries, out, ".srt"); err == nil {
+		t.Error("SaveManifest with unsupported format succeeded")
+	}
+}
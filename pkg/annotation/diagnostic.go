@@ -0,0 +1,56 @@
+package annotation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity 表示诊断信息的严重程度
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // 错误：该行内容无法被解析，会在保存时丢失
+	SeverityWarning Severity = "warning" // 警告：该行可以解析，但内容可疑
+)
+
+// Diagnostic 表示解析过程中发现的一条问题
+type Diagnostic struct {
+	Line     int      `json:"line"`     // 行号（从 1 开始）
+	Column   int      `json:"column"`   // 列号（从 1 开始，按字符计）
+	Text     string   `json:"text"`     // 原始行内容
+	Reason   string   `json:"reason"`   // 问题描述
+	Severity Severity `json:"severity"` // 严重程度
+}
+
+// String 返回 "line:column: severity: reason" 形式的描述
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Reason)
+}
+
+// Diagnostics 表示诊断信息列表
+type Diagnostics []Diagnostic
+
+// HasErrors 判断是否包含错误级别的诊断
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError 在严格模式下解析失败时返回，包含全部诊断信息
+type ParseError struct {
+	Diagnostics Diagnostics
+}
+
+func (e *ParseError) Error() string {
+	var errs []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d.String())
+		}
+	}
+	return fmt.Sprintf("failed to parse annotation: %s", strings.Join(errs, "; "))
+}
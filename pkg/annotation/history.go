@@ -0,0 +1,230 @@
+package annotation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryDir 是输出目录中保存修订历史的隐藏目录（相对输出目录，以 / 分隔）
+// 每个标注 key 一个子目录，如 .mp4label/history/course-a/intro/000001.rev
+const HistoryDir = ".mp4label/history"
+
+// SkipDir 判断遍历标注目录时是否跳过子目录 rel（相对标注目录，以 / 分隔）：
+// 修订历史目录和 git 仓库目录（.git）中没有标注文件，内容又会随每次保存变化
+func SkipDir(rel string) bool {
+	return rel == HistoryDir || path.Base(rel) == ".git"
+}
+
+// RevisionExt 是修订文件的扩展名；它不是标注格式，扫描和校验标注时会被忽略
+const RevisionExt = ".rev"
+
+// Revision 表示标注的一个修订版本：一次保存、删除或回滚后的文件内容
+type Revision struct {
+	Rev          int       `json:"rev"`                     // 修订号，从 1 开始递增
+	Action       string    `json:"action"`                  // EditSave、EditDelete 或 EditRevert
+	Annotator    string    `json:"annotator,omitempty"`     // 操作人员（多用户模式）
+	Time         time.Time `json:"time"`                    // 操作时间
+	RevertedFrom int       `json:"reverted_from,omitempty"` // 回滚时恢复的修订号
+	Format       string    `json:"format,omitempty"`        // 内容的格式（扩展名，如 .txt）
+	Content      string    `json:"content,omitempty"`       // 操作后的文件内容，删除时为空
+}
+
+// Annotation 解析修订中保存的标注，删除操作的修订返回错误
+func (r *Revision) Annotation() (*Annotation, error) {
+	if r.Action == EditDelete {
+		return nil, fmt.Errorf("revision %d is a deletion", r.Rev)
+	}
+	codec, ok := LookupCodec(r.Format)
+	if !ok {
+		return nil, fmt.Errorf("revision %d has unsupported format %q", r.Rev, r.Format)
+	}
+	ann, _, err := codec.Decode(bytes.NewReader([]byte(r.Content)), ParseOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %w", r.Rev, err)
+	}
+	return ann, nil
+}
+
+// HistoryPath 返回标注 key 在输出目录中的修订历史目录
+func HistoryPath(outputDir, key string) string {
+	return filepath.Join(outputDir, filepath.FromSlash(HistoryDir), filepath.FromSlash(key))
+}
+
+// HistoryKeys 返回输出目录中有修订历史的所有标注 key（已排序）
+func HistoryKeys(outputDir string) ([]string, error) {
+	root := filepath.Join(outputDir, filepath.FromSlash(HistoryDir))
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(d.Name()) != RevisionExt {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		seen[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// revisionNumbers 返回目录中所有修订号（升序），目录不存在时返回空
+func revisionNumbers(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var revs []int
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), RevisionExt)
+		if e.IsDir() || !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(name); err == nil && n > 0 {
+			revs = append(revs, n)
+		}
+	}
+	sort.Ints(revs)
+	return revs, nil
+}
+
+// revisionPath 返回修订文件路径
+func revisionPath(dir string, rev int) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d%s", rev, RevisionExt))
+}
+
+// LoadRevision 读取一个修订，不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)
+func LoadRevision(dir string, rev int) (*Revision, error) {
+	data, err := os.ReadFile(revisionPath(dir, rev))
+	if err != nil {
+		return nil, err
+	}
+	var r Revision
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %w", rev, err)
+	}
+	return &r, nil
+}
+
+// ListRevisions 读取目录中的所有修订（按修订号升序），没有历史时返回空
+func ListRevisions(dir string) ([]Revision, error) {
+	revs, err := revisionNumbers(dir)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(revs))
+	for _, n := range revs {
+		r, err := LoadRevision(dir, n)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *r)
+	}
+	return revisions, nil
+}
+
+// LatestRevision 返回最新的修订，没有历史时返回 nil
+func LatestRevision(dir string) (*Revision, error) {
+	revs, err := revisionNumbers(dir)
+	if err != nil || len(revs) == 0 {
+		return nil, err
+	}
+	return LoadRevision(dir, revs[len(revs)-1])
+}
+
+// AppendRevision 以下一个修订号写入修订并返回该修订号；已有的修订文件不会被覆盖
+// 调用方需保证同一目录不会被并发追加
+func AppendRevision(dir string, r Revision) (int, error) {
+	revs, err := revisionNumbers(dir)
+	if err != nil {
+		return 0, err
+	}
+	r.Rev = 1
+	if len(revs) > 0 {
+		r.Rev = revs[len(revs)-1] + 1
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode revision: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(revisionPath(dir, r.Rev), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return r.Rev, f.Close()
+}
+
+// PruneOptions 指定清理哪些旧修订
+type PruneOptions struct {
+	Keep   int       // 保留最新的 Keep 个修订，0 表示不按数量保留
+	Before time.Time // 只删除早于该时间的修订，零值表示不按时间限制
+	DryRun bool      // 只返回将要删除的修订，不删除文件
+}
+
+// PruneRevisions 删除目录中超出保留数量且早于指定时间的修订，返回被删除的修订号
+// 最新的修订总是保留，修订号因此不会重复使用
+func PruneRevisions(dir string, opts PruneOptions) ([]int, error) {
+	revs, err := revisionNumbers(dir)
+	if err != nil || len(revs) == 0 {
+		return nil, err
+	}
+	keep := opts.Keep
+	if keep < 1 {
+		keep = 1
+	}
+
+	var removed []int
+	for _, n := range revs[:max(len(revs)-keep, 0)] {
+		if !opts.Before.IsZero() {
+			r, err := LoadRevision(dir, n)
+			if err != nil {
+				return removed, err
+			}
+			if !r.Time.Before(opts.Before) {
+				continue
+			}
+		}
+		if !opts.DryRun {
+			if err := os.Remove(revisionPath(dir, n)); err != nil {
+				return removed, err
+			}
+		}
+		removed = append(removed, n)
+	}
+	return removed, nil
+}
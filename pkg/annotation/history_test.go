@@ -0,0 +1,148 @@
+package annotation
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRevisions(t *testing.T) {
+	outputDir := t.TempDir()
+	dir := HistoryPath(outputDir, "course/intro")
+	if latest, err := LatestRevision(dir); latest != nil || err != nil {
+		t.Fatalf("LatestRevision without history = %v, %v", latest, err)
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	revisions := []Revision{
+		{Action: EditSave, Annotator: "alice", Time: base, Format: ".txt", Content: "Title\n\n1) 00:01 a\n"},
+		{Action: EditDelete, Annotator: "bob", Time: base.Add(time.Hour)},
+		{Action: EditRevert, Time: base.Add(2 * time.Hour), RevertedFrom: 1, Format: ".json", Content: `{"title":"Title","is_tutorial":true,"steps":[]}`},
+	}
+	for i, r := range revisions {
+		rev, err := AppendRevision(dir, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rev != i+1 {
+			t.Errorf("AppendRevision = %d, want %d", rev, i+1)
+		}
+		revisions[i].Rev = rev
+	}
+
+	list, err := ListRevisions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, revisions) {
+		t.Errorf("ListRevisions = %+v, want %+v", list, revisions)
+	}
+	if latest, err := LatestRevision(dir); err != nil || latest.Rev != 3 {
+		t.Errorf("LatestRevision = %+v, %v", latest, err)
+	}
+	if _, err := LoadRevision(dir, 9); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadRevision of a missing revision = %v", err)
+	}
+
+	if ann, err := list[0].Annotation(); err != nil || len(ann.Steps) != 1 {
+		t.Errorf("revision 1 annotation = %+v, %v", ann, err)
+	}
+	if _, err := list[1].Annotation(); err == nil {
+		t.Error("annotation of a deletion succeeded")
+	}
+	if ann, err := list[2].Annotation(); err != nil || ann.Title != "Title" {
+		t.Errorf("revision 3 annotation = %+v, %v", ann, err)
+	}
+
+	// 其他文件不影响修订号
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AppendRevision(HistoryPath(outputDir, "outro"), revisions[0]); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := HistoryKeys(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"course/intro", "outro"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("HistoryKeys = %v, want %v", keys, want)
+	}
+	if keys, err := HistoryKeys(t.TempDir()); err != nil || len(keys) != 0 {
+		t.Errorf("HistoryKeys without history = %v, %v", keys, err)
+	}
+}
+
+func TestPruneRevisions(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts PruneOptions
+		want []int
+	}{
+		{"keep latest only", PruneOptions{}, []int{1, 2, 3, 4}},
+		{"keep two", PruneOptions{Keep: 2}, []int{1, 2, 3}},
+		{"before", PruneOptions{Before: base.Add(2 * time.Hour)}, []int{1, 2}},
+		{"keep and before", PruneOptions{Keep: 4, Before: base.Add(3 * time.Hour)}, []int{1}},
+		{"dry run", PruneOptions{Keep: 3, DryRun: true}, []int{1, 2}},
+		{"latest is never removed", PruneOptions{Before: base.Add(24 * time.Hour)}, []int{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i := 0; i < 5; i++ {
+				if _, err := AppendRevision(dir, Revision{Action: EditSave, Time: base.Add(time.Duration(i) * time.Hour)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			removed, err := PruneRevisions(dir, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(removed, tt.want) {
+				t.Errorf("PruneRevisions = %v, want %v", removed, tt.want)
+			}
+
+			left, err := revisionNumbers(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantLeft := 5 - len(tt.want)
+			if tt.opts.DryRun {
+				wantLeft = 5
+			}
+			if len(left) != wantLeft {
+				t.Errorf("%d revisions left, want %d", len(left), wantLeft)
+			}
+
+			// 修订号在清理后继续递增
+			if rev, err := AppendRevision(dir, Revision{Action: EditSave}); err != nil || rev != 6 {
+				t.Errorf("AppendRevision after prune = %d, %v; want 6", rev, err)
+			}
+		})
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{HistoryDir, true},
+		{".git", true},
+		{"course/.git", true},
+		{".mp4label", false},
+		{"course/history", false},
+		{"course/.mp4label/history", false},
+		{".", false},
+	}
+	for _, tt := range tests {
+		if got := SkipDir(tt.rel); got != tt.want {
+			t.Errorf("SkipDir(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package annotation
+
+import (
+	"path"
+	"strings"
+)
+
+// ValidKey 检查标注 key 是否合法
+// key 是视频相对视频目录的路径（不含扩展名，以 / 分隔），如 "intro" 或 "course-a/intro"；
+// 不允许绝对路径、反斜杠、空段以及 "." / ".." 段，避免写出标注目录
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	if path.Clean(key) != key {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
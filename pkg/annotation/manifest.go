@@ -0,0 +1,146 @@
+package annotation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestEntry 表示 JSONL 清单中的一行：一个视频及其标注
+// 标注字段与 stem 平铺在同一个 JSON 对象中
+type ManifestEntry struct {
+	Stem string `json:"stem"` // 标注 key：视频相对视频目录的路径（不含扩展名），顶层视频即文件名
+	*Annotation
+}
+
+// WriteManifest 将清单以 JSONL 格式写入 w，每行一个视频
+func WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode manifest entry %s: %w", entry.Stem, err)
+		}
+	}
+	return nil
+}
+
+// ReadManifest 读取 JSONL 清单，空行会被跳过
+func ReadManifest(r io.Reader) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry ManifestEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse manifest entry: %w", lineNum, err)
+		}
+		if entry.Stem == "" {
+			return nil, fmt.Errorf("line %d: manifest entry has no stem", lineNum)
+		}
+		if entry.Annotation == nil {
+			entry.Annotation = &Annotation{IsTutorial: true}
+		}
+		if entry.Steps == nil {
+			entry.Steps = []Step{}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CollectManifest 递归读取目录下所有已注册格式的标注文件，按 key 排序生成清单
+// 子目录中的文件以相对路径作为 key；同一 key 存在多种格式时以 Extensions() 的顺序为准
+// 修订历史和 .git 目录不读取（见 SkipDir）
+func CollectManifest(dir string) ([]ManifestEntry, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	priority := make(map[string]int)
+	for i, ext := range Extensions() {
+		priority[ext] = i
+	}
+
+	chosen := make(map[string]string) // key -> 相对路径
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if SkipDir(filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsAnnotationFile(d.Name()) {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(rel))
+		stem := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		if prev, ok := chosen[stem]; ok && priority[strings.ToLower(filepath.Ext(prev))] <= priority[ext] {
+			return nil
+		}
+		chosen[stem] = rel
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	stems := make([]string, 0, len(chosen))
+	for stem := range chosen {
+		stems = append(stems, stem)
+	}
+	sort.Strings(stems)
+
+	entries := make([]ManifestEntry, 0, len(stems))
+	for _, stem := range stems {
+		ann, err := ParseFile(filepath.Join(dir, chosen[stem]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", chosen[stem], err)
+		}
+		entries = append(entries, ManifestEntry{Stem: stem, Annotation: ann})
+	}
+
+	return entries, nil
+}
+
+// SaveManifest 将清单中的每个视频保存为 dir 下的单独文件，ext 指定文件格式
+// key 含子目录时按相同的目录结构保存
+func SaveManifest(entries []ManifestEntry, dir, ext string) error {
+	if _, ok := LookupCodec(ext); !ok {
+		return fmt.Errorf("unsupported annotation format: %s", ext)
+	}
+
+	for _, entry := range entries {
+		if !ValidKey(entry.Stem) {
+			return fmt.Errorf("invalid stem in manifest: %q", entry.Stem)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(entry.Stem)+normalizeExt(ext))
+		if err := entry.Save(path); err != nil {
+			return fmt.Errorf("failed to save %s: %w", entry.Stem, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MetaExt 是标注元数据文件的扩展名：与标注文件同名、放在同一目录（如 intro.meta）
+// 它不是标注格式，扫描和校验标注时会被忽略
+const MetaExt = ".meta"
+
+// 元数据中记录的操作
+const (
+	EditSave   = "save"
+	EditDelete = "delete"
+	EditRevert = "revert" // 回滚到历史修订
+)
+
+// EditRecord 表示一次保存或删除
+type EditRecord struct {
+	Action    string    `json:"action"`    // EditSave、EditDelete 或 EditRevert
+	Annotator string    `json:"annotator"` // 标注人员登录名
+	Time      time.Time `json:"time"`
+}
+
+// Meta 表示标注的元数据：最后一次操作的人员和时间，以及全部操作记录
+type Meta struct {
+	Annotator string       `json:"annotator"`  // 最后一次保存或删除的人员
+	UpdatedAt time.Time    `json:"updated_at"` // 最后一次保存或删除的时间
+	History   []EditRecord `json:"history"`    // 按时间顺序的操作记录
+}
+
+// LoadMeta 读取元数据文件，文件不存在时返回 nil
+func LoadMeta(path string) (*Meta, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta Meta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata %s: %w", path, err)
+	}
+	return &meta, nil
+}
+
+// RecordEdit 在元数据文件中追加一条操作记录（文件不存在时创建）
+// 调用方需保证同一文件不会被并发修改
+func RecordEdit(path string, record EditRecord) error {
+	meta, err := LoadMeta(path)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = &Meta{}
+	}
+	meta.Annotator = record.Annotator
+	meta.UpdatedAt = record.Time
+	meta.History = append(meta.History, record)
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
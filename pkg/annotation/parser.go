@@ -1,66 +1,112 @@
 package annotation
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Annotation 表示一个标注文件的内容
 type Annotation struct {
-	Title      string   `json:"title"`       // 教程题目（教学视频）或空（非教学视频）
-	IsTutorial bool     `json:"is_tutorial"` // 是否为教学视频
-	Steps      []Step   `json:"steps"`       // 步骤列表（仅教学视频）
+	Title      string `json:"title"`       // 教程题目（教学视频）或空（非教学视频）
+	IsTutorial bool   `json:"is_tutorial"` // 是否为教学视频
+	Steps      []Step `json:"steps"`       // 步骤列表（仅教学视频）
 }
 
 // Step 表示一个操作步骤
 type Step struct {
-	Number      int    `json:"number"`      // 步骤编号
-	Timestamp   string `json:"timestamp"`   // 时间戳 (mm:ss.SSS)
-	Description string `json:"description"` // 步骤描述
+	Number      int        `json:"number"`          // 步骤编号
+	Timestamp   Timestamp  `json:"timestamp"`       // 开始时间戳 (mm:ss.SSS 或 hh:mm:ss.SSS)
+	End         *Timestamp `json:"end,omitempty"`   // 结束时间戳（可选），未设置时以下一步骤的开始时间为结束
+	Frame       *int       `json:"frame,omitempty"` // 开始时间对应的帧序号（可选，对齐到帧后记录）
+	Description string     `json:"description"`     // 步骤描述
+	Line        int        `json:"-"`               // 来源文件中的行号（从 1 开始，非文本格式时为 0）
 }
 
-// ParseFile 解析标注文件
+// Segment 表示一个步骤覆盖的时间区间
+type Segment struct {
+	Start Timestamp `json:"start"`
+	End   Timestamp `json:"end"`
+}
+
+// ParseOptions 控制解析行为
+type ParseOptions struct {
+	// Strict 为 true 时，任何错误级别的诊断都会导致解析失败（返回 *ParseError）；
+	// 否则跳过无法解析的行并继续
+	Strict bool
+}
+
+// 步骤格式：1) 00:11 描述、1) 00:11.123 描述 或 1) 01:02:11.123 描述
+// 带结束时间：1) 00:11.200-00:19.850 描述
+// 带帧序号：1) 00:11.200@336 描述 或 1) 00:11.200-00:19.850@336 描述
+// 时间部分由 parseStepTime 进一步解析
+var stepPattern = regexp.MustCompile(`^(\d+)\)\s+(\S+)\s+(.+)$`)
+
+// ParseFile 解析标注文件（宽松模式，忽略诊断信息）
 func ParseFile(filePath string) (*Annotation, error) {
+	ann, _, err := ParseFileWithOptions(filePath, ParseOptions{})
+	return ann, err
+}
+
+// ParseFileWithOptions 解析标注文件，同时返回逐行诊断信息
+// 文件格式由扩展名决定（见 CodecForPath）
+func ParseFileWithOptions(filePath string, opts ParseOptions) (*Annotation, Diagnostics, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	return ParseLines(lines)
+	return CodecForPath(filePath).Decode(file, opts)
 }
 
-// ParseLines 解析标注内容（行列表）
+// ParseLines 解析标注内容（行列表，宽松模式，忽略诊断信息）
 func ParseLines(lines []string) (*Annotation, error) {
+	ann, _, err := ParseLinesWithOptions(lines, ParseOptions{})
+	return ann, err
+}
+
+// ParseLinesWithOptions 解析标注内容（行列表），同时返回逐行诊断信息
+// 宽松模式下无法解析的步骤行会被跳过并记录为错误诊断；
+// 严格模式下只要存在错误诊断就返回 *ParseError
+func ParseLinesWithOptions(lines []string, opts ParseOptions) (*Annotation, Diagnostics, error) {
+	diags := Diagnostics{}
+
 	if len(lines) == 0 {
 		return &Annotation{
 			Title:      "",
 			IsTutorial: true,
 			Steps:      []Step{},
-		}, nil
+		}, diags, nil
 	}
 
 	// 检查是否为非教学视频
 	firstLine := strings.TrimSpace(lines[0])
 	if strings.ToLower(firstLine) == "[not tutorial]" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) != "" {
+				diags = append(diags, Diagnostic{
+					Line:     i + 1,
+					Column:   1,
+					Text:     lines[i],
+					Reason:   "content after [not tutorial] marker is ignored",
+					Severity: SeverityWarning,
+				})
+			}
+		}
 		return &Annotation{
 			Title:      "",
 			IsTutorial: false,
 			Steps:      []Step{},
-		}, nil
+		}, diags, nil
 	}
 
 	// 解析教学视频格式
@@ -70,13 +116,7 @@ func ParseLines(lines []string) (*Annotation, error) {
 	}
 
 	// 第一行是题目
-	if len(lines) > 0 {
-		ann.Title = strings.TrimSpace(lines[0])
-	}
-
-	// 步骤格式：1) 00:11 描述 或 1) 00:11.123 描述
-	// 支持两种格式：mm:ss 和 mm:ss.SSS
-	stepPattern := regexp.MustCompile(`^(\d+)\)\s+(\d{2}:\d{2}(?:\.\d{3})?)\s+(.+)$`)
+	ann.Title = firstLine
 
 	for i := 1; i < len(lines); i++ {
 		line := strings.TrimSpace(lines[i])
@@ -84,28 +124,137 @@ func ParseLines(lines []string) (*Annotation, error) {
 			continue
 		}
 
-		matches := stepPattern.FindStringSubmatch(line)
-		if len(matches) == 4 {
-			var number int
-			fmt.Sscanf(matches[1], "%d", &number)
-			timestamp := matches[2]
-			
-			// 如果时间戳不包含毫秒，自动添加 .000
-			if !strings.Contains(timestamp, ".") {
-				timestamp = timestamp + ".000"
-			}
-			
-			description := matches[3]
+		matches := stepPattern.FindStringSubmatchIndex(line)
+		if matches == nil {
+			column, reason := diagnoseStepLine(line)
+			diags = append(diags, Diagnostic{
+				Line:     i + 1,
+				Column:   column + leadingSpaceWidth(lines[i]),
+				Text:     lines[i],
+				Reason:   reason,
+				Severity: SeverityError,
+			})
+			continue
+		}
+
+		var number int
+		fmt.Sscanf(line[matches[2]:matches[3]], "%d", &number)
+
+		// 不含毫秒的时间戳解析后统一格式化为 .SSS
+		timestamp, end, frame, offset, err := parseStepTime(line[matches[4]:matches[5]])
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				Line:     i + 1,
+				Column:   columnAt(line, matches[4]+offset) + leadingSpaceWidth(lines[i]),
+				Text:     lines[i],
+				Reason:   err.Error(),
+				Severity: SeverityError,
+			})
+			continue
+		}
+
+		description := line[matches[6]:matches[7]]
 
-			ann.Steps = append(ann.Steps, Step{
-				Number:      number,
-				Timestamp:   timestamp,
-				Description: description,
+		// 步骤编号不连续时给出警告（保存时校验会拒绝）
+		if expected := len(ann.Steps) + 1; number != expected {
+			diags = append(diags, Diagnostic{
+				Line:     i + 1,
+				Column:   1 + leadingSpaceWidth(lines[i]),
+				Text:     lines[i],
+				Reason:   fmt.Sprintf("step number %d out of sequence, expected %d", number, expected),
+				Severity: SeverityWarning,
 			})
 		}
+
+		ann.Steps = append(ann.Steps, Step{
+			Number:      number,
+			Timestamp:   timestamp,
+			End:         end,
+			Frame:       frame,
+			Description: description,
+			Line:        i + 1,
+		})
+	}
+
+	if opts.Strict && diags.HasErrors() {
+		return nil, diags, &ParseError{Diagnostics: diags}
 	}
 
-	return ann, nil
+	return ann, diags, nil
+}
+
+// diagnoseStepLine 找出步骤行不符合格式的具体位置，返回列号（从 1 开始）和原因
+// line 应已去除首尾空白
+func diagnoseStepLine(line string) (int, string) {
+	pos := 0
+	for pos < len(line) && line[pos] >= '0' && line[pos] <= '9' {
+		pos++
+	}
+	if pos == 0 {
+		return 1, "missing step number, expected format 'N) mm:ss.SSS description'"
+	}
+
+	if pos >= len(line) || line[pos] != ')' {
+		return columnAt(line, pos), "expected ')' after step number"
+	}
+	pos++
+
+	if pos >= len(line) || (line[pos] != ' ' && line[pos] != '\t') {
+		return columnAt(line, pos), "missing space after ')'"
+	}
+	for pos < len(line) && (line[pos] == ' ' || line[pos] == '\t') {
+		pos++
+	}
+
+	start := pos
+	for pos < len(line) && line[pos] != ' ' && line[pos] != '\t' {
+		pos++
+	}
+	if _, _, _, offset, err := parseStepTime(line[start:pos]); err != nil {
+		return columnAt(line, start+offset), err.Error()
+	}
+
+	return columnAt(line, pos) + 1, "missing step description"
+}
+
+// parseStepTime 解析步骤的时间字段："start" 或 "start-end"，可带 "@帧序号" 后缀
+// 出错时同时返回出错部分在 token 中的字节偏移
+func parseStepTime(token string) (Timestamp, *Timestamp, *int, int, error) {
+	timeText, frameText, hasFrame := strings.Cut(token, "@")
+
+	var frame *int
+	if hasFrame {
+		n, err := strconv.Atoi(frameText)
+		if err != nil || n < 0 {
+			return 0, nil, nil, len(timeText) + 1, fmt.Errorf("invalid frame index %q, should be a non-negative integer", frameText)
+		}
+		frame = &n
+	}
+
+	startText, endText, hasEnd := strings.Cut(timeText, "-")
+	start, err := ParseTimestamp(startText)
+	if err != nil {
+		return 0, nil, nil, 0, err
+	}
+	if !hasEnd {
+		return start, nil, frame, 0, nil
+	}
+
+	end, err := ParseTimestamp(endText)
+	if err != nil {
+		return 0, nil, nil, len(startText) + 1, err
+	}
+	return start, &end, frame, 0, nil
+}
+
+// columnAt 将字节偏移转换为列号（从 1 开始，按字符计）
+func columnAt(line string, offset int) int {
+	return utf8.RuneCountInString(line[:offset]) + 1
+}
+
+// leadingSpaceWidth 返回行首空白字符的数量
+func leadingSpaceWidth(line string) int {
+	return utf8.RuneCountInString(line) - utf8.RuneCountInString(strings.TrimLeftFunc(line, unicode.IsSpace))
 }
 
 // Format 将标注格式化为文本
@@ -121,13 +270,51 @@ func (a *Annotation) Format() string {
 	}
 
 	for _, step := range a.Steps {
-		sb.WriteString(fmt.Sprintf("%d) %s %s\n", step.Number, step.Timestamp, step.Description))
+		timeText := step.Timestamp.String()
+		if step.End != nil {
+			timeText += "-" + step.End.String()
+		}
+		if step.Frame != nil {
+			timeText += fmt.Sprintf("@%d", *step.Frame)
+		}
+		sb.WriteString(fmt.Sprintf("%d) %s %s\n", step.Number, timeText, step.Description))
 	}
 
 	return sb.String()
 }
 
-// Save 保存标注到文件
+// Segments 返回每个步骤的时间区间
+// 未显式给出结束时间的步骤以下一步骤的开始时间作为结束；
+// 最后一个步骤使用 videoDuration 作为结束（videoDuration 为 0 时结束等于开始）
+func (a *Annotation) Segments(videoDuration time.Duration) []Segment {
+	segments := make([]Segment, len(a.Steps))
+	for i, step := range a.Steps {
+		segments[i].Start = step.Timestamp
+		switch {
+		case step.End != nil:
+			segments[i].End = *step.End
+		case i+1 < len(a.Steps):
+			segments[i].End = a.Steps[i+1].Timestamp
+		case videoDuration > 0:
+			segments[i].End = NewTimestamp(videoDuration)
+		default:
+			segments[i].End = step.Timestamp
+		}
+	}
+	return segments
+}
+
+// SortSteps 按时间戳对步骤进行稳定排序，并重新编号
+func (a *Annotation) SortSteps() {
+	sort.SliceStable(a.Steps, func(i, j int) bool {
+		return a.Steps[i].Timestamp < a.Steps[j].Timestamp
+	})
+	for i := range a.Steps {
+		a.Steps[i].Number = i + 1
+	}
+}
+
+// Save 保存标注到文件，文件格式由扩展名决定（见 CodecForPath）
 func (a *Annotation) Save(filePath string) error {
 	// 确保目录存在
 	dir := filepath.Dir(filePath)
@@ -135,6 +322,9 @@ func (a *Annotation) Save(filePath string) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	content := a.Format()
-	return os.WriteFile(filePath, []byte(content), 0644)
+	var buf bytes.Buffer
+	if err := CodecForPath(filePath).Encode(&buf, a); err != nil {
+		return fmt.Errorf("failed to encode annotation: %w", err)
+	}
+	return os.WriteFile(filePath, buf.Bytes(), 0644)
 }
@@ -0,0 +1,99 @@
+package annotation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLinesDiagnostics(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		steps int
+		want  []Diagnostic // 只比较 Line、Column、Severity、Reason
+	}{
+		{"valid", []string{"Title", "1) 00:01 first", "", "2) 00:02.500 second"}, 2, nil},
+		{"missing number", []string{"Title", "x) 00:01 first"}, 0, []Diagnostic{
+			{Line: 2, Column: 1, Severity: SeverityError, Reason: "missing step number, expected format 'N) mm:ss.SSS description'"},
+		}},
+		{"missing paren", []string{"Title", "12. 00:01 first"}, 0, []Diagnostic{
+			{Line: 2, Column: 3, Severity: SeverityError, Reason: "expected ')' after step number"},
+		}},
+		{"missing space", []string{"Title", "1)00:01 first"}, 0, []Diagnostic{
+			{Line: 2, Column: 3, Severity: SeverityError, Reason: "missing space after ')'"},
+		}},
+		{"missing description", []string{"Title", "1) 00:01"}, 0, []Diagnostic{
+			{Line: 2, Column: 10, Severity: SeverityError, Reason: "missing step description"},
+		}},
+		// 列号按字符计，并包含行首空白
+		{"bad timestamp", []string{"Title", "  1) 0:01 步骤"}, 0, []Diagnostic{
+			{Line: 2, Column: 6, Severity: SeverityError},
+		}},
+		{"bad end timestamp", []string{"Title", "1) 00:01-00:1 first"}, 0, []Diagnostic{
+			{Line: 2, Column: 10, Severity: SeverityError},
+		}},
+		{"bad frame", []string{"Title", "1) 00:01@x first"}, 0, []Diagnostic{
+			{Line: 2, Column: 10, Severity: SeverityError, Reason: `invalid frame index "x", should be a non-negative integer`},
+		}},
+		{"out of sequence", []string{"Title", "1) 00:01 first", "3) 00:02 second"}, 2, []Diagnostic{
+			{Line: 3, Column: 1, Severity: SeverityWarning, Reason: "step number 3 out of sequence, expected 2"},
+		}},
+		{"content after not tutorial", []string{"[Not Tutorial]", "", "1) 00:01 first"}, 0, []Diagnostic{
+			{Line: 3, Column: 1, Severity: SeverityWarning, Reason: "content after [not tutorial] marker is ignored"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ann, diags, err := ParseLinesWithOptions(tt.lines, ParseOptions{})
+			if err != nil {
+				t.Fatalf("lenient parse failed: %v", err)
+			}
+			if len(ann.Steps) != tt.steps {
+				t.Errorf("got %d steps, want %d", len(ann.Steps), tt.steps)
+			}
+			if len(diags) != len(tt.want) {
+				t.Fatalf("diagnostics = %v, want %d", diags, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := diags[i]
+				if got.Line != want.Line || got.Column != want.Column || got.Severity != want.Severity ||
+					(want.Reason != "" && got.Reason != want.Reason) {
+					t.Errorf("diagnostic %d = %v, want %v", i, got, want)
+				}
+				if got.Text != tt.lines[got.Line-1] {
+					t.Errorf("diagnostic %d text = %q, want %q", i, got.Text, tt.lines[got.Line-1])
+				}
+			}
+
+			// 严格模式下只有错误会导致失败
+			_, _, err = ParseLinesWithOptions(tt.lines, ParseOptions{Strict: true})
+			var perr *ParseError
+			if diags.HasErrors() != errors.As(err, &perr) {
+				t.Errorf("strict parse error = %v, diagnostics have errors = %v", err, diags.HasErrors())
+			}
+		})
+	}
+}
+
+func TestParseLinesStepFields(t *testing.T) {
+	lines := []string{"Title", "1) 00:11.200-00:19.850@336 open the menu", "2) 01:02:03 save"}
+	ann, err := ParseLines(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, frame := NewTimestamp(19850*time.Millisecond), 336
+	want := []Step{
+		{Number: 1, Timestamp: NewTimestamp(11200 * time.Millisecond), End: &end, Frame: &frame, Description: "open the menu", Line: 2},
+		{Number: 2, Timestamp: NewTimestamp(time.Hour + 2*time.Minute + 3*time.Second), Description: "save", Line: 3},
+	}
+	if !reflect.DeepEqual(ann.Steps, want) {
+		t.Errorf("steps = %+v, want %+v", ann.Steps, want)
+	}
+
+	formatted := "Title\n\n1) 00:11.200-00:19.850@336 open the menu\n2) 01:02:03.000 save\n"
+	if got := ann.Format(); got != formatted {
+		t.Errorf("Format() = %q, want %q", got, formatted)
+	}
+}
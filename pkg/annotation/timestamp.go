@@ -0,0 +1,92 @@
+package annotation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Timestamp 表示视频中的时间点，精度为毫秒
+// 文本形式：不足一小时为 mm:ss.SSS，否则为 hh:mm:ss.SSS
+type Timestamp time.Duration
+
+// 支持的格式：mm:ss、mm:ss.SSS、h:mm:ss(.SSS)、hh:mm:ss(.SSS)
+// 旧文件中超过一小时的视频写作 mm:ss（如 75:30），两段格式的分钟数因此允许超过 59
+var timestampPattern = regexp.MustCompile(`^(?:(\d{1,2}):)?(\d{2}):(\d{2})(?:\.(\d{3}))?$`)
+
+// NewTimestamp 由 time.Duration 创建时间戳（截断到毫秒）
+func NewTimestamp(d time.Duration) Timestamp {
+	return Timestamp(d.Truncate(time.Millisecond))
+}
+
+// ParseTimestamp 解析时间戳字符串；mm:ss 中 60 分钟以上的时间保存时会写作 hh:mm:ss.SSS
+func ParseTimestamp(s string) (Timestamp, error) {
+	matches := timestampPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid timestamp %q, should be mm:ss.SSS or hh:mm:ss.SSS (e.g., 12:32.766)", s)
+	}
+
+	var hours, minutes, seconds, millis int
+	if matches[1] != "" {
+		hours, _ = strconv.Atoi(matches[1])
+	}
+	minutes, _ = strconv.Atoi(matches[2])
+	seconds, _ = strconv.Atoi(matches[3])
+	if matches[4] != "" {
+		millis, _ = strconv.Atoi(matches[4])
+	}
+
+	if matches[1] != "" && minutes >= 60 {
+		return 0, fmt.Errorf("invalid timestamp %q, minutes cannot exceed 59", s)
+	}
+	if seconds >= 60 {
+		return 0, fmt.Errorf("invalid timestamp %q, seconds cannot exceed 59", s)
+	}
+
+	d := time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond
+	return Timestamp(d), nil
+}
+
+// Duration 返回对应的 time.Duration
+func (t Timestamp) Duration() time.Duration {
+	return time.Duration(t)
+}
+
+// String 格式化时间戳，不足一小时时输出 mm:ss.SSS 以保持与旧文件一致
+func (t Timestamp) String() string {
+	ms := time.Duration(t).Milliseconds()
+	sign := ""
+	if ms < 0 {
+		sign = "-"
+		ms = -ms
+	}
+
+	hours := ms / 3600000
+	minutes := (ms % 3600000) / 60000
+	seconds := (ms % 60000) / 1000
+	millis := ms % 1000
+
+	if hours > 0 {
+		return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, hours, minutes, seconds, millis)
+	}
+	return fmt.Sprintf("%s%02d:%02d.%03d", sign, minutes, seconds, millis)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，JSON 中以字符串形式出现
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (t *Timestamp) UnmarshalText(data []byte) error {
+	ts, err := ParseTimestamp(string(data))
+	if err != nil {
+		return err
+	}
+	*t = ts
+	return nil
+}
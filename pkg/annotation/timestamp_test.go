@@ -0,0 +1,90 @@
+package annotation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantStr string
+	}{
+		{"00:00", 0, "00:00.000"},
+		{"12:32", 12*time.Minute + 32*time.Second, "12:32.000"},
+		{"12:32.766", 12*time.Minute + 32*time.Second + 766*time.Millisecond, "12:32.766"},
+		{"59:59.999", 59*time.Minute + 59*time.Second + 999*time.Millisecond, "59:59.999"},
+		{"1:00:00", time.Hour, "01:00:00.000"},
+		{"01:02:03.004", time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, "01:02:03.004"},
+		{"99:59:59.999", 99*time.Hour + 59*time.Minute + 59*time.Second + 999*time.Millisecond, "99:59:59.999"},
+		// 旧文件中超过一小时的 mm:ss
+		{"60:00", time.Hour, "01:00:00.000"},
+		{"75:30", time.Hour + 15*time.Minute + 30*time.Second, "01:15:30.000"},
+		{"99:59.500", time.Hour + 39*time.Minute + 59*time.Second + 500*time.Millisecond, "01:39:59.500"},
+	}
+	for _, tt := range tests {
+		ts, err := ParseTimestamp(tt.in)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q): %v", tt.in, err)
+			continue
+		}
+		if ts.Duration() != tt.want || ts.String() != tt.wantStr {
+			t.Errorf("ParseTimestamp(%q) = %v (%s), want %v (%s)", tt.in, ts.Duration(), ts, tt.want, tt.wantStr)
+		}
+		// 格式化后的文本应解析回同一时间
+		if again, err := ParseTimestamp(ts.String()); err != nil || again != ts {
+			t.Errorf("round trip of %q: %v, %v", tt.in, again, err)
+		}
+	}
+}
+
+func TestParseTimestampErrors(t *testing.T) {
+	for _, in := range []string{
+		"", "1:30", "12:3", "12:32.7", "12:32.7666", "12:60", "01:60:00", "1:02:60.000",
+		"123:00", "100:00:00", "-01:00", "12:32,766", "aa:bb", " 12:32",
+	} {
+		if ts, err := ParseTimestamp(in); err == nil {
+			t.Errorf("ParseTimestamp(%q) = %s, want error", in, ts)
+		}
+	}
+}
+
+func TestTimestampJSON(t *testing.T) {
+	var step Step
+	if err := json.Unmarshal([]byte(`{"number":1,"timestamp":"75:30","description":"x"}`), &step); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(step.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"01:15:30.000"` {
+		t.Errorf("marshal = %s, want \"01:15:30.000\"", data)
+	}
+	if err := json.Unmarshal([]byte(`{"timestamp":"1:60:00"}`), &step); err == nil {
+		t.Error("unmarshal of invalid timestamp succeeded")
+	}
+}
+
+func TestNewTimestampTruncates(t *testing.T) {
+	ts := NewTimestamp(1500*time.Microsecond + time.Second)
+	if ts.String() != "00:01.001" {
+		t.Errorf("NewTimestamp = %s, want 00:01.001", ts)
+	}
+}
+
+func TestParseLinesLegacyHourTimestamps(t *testing.T) {
+	ann, diags, err := ParseLinesWithOptions([]string{"Title", "1) 59:00 before", "2) 75:30 after", "3) 99:59.500 last"}, ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("ParseLinesWithOptions: %v (%v)", err, diags)
+	}
+	if len(ann.Steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(ann.Steps))
+	}
+	want := "Title\n\n1) 59:00.000 before\n2) 01:15:30.000 after\n3) 01:39:59.500 last\n"
+	if got := ann.Format(); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
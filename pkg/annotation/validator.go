@@ -1,101 +1,316 @@
 package annotation
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
-// ValidateTimestamp 验证时间戳格式 (mm:ss.SSS 或 mm:ss)
-func ValidateTimestamp(timestamp string) error {
-	// 支持两种格式：mm:ss.SSS 或 mm:ss
-	pattern := regexp.MustCompile(`^\d{2}:\d{2}(\.\d{3})?$`)
-	if !pattern.MatchString(timestamp) {
-		return fmt.Errorf("invalid timestamp format, should be mm:ss.SSS (e.g., 12:32.766)")
-	}
-
-	// 验证分钟和秒数范围
-	parts := strings.Split(timestamp, ":")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid timestamp format")
-	}
-	
-	var minutes int
-	fmt.Sscanf(parts[0], "%d", &minutes)
-	
-	// 解析秒和毫秒
-	secondsParts := strings.Split(parts[1], ".")
-	var seconds int
-	fmt.Sscanf(secondsParts[0], "%d", &seconds)
-	
-	if seconds >= 60 {
-		return fmt.Errorf("seconds cannot exceed 59")
-	}
-	
-	// 如果有毫秒，验证毫秒范围
-	if len(secondsParts) > 1 {
-		var millis int
-		fmt.Sscanf(secondsParts[1], "%d", &millis)
-		if millis >= 1000 {
-			return fmt.Errorf("milliseconds cannot exceed 999")
-		}
+// ValidationIssue 表示一条校验问题
+type ValidationIssue struct {
+	Index    int      `json:"index"`    // 步骤索引（从 0 开始），-1 表示标注级问题
+	Field    string   `json:"field"`    // 出问题的字段：title、steps、number、timestamp、end、frame、description
+	Severity Severity `json:"severity"` // 严重程度
+	Message  string   `json:"message"`  // 问题描述
+}
+
+// Error 实现 error 接口，步骤级问题带上步骤序号
+func (i ValidationIssue) Error() string {
+	if i.Index >= 0 {
+		return fmt.Sprintf("step %d validation failed: %s", i.Index+1, i.Message)
 	}
+	return i.Message
+}
 
-	return nil
+// ValidationReport 收集一次校验中的全部错误和警告
+type ValidationReport struct {
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
 }
 
-// ValidateStep 验证步骤格式
+// newValidationReport 创建空报告（JSON 中输出空数组而非 null）
+func newValidationReport() *ValidationReport {
+	return &ValidationReport{
+		Errors:   []ValidationIssue{},
+		Warnings: []ValidationIssue{},
+	}
+}
+
+// add 按严重程度记录问题
+func (r *ValidationReport) add(index int, field string, severity Severity, format string, args ...interface{}) {
+	issue := ValidationIssue{
+		Index:    index,
+		Field:    field,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if severity == SeverityError {
+		r.Errors = append(r.Errors, issue)
+	} else {
+		r.Warnings = append(r.Warnings, issue)
+	}
+}
+
+// Valid 判断报告中是否没有错误（警告不影响）
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Err 返回第一个错误，没有错误时返回 nil
+func (r *ValidationReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return r.Errors[0]
+}
+
+// ValidateTimestamp 验证时间戳格式 (mm:ss(.SSS) 或 hh:mm:ss(.SSS))
+func ValidateTimestamp(timestamp string) error {
+	_, err := ParseTimestamp(timestamp)
+	return err
+}
+
+// ValidateStep 验证步骤格式，返回第一个问题
 func ValidateStep(step Step) error {
+	report := newValidationReport()
+	validateStep(report, -1, step)
+	if len(report.Errors) > 0 {
+		return errors.New(report.Errors[0].Message)
+	}
+	return nil
+}
+
+// validateStep 验证单个步骤，问题记录到 report
+func validateStep(report *ValidationReport, index int, step Step) {
 	if step.Number <= 0 {
-		return fmt.Errorf("step number must be greater than 0")
+		report.add(index, "number", SeverityError, "step number must be greater than 0")
 	}
 
-	if err := ValidateTimestamp(step.Timestamp); err != nil {
-		return err
+	if step.Timestamp < 0 {
+		report.add(index, "timestamp", SeverityError, "timestamp cannot be negative")
+	}
+
+	if step.End != nil && *step.End <= step.Timestamp {
+		report.add(index, "end", SeverityError, "end timestamp %s must be later than start timestamp %s", *step.End, step.Timestamp)
+	}
+
+	if step.Frame != nil && *step.Frame < 0 {
+		report.add(index, "frame", SeverityError, "frame index cannot be negative")
 	}
 
 	if strings.TrimSpace(step.Description) == "" {
-		return fmt.Errorf("step description cannot be empty")
+		report.add(index, "description", SeverityError, "step description cannot be empty")
 	}
+}
 
-	return nil
+// OrderRule 表示时间戳顺序规则
+type OrderRule string
+
+const (
+	OrderOff       OrderRule = "off"        // 不检查顺序
+	OrderNonStrict OrderRule = "non-strict" // 非递减：允许相同时间戳
+	OrderStrict    OrderRule = "strict"     // 严格递增：不允许相同时间戳
+)
+
+// ValidateOptions 控制标注验证规则
+// 规则的严重程度为空时表示关闭该规则
+type ValidateOptions struct {
+	AllowOverlap bool // 是否允许步骤区间重叠（仅对显式给出结束时间的步骤生效）
+
+	TimestampOrder OrderRule // 时间戳顺序规则
+	OrderSeverity  Severity  // 违反顺序规则时的严重程度
+
+	MinGap         time.Duration // 相邻步骤的最小时间间隔，0 表示不检查
+	MinGapSeverity Severity      // 间隔过小时的严重程度
+
+	DuplicateDescriptions Severity // 步骤描述重复时的严重程度
+
+	VideoDuration   time.Duration // 视频实际时长，0 表示未知（不检查）
+	PastEndSeverity Severity      // 步骤时间超出视频时长时的严重程度
+
+	SceneChanges        []time.Duration // 视频中的场景切换点（由关键帧分布推测），为空时不检查
+	SceneChangeDistance time.Duration   // 步骤与最近场景切换点的最大距离，0 表示不检查
+	SceneChangeSeverity Severity        // 距离过远时的严重程度
 }
 
-// ValidateAnnotation 验证标注内容
+// DefaultValidateOptions 返回推荐的校验规则：
+// 时间戳须严格递增、描述不应重复，均以警告形式报告，不阻止保存；
+// 已知视频时长时，超出视频结尾的步骤视为错误
+func DefaultValidateOptions() ValidateOptions {
+	return ValidateOptions{
+		TimestampOrder:        OrderStrict,
+		OrderSeverity:         SeverityWarning,
+		MinGapSeverity:        SeverityWarning,
+		DuplicateDescriptions: SeverityWarning,
+		PastEndSeverity:       SeverityError,
+		SceneChangeSeverity:   SeverityWarning,
+	}
+}
+
+// ValidateAnnotation 使用默认规则（见 DefaultValidateOptions）验证标注内容，返回第一个错误
 func ValidateAnnotation(ann *Annotation) error {
+	return ValidateAnnotationWithOptions(ann, DefaultValidateOptions())
+}
+
+// ValidateAnnotationWithOptions 按指定规则验证标注内容，返回第一个错误
+// 需要全部问题时使用 Validate
+func ValidateAnnotationWithOptions(ann *Annotation, opts ValidateOptions) error {
+	return Validate(ann, opts).Err()
+}
+
+// Validate 按指定规则验证标注内容，返回包含全部错误和警告的报告
+func Validate(ann *Annotation, opts ValidateOptions) *ValidationReport {
+	report := newValidationReport()
+
 	if ann == nil {
-		return fmt.Errorf("annotation cannot be nil")
+		report.add(-1, "", SeverityError, "annotation cannot be nil")
+		return report
 	}
 
 	if !ann.IsTutorial {
 		// 非教学视频，无需验证
-		return nil
+		return report
 	}
 
 	// 教学视频验证
 	if strings.TrimSpace(ann.Title) == "" {
-		return fmt.Errorf("tutorial title cannot be empty")
+		report.add(-1, "title", SeverityError, "tutorial title cannot be empty")
 	}
 
 	if len(ann.Title) > 100 {
-		return fmt.Errorf("tutorial title cannot exceed 100 characters")
+		report.add(-1, "title", SeverityError, "tutorial title cannot exceed 100 characters")
 	}
 
 	if len(ann.Steps) == 0 {
-		return fmt.Errorf("at least one step is required")
+		report.add(-1, "steps", SeverityError, "at least one step is required")
 	}
 
 	// 验证每个步骤
 	for i, step := range ann.Steps {
-		if err := ValidateStep(step); err != nil {
-			return fmt.Errorf("step %d validation failed: %w", i+1, err)
-		}
+		validateStep(report, i, step)
 
 		// 验证步骤编号是否连续
-		if step.Number != i+1 {
-			return fmt.Errorf("step numbers not consecutive, expected %d, got %d", i+1, step.Number)
+		if step.Number > 0 && step.Number != i+1 {
+			report.add(i, "number", SeverityError, "step numbers not consecutive, expected %d, got %d", i+1, step.Number)
 		}
 	}
 
-	return nil
+	// 验证步骤区间是否重叠
+	if !opts.AllowOverlap {
+		validateOverlap(report, ann.Steps)
+	}
+
+	validateOrder(report, ann.Steps, opts)
+	validateDuplicateDescriptions(report, ann.Steps, opts.DuplicateDescriptions)
+	validateVideoDuration(report, ann.Steps, opts.VideoDuration, opts.PastEndSeverity)
+	validateSceneChanges(report, ann.Steps, opts)
+
+	return report
+}
+
+// validateVideoDuration 检查步骤时间是否超出视频时长
+func validateVideoDuration(report *ValidationReport, steps []Step, duration time.Duration, severity Severity) {
+	if duration <= 0 || severity == "" {
+		return
+	}
+
+	end := NewTimestamp(duration)
+	for i, step := range steps {
+		if step.Timestamp >= end {
+			report.add(i, "timestamp", severity, "timestamp %s is past the end of the video (%s)", step.Timestamp, end)
+		} else if step.End != nil && *step.End > end {
+			report.add(i, "end", severity, "end timestamp %s is past the end of the video (%s)", *step.End, end)
+		}
+	}
+}
+
+// validateSceneChanges 检查步骤开始时间是否远离所有场景切换点
+func validateSceneChanges(report *ValidationReport, steps []Step, opts ValidateOptions) {
+	changes := opts.SceneChanges
+	if len(changes) == 0 || opts.SceneChangeDistance <= 0 || opts.SceneChangeSeverity == "" {
+		return
+	}
+
+	for i, step := range steps {
+		t := step.Timestamp.Duration()
+		k := sort.Search(len(changes), func(k int) bool { return changes[k] >= t })
+		nearest := -1
+		if k < len(changes) {
+			nearest = k
+		}
+		if k > 0 && (nearest < 0 || t-changes[k-1] < changes[k]-t) {
+			nearest = k - 1
+		}
+
+		distance := changes[nearest] - t
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance > opts.SceneChangeDistance {
+			report.add(i, "timestamp", opts.SceneChangeSeverity, "timestamp %s is %s away from the nearest scene change at %s", step.Timestamp, distance.Round(time.Millisecond), NewTimestamp(changes[nearest]))
+		}
+	}
+}
+
+// validateOrder 检查相邻步骤的时间戳顺序和最小间隔
+func validateOrder(report *ValidationReport, steps []Step, opts ValidateOptions) {
+	for i := 1; i < len(steps); i++ {
+		prev, cur := steps[i-1], steps[i]
+		gap := cur.Timestamp.Duration() - prev.Timestamp.Duration()
+
+		if opts.OrderSeverity != "" {
+			switch {
+			case opts.TimestampOrder == OrderStrict && gap == 0:
+				report.add(i, "timestamp", opts.OrderSeverity, "timestamp %s duplicates step %d", cur.Timestamp, prev.Number)
+				continue
+			case (opts.TimestampOrder == OrderStrict || opts.TimestampOrder == OrderNonStrict) && gap < 0:
+				report.add(i, "timestamp", opts.OrderSeverity, "timestamp %s is earlier than step %d (%s)", cur.Timestamp, prev.Number, prev.Timestamp)
+				continue
+			}
+		}
+
+		if opts.MinGap > 0 && opts.MinGapSeverity != "" && gap >= 0 && gap < opts.MinGap {
+			report.add(i, "timestamp", opts.MinGapSeverity, "only %s after step %d, minimum gap is %s", gap, prev.Number, opts.MinGap)
+		}
+	}
+}
+
+// validateDuplicateDescriptions 检查重复的步骤描述（忽略大小写和首尾空白）
+func validateDuplicateDescriptions(report *ValidationReport, steps []Step, severity Severity) {
+	if severity == "" {
+		return
+	}
+
+	seen := make(map[string]int)
+	for i, step := range steps {
+		key := strings.ToLower(strings.TrimSpace(step.Description))
+		if key == "" {
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			report.add(i, "description", severity, "description duplicates step %d", steps[first].Number)
+			continue
+		}
+		seen[key] = i
+	}
+}
+
+// validateOverlap 检查显式结束时间是否越过后续步骤的开始时间
+func validateOverlap(report *ValidationReport, steps []Step) {
+	order := make([]int, len(steps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return steps[order[a]].Timestamp < steps[order[b]].Timestamp
+	})
+
+	for k := 0; k+1 < len(order); k++ {
+		cur, next := steps[order[k]], steps[order[k+1]]
+		if cur.End != nil && *cur.End > next.Timestamp {
+			report.add(order[k], "end", SeverityError, "step %d (%s-%s) overlaps step %d starting at %s", cur.Number, cur.Timestamp, *cur.End, next.Number, next.Timestamp)
+		}
+	}
 }
@@ -0,0 +1,196 @@
+package annotation
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// issueFields 返回报告中每条问题的 "索引:字段"，便于比较
+func issueFields(issues []ValidationIssue) []string {
+	fields := []string{}
+	for _, i := range issues {
+		fields = append(fields, strconv.Itoa(i.Index+1)+":"+i.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	sec := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	defaults := DefaultValidateOptions()
+	tests := []struct {
+		name         string
+		lines        []string
+		opts         ValidateOptions
+		wantErrors   []string // "步骤序号:字段"，0 表示标注级问题
+		wantWarnings []string
+	}{
+		{"valid", []string{"Title", "1) 00:01 a", "2) 00:02 b"}, defaults, nil, nil},
+		{"not tutorial", []string{"[not tutorial]"}, defaults, nil, nil},
+		{"missing title and steps", []string{""}, defaults, []string{"0:title", "0:steps"}, nil},
+		// 所有错误都被收集，而不是在第一个错误处停止
+		{"all errors collected", []string{"Title", "1) 00:05-00:04 a", "3) 00:06 b", "4) 00:07 c"}, defaults,
+			[]string{"1:end", "2:number", "3:number"}, nil},
+		{"overlap", []string{"Title", "1) 00:01-00:03 a", "2) 00:02 b"}, defaults, []string{"1:end"}, nil},
+		{"overlap allowed", []string{"Title", "1) 00:01-00:03 a", "2) 00:02 b"}, ValidateOptions{AllowOverlap: true}, nil, nil},
+		{"strict order", []string{"Title", "1) 00:05 a", "2) 00:05 b", "3) 00:01 c"}, defaults,
+			nil, []string{"2:timestamp", "3:timestamp"}},
+		{"non-strict order", []string{"Title", "1) 00:05 a", "2) 00:05 b", "3) 00:01 c"},
+			ValidateOptions{TimestampOrder: OrderNonStrict, OrderSeverity: SeverityError}, []string{"3:timestamp"}, nil},
+		{"order off", []string{"Title", "1) 00:05 a", "2) 00:01 b"}, ValidateOptions{TimestampOrder: OrderOff, OrderSeverity: SeverityError}, nil, nil},
+		{"min gap", []string{"Title", "1) 00:01 a", "2) 00:01.500 b", "3) 00:05 c"},
+			ValidateOptions{MinGap: time.Second, MinGapSeverity: SeverityWarning}, nil, []string{"2:timestamp"}},
+		{"duplicate descriptions", []string{"Title", "1) 00:01 Open", "2) 00:02  open ", "3) 00:03 close"}, defaults,
+			nil, []string{"2:description"}},
+		{"past end", []string{"Title", "1) 00:01-00:11 a", "2) 00:20 b"},
+			ValidateOptions{AllowOverlap: true, VideoDuration: sec(10.5), PastEndSeverity: SeverityError}, []string{"1:end", "2:timestamp"}, nil},
+		{"scene changes", []string{"Title", "1) 00:01 a", "2) 00:05 b", "3) 00:30 c"},
+			ValidateOptions{SceneChanges: []time.Duration{sec(1.2), sec(20)}, SceneChangeDistance: time.Second, SceneChangeSeverity: SeverityWarning},
+			nil, []string{"2:timestamp", "3:timestamp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ann, err := ParseLines(tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := Validate(ann, tt.opts)
+			if tt.wantErrors == nil {
+				tt.wantErrors = []string{}
+			}
+			if tt.wantWarnings == nil {
+				tt.wantWarnings = []string{}
+			}
+			if got := issueFields(report.Errors); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors = %v (%v), want %v", got, report.Errors, tt.wantErrors)
+			}
+			if got := issueFields(report.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v (%v), want %v", got, report.Warnings, tt.wantWarnings)
+			}
+			if report.Valid() != (len(tt.wantErrors) == 0) || (report.Err() == nil) != report.Valid() {
+				t.Errorf("Valid() = %v, Err() = %v", report.Valid(), report.Err())
+			}
+		})
+	}
+}
+
+func TestValidateAnnotation(t *testing.T) {
+	// 警告不影响 ValidateAnnotation 的结果
+	ann, err := ParseLines([]string{"Title", "1) 00:05 same", "2) 00:01 same"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateAnnotation(ann); err != nil {
+		t.Errorf("ValidateAnnotation = %v, want nil", err)
+	}
+	if err := ValidateAnnotation(nil); err == nil {
+		t.Error("ValidateAnnotation(nil) succeeded")
+	}
+
+	ann.Steps[1].Number = 3
+	err = ValidateAnnotation(ann)
+	if want := "step 2 validation failed: step numbers not consecutive, expected 2, got 3"; err == nil || err.Error() != want {
+		t.Errorf("ValidateAnnotation = %v, want %q", err, want)
+	}
+}
+
+// issueMessages 返回报告中每条问题的 "索引:字段 描述"
+func issueMessages(issues []ValidationIssue) []string {
+	messages := []string{}
+	for _, i := range issues {
+		messages = append(messages, strconv.Itoa(i.Index+1)+":"+i.Field+" "+i.Message)
+	}
+	return messages
+}
+
+func TestValidateVideoDuration(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	ts := func(n int) Timestamp { return NewTimestamp(ms(n)) }
+	end := ts(10000)
+	tests := []struct {
+		name     string
+		steps    []Step
+		duration time.Duration
+		severity Severity
+		want     []string
+	}{
+		{"inside", []Step{{Timestamp: ts(1000), End: &end}, {Timestamp: ts(9999)}}, ms(10000), SeverityError, []string{}},
+		// 开始于视频结尾处的步骤已无画面；结束时间等于视频时长是允许的
+		{"start at end", []Step{{Timestamp: ts(10000)}}, ms(10000), SeverityError,
+			[]string{"1:timestamp timestamp 00:10.000 is past the end of the video (00:10.000)"}},
+		{"end past end", []Step{{Timestamp: ts(1000), End: &end}}, ms(9500), SeverityWarning,
+			[]string{"1:end end timestamp 00:10.000 is past the end of the video (00:09.500)"}},
+		// 视频时长截断到毫秒后比较，不足 1 毫秒的尾部不算
+		{"sub-millisecond duration", []Step{{Timestamp: ts(10000)}}, ms(10000) + 900*time.Microsecond, SeverityError,
+			[]string{"1:timestamp timestamp 00:10.000 is past the end of the video (00:10.000)"}},
+		// 开始已超出时只报告开始时间
+		{"start and end past end", []Step{{Timestamp: ts(12000), End: &end}}, ms(5000), SeverityError,
+			[]string{"1:timestamp timestamp 00:12.000 is past the end of the video (00:05.000)"}},
+		{"unknown duration", []Step{{Timestamp: ts(12000)}}, 0, SeverityError, []string{}},
+		{"rule off", []Step{{Timestamp: ts(12000)}}, ms(5000), "", []string{}},
+	}
+	for _, tt := range tests {
+		report := newValidationReport()
+		validateVideoDuration(report, tt.steps, tt.duration, tt.severity)
+		issues := report.Errors
+		if tt.severity == SeverityWarning {
+			issues = report.Warnings
+		}
+		if got := issueMessages(issues); !reflect.DeepEqual(got, tt.want) || len(report.Errors)+len(report.Warnings) != len(tt.want) {
+			t.Errorf("%s: issues = %v, want %v", tt.name, append(report.Errors, report.Warnings...), tt.want)
+		}
+	}
+}
+
+func TestValidateSceneChanges(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	changes := []time.Duration{ms(2000), ms(5000)}
+	tests := []struct {
+		name string
+		at   int // 步骤开始时间（毫秒）
+		want string
+	}{
+		{"on a change", 2000, ""},
+		{"within distance", 5400, ""},
+		// 第一个切换点之前和最后一个之后只有一侧可比较
+		{"before the first change", 500, "timestamp 00:00.500 is 1.5s away from the nearest scene change at 00:02.000"},
+		{"after the last change", 9000, "timestamp 00:09.000 is 4s away from the nearest scene change at 00:05.000"},
+		// 两个切换点之间取距离较近的一个，距离相等时取后一个
+		{"closer to the earlier change", 2800, "timestamp 00:02.800 is 800ms away from the nearest scene change at 00:02.000"},
+		{"closer to the later change", 4000, "timestamp 00:04.000 is 1s away from the nearest scene change at 00:05.000"},
+		{"halfway", 3500, "timestamp 00:03.500 is 1.5s away from the nearest scene change at 00:05.000"},
+	}
+	opts := ValidateOptions{SceneChanges: changes, SceneChangeDistance: ms(500), SceneChangeSeverity: SeverityWarning}
+	for _, tt := range tests {
+		report := newValidationReport()
+		validateSceneChanges(report, []Step{{Timestamp: NewTimestamp(ms(tt.at))}}, opts)
+		want := []string{}
+		if tt.want != "" {
+			want = []string{"1:timestamp " + tt.want}
+		}
+		if got := issueMessages(report.Warnings); !reflect.DeepEqual(got, want) || len(report.Errors) != 0 {
+			t.Errorf("%s: warnings = %v, errors = %v, want %v", tt.name, got, report.Errors, want)
+		}
+	}
+
+	// 只有一个切换点，或规则关闭时
+	report := newValidationReport()
+	validateSceneChanges(report, []Step{{Timestamp: NewTimestamp(ms(100))}}, ValidateOptions{
+		SceneChanges: []time.Duration{ms(3000)}, SceneChangeDistance: ms(500), SceneChangeSeverity: SeverityError,
+	})
+	if len(report.Errors) != 1 || report.Errors[0].Message != "timestamp 00:00.100 is 2.9s away from the nearest scene change at 00:03.000" {
+		t.Errorf("single change: errors = %v", report.Errors)
+	}
+	for _, off := range []ValidateOptions{
+		{SceneChangeDistance: ms(500), SceneChangeSeverity: SeverityWarning},
+		{SceneChanges: changes, SceneChangeSeverity: SeverityWarning},
+		{SceneChanges: changes, SceneChangeDistance: ms(500)},
+	} {
+		report := newValidationReport()
+		validateSceneChanges(report, []Step{{Timestamp: NewTimestamp(ms(9000))}}, off)
+		if len(report.Errors)+len(report.Warnings) != 0 {
+			t.Errorf("validateSceneChanges(%+v) reported %v %v", off, report.Errors, report.Warnings)
+		}
+	}
+}
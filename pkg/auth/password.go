@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 密码哈希参数（PBKDF2-HMAC-SHA256），参数随哈希一起保存，调整后旧密码仍可验证
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 210000
+	saltLength     = 16
+	keyLength      = 32
+	// maxHashIterations 验证时接受的最大迭代次数，防止被篡改的用户文件让每次登录耗尽 CPU
+	maxHashIterations = 10 * hashIterations
+
+	// MinPasswordLength 密码最小长度
+	MinPasswordLength = 8
+)
+
+// dummyHash 用户不存在时参与验证的哈希，使其耗时与验证真实用户相同，避免按响应时间猜测用户名
+var dummyHash = fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIterations,
+	base64.RawStdEncoding.EncodeToString(make([]byte, saltLength)),
+	base64.RawStdEncoding.EncodeToString(make([]byte, keyLength)))
+
+// HashPassword 生成密码哈希，格式为 pbkdf2-sha256$迭代次数$盐$哈希（盐和哈希为 base64）
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := pbkdf2SHA256([]byte(password), salt, hashIterations, keyLength)
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword 判断密码与哈希是否匹配，哈希格式无效或迭代次数超过上限时返回 false
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 || iterations > maxHashIterations {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := pbkdf2SHA256([]byte(password), salt, iterations, len(want))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// pbkdf2SHA256 按 RFC 8018 计算 PBKDF2-HMAC-SHA256
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		key = prf.Sum(key)
+		t := key[len(key)-hashLen:]
+		copy(u, t)
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return key[:keyLen]
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionCookie 是保存会话令牌的 Cookie 名称
+const SessionCookie = "mp4label_session"
+
+// session 表示一个登录会话
+type session struct {
+	userID  string
+	expires time.Time
+}
+
+// SessionStore 在内存中保存登录会话，服务重启后需要重新登录
+type SessionStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]session // 令牌 -> 会话
+}
+
+// NewSessionStore 创建会话存储，ttl 为登录有效期
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	return &SessionStore{ttl: ttl, sessions: make(map[string]session)}
+}
+
+// Create 为用户创建新会话，返回令牌和过期时间
+func (s *SessionStore) Create(userID string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 顺便清理已过期的会话
+	now := time.Now()
+	for t, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = session{userID: userID, expires: expires}
+	return token, expires, nil
+}
+
+// Lookup 返回令牌对应的用户，令牌不存在或已过期时返回 false
+func (s *SessionStore) Lookup(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[token]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, token)
+		return "", false
+	}
+	return sess.userID, true
+}
+
+// Delete 删除会话（退出登录）
+func (s *SessionStore) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionStore(t *testing.T) {
+	s := NewSessionStore(time.Hour)
+	token, expires, err := s.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 || time.Until(expires) <= 0 {
+		t.Errorf("Create = %q, %v", token, expires)
+	}
+	if other, _, _ := s.Create("alice"); other == token {
+		t.Error("two sessions share a token")
+	}
+
+	if user, ok := s.Lookup(token); !ok || user != "alice" {
+		t.Errorf("Lookup = %q, %v; want alice", user, ok)
+	}
+	if _, ok := s.Lookup("unknown"); ok {
+		t.Error("Lookup of an unknown token succeeded")
+	}
+	s.Delete(token)
+	if _, ok := s.Lookup(token); ok {
+		t.Error("Lookup after Delete succeeded")
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	s := NewSessionStore(-time.Second)
+	token, _, err := s.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup(token); ok {
+		t.Error("Lookup of an expired session succeeded")
+	}
+
+	// 创建新会话时清理已过期的会话
+	expired, _, _ := s.Create("bob")
+	s.ttl = time.Hour
+	if _, _, err := s.Create("carol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.sessions[expired]; ok || len(s.sessions) != 1 {
+		t.Errorf("%d sessions left, want 1", len(s.sessions))
+	}
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCredentials 表示用户名或密码错误（不区分两者，避免泄露用户是否存在）
+var ErrInvalidCredentials = errors.New("invalid user name or password")
+
+// Identity 表示发起请求的标注人员
+type Identity struct {
+	ID    string `json:"id"`             // 登录名，记录在标注元数据中
+	Name  string `json:"name,omitempty"` // 显示名称
+	Admin bool   `json:"admin"`          // 管理员可以修改配置
+}
+
+// DisplayName 返回显示名称，未设置时为登录名
+func (i *Identity) DisplayName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	return i.ID
+}
+
+// User 表示用户文件中的一个用户
+type User struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	Admin        bool      `json:"admin,omitempty"`
+	PasswordHash string    `json:"password_hash"` // 见 HashPassword
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// usersFile 是用户文件的磁盘格式
+type usersFile struct {
+	Users []User `json:"users"`
+}
+
+// UserStore 是基于 JSON 文件的本地用户库
+// 文件被其他进程（如 mp4label users 命令）修改后，下次查询时自动重新加载
+type UserStore struct {
+	mu      sync.Mutex
+	path    string
+	users   map[string]*User
+	modTime time.Time // 最近一次加载时文件的修改时间
+}
+
+// LoadUserStore 加载用户文件，文件不存在时返回空用户库
+func LoadUserStore(path string) (*UserStore, error) {
+	s := &UserStore{path: path, users: make(map[string]*User)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload 在文件修改时间变化时重新读取用户文件，调用时需持有锁（LoadUserStore 除外）
+func (s *UserStore) reload() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.users = make(map[string]*User)
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read users file: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read users file: %w", err)
+	}
+	var file usersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse users file %s: %w", s.path, err)
+	}
+	users := make(map[string]*User, len(file.Users))
+	for i := range file.Users {
+		u := file.Users[i]
+		users[u.ID] = &u
+	}
+	s.users = users
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// Authenticate 验证用户名和密码，成功时返回用户身份
+func (s *UserStore) Authenticate(id, password string) (*Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	u := s.users[id]
+	if u == nil {
+		// 用户不存在时同样计算一次哈希，登录耗时不暴露用户是否存在
+		CheckPassword(dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if !CheckPassword(u.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return u.identity(), nil
+}
+
+// Lookup 返回用户的当前身份，用户已被删除时返回 nil
+func (s *UserStore) Lookup(id string) *Identity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil
+	}
+	if u := s.users[id]; u != nil {
+		return u.identity()
+	}
+	return nil
+}
+
+// Users 返回所有用户（按登录名排序）
+func (s *UserStore) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// SetUser 添加或更新用户；password 为空时保留原密码（新用户必须设置密码）
+func (s *UserStore) SetUser(id, name, password string, admin bool) error {
+	if !ValidUserID(id) {
+		return fmt.Errorf("invalid user id %q: use letters, digits, '.', '_', '-' or '@'", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[id]
+	if u == nil {
+		if password == "" {
+			return fmt.Errorf("password is required for new user %s", id)
+		}
+		u = &User{ID: id, CreatedAt: time.Now().UTC()}
+	}
+	updated := *u
+	updated.Name = name
+	updated.Admin = admin
+	if password != "" {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return err
+		}
+		updated.PasswordHash = hash
+	}
+	s.users[id] = &updated
+	return nil
+}
+
+// Remove 删除用户
+func (s *UserStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users[id] == nil {
+		return fmt.Errorf("user %s does not exist", id)
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// Save 写回用户文件（仅所有者可读写）
+func (s *UserStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := usersFile{Users: make([]User, 0, len(s.users))}
+	for _, u := range s.users {
+		file.Users = append(file.Users, *u)
+	}
+	sort.Slice(file.Users, func(i, j int) bool { return file.Users[i].ID < file.Users[j].ID })
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize users: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create users directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save users file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save users file: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// identity 返回用户身份
+func (u *User) identity() *Identity {
+	return &Identity{ID: u.ID, Name: u.Name, Admin: u.Admin}
+}
+
+// ValidUserID 判断登录名是否有效：1-64 个字母、数字或 . _ - @
+// 登录名会写入标注元数据和提交记录，因此不允许空白和控制字符
+func ValidUserID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
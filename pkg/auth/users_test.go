@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUserStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth", "users.json")
+	s, err := LoadUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetUser("alice", "Alice", "", false); err == nil {
+		t.Error("SetUser without a password for a new user succeeded")
+	}
+	if err := s.SetUser("bad id", "", "password1", false); err == nil {
+		t.Error("SetUser with an invalid id succeeded")
+	}
+	if err := s.SetUser("alice", "Alice", "password1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetUser("bob@example.com", "", "password2", false); err != nil {
+		t.Fatal(err)
+	}
+	// 密码为空时保留原密码
+	if err := s.SetUser("alice", "", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("users file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// 另一个进程（如 mp4label users 命令）看到的用户库
+	other, err := LoadUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := other.Authenticate("alice", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != "alice" || id.Admin || id.DisplayName() != "alice" {
+		t.Errorf("Authenticate = %+v", id)
+	}
+	for _, c := range [][2]string{{"alice", "password2"}, {"carol", "password1"}} {
+		if _, err := other.Authenticate(c[0], c[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%s, %s) error = %v, want ErrInvalidCredentials", c[0], c[1], err)
+		}
+	}
+
+	// 文件被修改后自动重新加载
+	if err := s.Remove("bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("bob@example.com"); err == nil {
+		t.Error("removing a missing user succeeded")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if id := other.Lookup("bob@example.com"); id != nil {
+		t.Errorf("Lookup of a removed user = %+v", id)
+	}
+	if users := other.Users(); len(users) != 1 || users[0].ID != "alice" {
+		t.Errorf("Users = %+v", users)
+	}
+}
+
+func TestValidUserID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"alice", true},
+		{"a.b_c-d@example.com", true},
+		{"", false},
+		{"has space", false},
+		{"名字", false},
+		{"line\nbreak", false},
+		{string(make([]byte, 65)), false},
+	}
+	for _, tt := range tests {
+		if got := ValidUserID(tt.id); got != tt.want {
+			t.Errorf("ValidUserID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/video"
 )
 
 // Config 表示应用配置
@@ -15,6 +19,62 @@ type Config struct {
 	OutputDir          string `json:"output_dir"`           // 输出目录
 	TaskFile           string `json:"task_file"`            // 子任务文件（可选），用于指定要标注的视频列表
 	ModelAnnotationDir string `json:"model_annotation_dir"` // 模型标注目录（可选），用于算法人员对比模型标注效果
+
+	VideoExtensions      []string `json:"video_extensions"`       // 扫描的视频扩展名（如 .mp4、.mov），为空时只扫描 .mp4
+	ScanIntervalSeconds  int      `json:"scan_interval_seconds"`  // 后台重新扫描视频目录的间隔（秒），0 表示默认 60 秒
+	WatchIntervalSeconds int      `json:"watch_interval_seconds"` // 轮询标注目录变化的间隔（秒），0 表示默认 2 秒
+
+	OutputFormat          string          `json:"output_format"`           // 标注输出格式：txt（默认）或 json
+	AllowOverlappingSteps bool            `json:"allow_overlapping_steps"` // 是否允许带结束时间的步骤区间相互重叠
+	SnapToFrames          bool            `json:"snap_to_frames"`          // 保存时是否将步骤时间对齐到最接近的视频帧
+	Validation            ValidationRules `json:"validation"`              // 保存时的校验规则
+
+	Auth             AuthConfig `json:"auth"`               // 多用户模式（登录和标注人员记录）
+	LockLeaseSeconds int        `json:"lock_lease_seconds"` // 多用户模式下编辑锁的租约时长（秒），0 表示默认 300 秒
+
+	Git GitConfig `json:"git"` // 将输出目录中的标注变化提交到 git 仓库（可选）
+}
+
+// GitConfig 表示输出目录的 git 存储配置，只能通过配置文件修改，服务启动时生效
+type GitConfig struct {
+	Enabled      bool   `json:"enabled,omitempty"`       // 每次保存、删除和回滚都提交到输出目录所在的 git 仓库（不是仓库时自动初始化）
+	BatchMinutes int    `json:"batch_minutes,omitempty"` // 每隔 N 分钟合并提交一次，0 表示每次修改立即提交
+	EmailDomain  string `json:"email_domain,omitempty"`  // 提交作者邮箱的域名（登录名@域名），默认 mp4label.local
+}
+
+// 认证模式
+const (
+	AuthNone  = ""      // 单用户，不需要登录（默认）
+	AuthLocal = "local" // 本地用户文件，用户名和密码登录
+	AuthProxy = "proxy" // 信任反向代理在请求头中传入的用户名
+)
+
+// AuthConfig 表示多用户模式的配置，只能通过配置文件修改
+type AuthConfig struct {
+	Mode         string   `json:"mode,omitempty"`          // AuthNone、AuthLocal 或 AuthProxy
+	UsersFile    string   `json:"users_file,omitempty"`    // local 模式的用户文件，默认为配置目录下的 users.json
+	ProxyHeader  string   `json:"proxy_header,omitempty"`  // proxy 模式下携带用户名的请求头，默认 X-Forwarded-User
+	ProxyAdmins  []string `json:"proxy_admins,omitempty"`  // proxy 模式下的管理员用户名
+	SessionHours int      `json:"session_hours,omitempty"` // local 模式登录有效期（小时），0 表示默认 12 小时
+}
+
+// ValidationRules 表示可配置的校验规则，字段为空时使用默认值
+// 严重程度取值：off（关闭）、warning（仅提示）、error（阻止保存）
+type ValidationRules struct {
+	TimestampOrder        string `json:"timestamp_order,omitempty"`          // strict（严格递增，默认）、non-strict（非递减）或 off
+	OrderSeverity         string `json:"order_severity,omitempty"`           // 默认 warning
+	MinGapMs              int    `json:"min_gap_ms,omitempty"`               // 相邻步骤最小间隔（毫秒），0 表示不检查
+	MinGapSeverity        string `json:"min_gap_severity,omitempty"`         // 默认 warning
+	DuplicateDescriptions string `json:"duplicate_descriptions,omitempty"`   // 默认 warning
+	PastEndSeverity       string `json:"past_end_severity,omitempty"`        // 步骤超出视频时长，默认 error
+	SceneChangeDistanceMs int    `json:"scene_change_distance_ms,omitempty"` // 步骤与最近场景切换点的最大距离（毫秒），0 表示不检查
+	SceneChangeSeverity   string `json:"scene_change_severity,omitempty"`    // 默认 warning
+}
+
+// 支持的标注输出格式
+var outputFormats = map[string]bool{
+	"txt":  true,
+	"json": true,
 }
 
 var defaultConfig = Config{
@@ -36,6 +96,15 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, "config.json"), nil
 }
 
+// GetCacheDir 获取缓存目录（位于配置文件所在目录下）
+func GetCacheDir() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "cache"), nil
+}
+
 // Load 加载配置
 func Load() (*Config, error) {
 	configPath, err := GetConfigPath()
@@ -100,6 +169,185 @@ func CleanPath(path string) string {
 	return path
 }
 
+// AnnotationExt 返回输出标注文件的扩展名（含点），默认 ".txt"
+func (c *Config) AnnotationExt() string {
+	if c.OutputFormat == "" {
+		return ".txt"
+	}
+	return "." + c.OutputFormat
+}
+
+// DefaultScanInterval 未配置时后台重新扫描的间隔
+const DefaultScanInterval = 60 * time.Second
+
+// ScanInterval 返回后台重新扫描视频目录的间隔
+func (c *Config) ScanInterval() time.Duration {
+	if c.ScanIntervalSeconds <= 0 {
+		return DefaultScanInterval
+	}
+	return time.Duration(c.ScanIntervalSeconds) * time.Second
+}
+
+// DefaultWatchInterval 未配置时轮询标注目录的间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchInterval 返回轮询标注目录变化的间隔
+func (c *Config) WatchInterval() time.Duration {
+	if c.WatchIntervalSeconds <= 0 {
+		return DefaultWatchInterval
+	}
+	return time.Duration(c.WatchIntervalSeconds) * time.Second
+}
+
+// DefaultLockLease 未配置时编辑锁的租约时长
+const DefaultLockLease = 5 * time.Minute
+
+// LockLease 返回编辑锁的租约时长，持有者需要在到期前续期
+func (c *Config) LockLease() time.Duration {
+	if c.LockLeaseSeconds <= 0 {
+		return DefaultLockLease
+	}
+	return time.Duration(c.LockLeaseSeconds) * time.Second
+}
+
+// Domain 返回提交作者邮箱的域名
+func (g GitConfig) Domain() string {
+	if g.EmailDomain == "" {
+		return "mp4label.local"
+	}
+	return g.EmailDomain
+}
+
+// BatchInterval 返回合并提交的间隔，0 表示每次修改立即提交
+func (g GitConfig) BatchInterval() time.Duration {
+	if g.BatchMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(g.BatchMinutes) * time.Minute
+}
+
+// Enabled 判断是否启用了多用户模式
+func (a AuthConfig) Enabled() bool {
+	return a.Mode != AuthNone
+}
+
+// UsersPath 返回本地用户文件路径
+func (a AuthConfig) UsersPath() (string, error) {
+	if a.UsersFile != "" {
+		return a.UsersFile, nil
+	}
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "users.json"), nil
+}
+
+// Header 返回 proxy 模式下携带用户名的请求头
+func (a AuthConfig) Header() string {
+	if a.ProxyHeader == "" {
+		return "X-Forwarded-User"
+	}
+	return a.ProxyHeader
+}
+
+// DefaultSessionTTL 未配置时的登录有效期
+const DefaultSessionTTL = 12 * time.Hour
+
+// SessionTTL 返回登录有效期
+func (a AuthConfig) SessionTTL() time.Duration {
+	if a.SessionHours <= 0 {
+		return DefaultSessionTTL
+	}
+	return time.Duration(a.SessionHours) * time.Hour
+}
+
+// Validate 验证认证配置
+func (a AuthConfig) Validate() error {
+	switch a.Mode {
+	case AuthNone, AuthLocal, AuthProxy:
+	default:
+		return fmt.Errorf("invalid auth mode: %s", a.Mode)
+	}
+	if a.SessionHours < 0 {
+		return fmt.Errorf("auth session_hours cannot be negative")
+	}
+	return nil
+}
+
+// ScanOptions 根据配置生成视频扫描选项
+func (c *Config) ScanOptions() video.ScanOptions {
+	return video.ScanOptions{Extensions: c.VideoExtensions}
+}
+
+// ValidateOptions 根据配置生成校验选项
+func (c *Config) ValidateOptions() annotation.ValidateOptions {
+	opts := annotation.DefaultValidateOptions()
+	opts.AllowOverlap = c.AllowOverlappingSteps
+
+	rules := c.Validation
+	if rules.TimestampOrder != "" {
+		opts.TimestampOrder = annotation.OrderRule(rules.TimestampOrder)
+	}
+	if rules.OrderSeverity != "" {
+		opts.OrderSeverity = ruleSeverity(rules.OrderSeverity)
+	}
+	opts.MinGap = time.Duration(rules.MinGapMs) * time.Millisecond
+	if rules.MinGapSeverity != "" {
+		opts.MinGapSeverity = ruleSeverity(rules.MinGapSeverity)
+	}
+	if rules.DuplicateDescriptions != "" {
+		opts.DuplicateDescriptions = ruleSeverity(rules.DuplicateDescriptions)
+	}
+	if rules.PastEndSeverity != "" {
+		opts.PastEndSeverity = ruleSeverity(rules.PastEndSeverity)
+	}
+	opts.SceneChangeDistance = time.Duration(rules.SceneChangeDistanceMs) * time.Millisecond
+	if rules.SceneChangeSeverity != "" {
+		opts.SceneChangeSeverity = ruleSeverity(rules.SceneChangeSeverity)
+	}
+	return opts
+}
+
+// ruleSeverity 将配置中的严重程度转换为校验使用的值，off 对应空（关闭）
+func ruleSeverity(s string) annotation.Severity {
+	if s == "off" {
+		return ""
+	}
+	return annotation.Severity(s)
+}
+
+// Validate 验证校验规则取值
+func (r ValidationRules) Validate() error {
+	switch r.TimestampOrder {
+	case "", string(annotation.OrderStrict), string(annotation.OrderNonStrict), string(annotation.OrderOff):
+	default:
+		return fmt.Errorf("invalid timestamp_order: %s", r.TimestampOrder)
+	}
+
+	for name, value := range map[string]string{
+		"order_severity":         r.OrderSeverity,
+		"min_gap_severity":       r.MinGapSeverity,
+		"duplicate_descriptions": r.DuplicateDescriptions,
+		"past_end_severity":      r.PastEndSeverity,
+		"scene_change_severity":  r.SceneChangeSeverity,
+	} {
+		switch value {
+		case "", "off", string(annotation.SeverityWarning), string(annotation.SeverityError):
+		default:
+			return fmt.Errorf("invalid %s: %s", name, value)
+		}
+	}
+
+	if r.MinGapMs < 0 {
+		return fmt.Errorf("min_gap_ms cannot be negative")
+	}
+	if r.SceneChangeDistanceMs < 0 {
+		return fmt.Errorf("scene_change_distance_ms cannot be negative")
+	}
+	return nil
+}
+
 // Normalize 规范化配置路径
 func (c *Config) Normalize() {
 	c.VideoDir = CleanPath(c.VideoDir)
@@ -107,6 +355,10 @@ func (c *Config) Normalize() {
 	c.OutputDir = CleanPath(c.OutputDir)
 	c.TaskFile = CleanPath(c.TaskFile)
 	c.ModelAnnotationDir = CleanPath(c.ModelAnnotationDir)
+	c.OutputFormat = strings.ToLower(strings.TrimSpace(c.OutputFormat))
+	for i, ext := range c.VideoExtensions {
+		c.VideoExtensions[i] = video.NormalizeExtension(ext)
+	}
 }
 
 // Validate 验证配置
@@ -122,6 +374,36 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output directory cannot be empty")
 	}
 
+	if c.OutputFormat != "" && !outputFormats[c.OutputFormat] {
+		return fmt.Errorf("unsupported output format: %s", c.OutputFormat)
+	}
+
+	for _, ext := range c.VideoExtensions {
+		if ext == "" || ext == "." || strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("invalid video extension: %q", ext)
+		}
+	}
+
+	if c.ScanIntervalSeconds < 0 {
+		return fmt.Errorf("scan_interval_seconds cannot be negative")
+	}
+	if c.WatchIntervalSeconds < 0 {
+		return fmt.Errorf("watch_interval_seconds cannot be negative")
+	}
+	if c.LockLeaseSeconds < 0 {
+		return fmt.Errorf("lock_lease_seconds cannot be negative")
+	}
+	if c.Git.BatchMinutes < 0 {
+		return fmt.Errorf("git batch_minutes cannot be negative")
+	}
+
+	if err := c.Validation.Validate(); err != nil {
+		return err
+	}
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+
 	// 验证目录是否存在（如果已设置）
 	if c.VideoDir != "" {
 		if _, err := os.Stat(c.VideoDir); os.IsNotExist(err) {
@@ -139,6 +421,9 @@ func (c *Config) Validate() error {
 		if _, err := os.Stat(c.TaskFile); os.IsNotExist(err) {
 			return fmt.Errorf("task file does not exist: %s", c.TaskFile)
 		}
+		if err := video.ValidateTaskFile(c.TaskFile); err != nil {
+			return fmt.Errorf("invalid task file: %w", err)
+		}
 	}
 
 	if c.ModelAnnotationDir != "" {
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// authenticator 识别多用户模式下发起请求的标注人员
+type authenticator struct {
+	cfg      config.AuthConfig
+	users    *auth.UserStore    // local 模式
+	sessions *auth.SessionStore // local 模式
+	admins   map[string]bool    // proxy 模式的管理员
+}
+
+// newAuthenticator 根据配置创建认证器，未启用多用户模式时返回 nil
+func newAuthenticator(cfg config.AuthConfig) (*authenticator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	a := &authenticator{cfg: cfg, admins: make(map[string]bool)}
+	switch cfg.Mode {
+	case config.AuthLocal:
+		path, err := cfg.UsersPath()
+		if err != nil {
+			return nil, err
+		}
+		if a.users, err = auth.LoadUserStore(path); err != nil {
+			return nil, err
+		}
+		if len(a.users.Users()) == 0 {
+			log.Printf("多用户模式：用户文件 %s 中还没有用户，请先运行 mp4label users add", path)
+		}
+		a.sessions = auth.NewSessionStore(cfg.SessionTTL())
+	case config.AuthProxy:
+		for _, id := range cfg.ProxyAdmins {
+			a.admins[id] = true
+		}
+	}
+	return a, nil
+}
+
+// identify 返回请求对应的标注人员，未登录时返回 nil
+func (a *authenticator) identify(r *http.Request) *auth.Identity {
+	if a.cfg.Mode == config.AuthProxy {
+		id := strings.TrimSpace(r.Header.Get(a.cfg.Header()))
+		if !auth.ValidUserID(id) {
+			return nil
+		}
+		return &auth.Identity{ID: id, Admin: a.admins[id]}
+	}
+
+	cookie, err := r.Cookie(auth.SessionCookie)
+	if err != nil {
+		return nil
+	}
+	userID, ok := a.sessions.Lookup(cookie.Value)
+	if !ok {
+		return nil
+	}
+	// 每次都查询用户库，被删除的用户立即失去访问权限，权限变化立即生效
+	return a.users.Lookup(userID)
+}
+
+type contextKey int
+
+const userContextKey contextKey = 0
+
+// currentUser 返回请求的标注人员，单用户模式下为 nil
+func currentUser(r *http.Request) *auth.Identity {
+	user, _ := r.Context().Value(userContextKey).(*auth.Identity)
+	return user
+}
+
+// isAdmin 判断请求者是否为管理员，单用户模式下总是 true
+func isAdmin(r *http.Request) bool {
+	user := currentUser(r)
+	return user == nil || user.Admin
+}
+
+// requireUser 包装需要登录的接口：未登录时返回 401，单用户模式下直接放行
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next(w, r)
+			return
+		}
+		user := s.auth.identify(r)
+		if user == nil {
+			http.Error(w, "Login required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	}
+}
+
+// requireAdmin 包装只允许管理员使用的接口（如修改配置），单用户模式下直接放行
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireUser(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r) {
+			http.Error(w, "Administrator required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// handleLogin 处理登录（local 模式）：校验用户名和密码，成功后设置会话 Cookie
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil || s.auth.cfg.Mode != config.AuthLocal {
+		http.Error(w, "Login is not used in this mode", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.auth.users.Authenticate(strings.TrimSpace(req.ID), req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to log in: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	token, expires, err := s.auth.sessions.Create(user.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to log in: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "user": user})
+}
+
+// handleLogout 处理退出登录：删除会话并清除 Cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil && s.auth.sessions != nil {
+		if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+			s.auth.sessions.Delete(cookie.Value)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.SessionCookie, Value: "", Path: "/", MaxAge: -1})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// handleMe 返回认证模式和当前标注人员；启用多用户模式但未登录时 user 为 null
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mode := config.AuthNone
+	var user *auth.Identity
+	if s.auth != nil {
+		mode = s.auth.cfg.Mode
+		user = s.auth.identify(r)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auth_mode": mode,
+		"user":      user,
+	})
+}
+
+// recordEdit 在标注的元数据文件中记录操作人员和时间（仅多用户模式），失败时只记录日志
+// 调用方需持有 s.saveMu
+func (s *Server) recordEdit(r *http.Request, cfg *config.Config, stem, action string) {
+	user := currentUser(r)
+	if user == nil {
+		return
+	}
+
+	metaPath := video.GetAnnotationPathWithExt(stem, cfg.OutputDir, annotation.MetaExt)
+	record := annotation.EditRecord{Action: action, Annotator: user.ID, Time: time.Now().UTC()}
+	if err := annotation.RecordEdit(metaPath, record); err != nil {
+		log.Printf("Failed to record %s of %s by %s: %v", action, stem, user.ID, err)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/config"
+)
+
+func TestGetConfigHidesAuth(t *testing.T) {
+	authCfg := config.AuthConfig{Mode: config.AuthProxy, ProxyHeader: "X-Forwarded-User", ProxyAdmins: []string{"admin"}}
+	s := newTestServer(t, authCfg)
+	cfg := *s.currentConfig()
+	cfg.Auth = authCfg
+	s.config.Store(&cfg)
+	handler := s.requireUser(s.handleConfig)
+
+	tests := []struct {
+		user string
+		want config.AuthConfig
+	}{
+		{"admin", authCfg},
+		{"alice", config.AuthConfig{}},
+	}
+	for _, tt := range tests {
+		w := testRequest(handler, http.MethodGet, "/api/config", tt.user, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/config as %s = %d", tt.user, w.Code)
+		}
+		var got config.Config
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Auth, tt.want) || got.OutputDir != cfg.OutputDir {
+			t.Errorf("GET /api/config as %s = auth %+v, output %q; want auth %+v", tt.user, got.Auth, got.OutputDir, tt.want)
+		}
+	}
+	// 返回的是副本，服务器上的配置不受影响
+	if !reflect.DeepEqual(s.currentConfig().Auth, authCfg) {
+		t.Errorf("server auth config changed to %+v", s.currentConfig().Auth)
+	}
+
+	if w := testRequest(handler, http.MethodPost, "/api/config", "alice", "{}", nil); w.Code != http.StatusForbidden {
+		t.Errorf("POST /api/config as alice = %d, want 403", w.Code)
+	}
+}
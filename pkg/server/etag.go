@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// noAnnotationETag 是输出目录中还没有标注时的版本号
+// 新建标注时以它作为 If-Match，可以避免覆盖其他人刚刚保存的标注
+const noAnnotationETag = `"none"`
+
+// annotationETag 返回输出目录中标注的版本号（文件内容 SHA-256 的前 32 位十六进制，带引号），
+// 没有标注时返回 noAnnotationETag
+func (s *Server) annotationETag(cfg *config.Config, stem string) (string, error) {
+	if cfg.OutputDir == "" {
+		return noAnnotationETag, nil
+	}
+	outputPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.OutputDir, cfg.AnnotationExt())
+	if outputPath == "" {
+		return noAnnotationETag, nil
+	}
+	data, err := os.ReadFile(outputPath)
+	if os.IsNotExist(err) {
+		return noAnnotationETag, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// loadOutputAnnotation 读取输出目录中的标注及其元数据，不存在或无法解析时返回 nil
+func (s *Server) loadOutputAnnotation(cfg *config.Config, stem string) *annotationResponse {
+	if cfg.OutputDir == "" {
+		return nil
+	}
+	outputPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.OutputDir, cfg.AnnotationExt())
+	if outputPath == "" {
+		return nil
+	}
+	ann, diags, err := annotation.ParseFileWithOptions(outputPath, annotation.ParseOptions{})
+	if err != nil {
+		return nil
+	}
+	meta, err := annotation.LoadMeta(video.GetAnnotationPathWithExt(stem, cfg.OutputDir, annotation.MetaExt))
+	if err != nil {
+		log.Printf("Failed to read metadata of %s: %v", stem, err)
+	}
+	return &annotationResponse{Annotation: ann, Diagnostics: diags, Meta: meta}
+}
+
+// checkIfMatch 检查保存或删除请求的 If-Match 请求头，没有该请求头时不检查
+// 版本不一致时返回 409，响应中包含服务器上的当前标注（已被删除时为 null）及其版本号，供页面处理冲突
+// 调用方需持有 s.saveMu
+func (s *Server) checkIfMatch(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	current, err := s.annotationETag(cfg, stem)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read annotation: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	if etagMatches(ifMatch, current) {
+		return true
+	}
+
+	response := map[string]interface{}{
+		"error":   "Annotation was changed by someone else since it was loaded",
+		"etag":    current,
+		"current": nil,
+	}
+	if current != noAnnotationETag {
+		if saved := s.loadOutputAnnotation(cfg, stem); saved != nil {
+			response["current"] = saved
+		}
+	}
+	w.Header().Set("ETag", current)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(response)
+	return false
+}
+
+// etagMatches 判断 If-Match 是否匹配当前版本：逗号分隔的版本号之一相同，
+// 或为 *（已有标注）；弱校验的版本号（W/ 前缀）不会匹配
+func etagMatches(ifMatch, current string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == current || (tag == "*" && current != noAnnotationETag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// newTestServer 创建使用临时视频和输出目录的服务器，不读取用户的配置文件
+func newTestServer(t *testing.T, authCfg config.AuthConfig) *Server {
+	t.Helper()
+	authenticator, err := newAuthenticator(authCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		keyframes: video.NewKeyframeCache(""),
+		events:    newEventHub(),
+		auth:      authenticator,
+		locks:     newLockManager(""),
+	}
+	s.config.Store(&config.Config{VideoDir: t.TempDir(), OutputDir: t.TempDir()})
+	s.library = newVideoLibrary("", s.publishVideoChanges)
+	return s
+}
+
+// testRequest 发送请求，user 不为空时以该用户身份（proxy 模式的请求头）发送
+func testRequest(handler http.HandlerFunc, method, target, user, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if user != "" {
+		r.Header.Set("X-Forwarded-User", user)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+const testAnnotationJSON = `{"title":"Title","is_tutorial":true,"steps":[{"number":1,"timestamp":"00:01","description":"first"}]}`
+
+func TestSaveAnnotationIfMatch(t *testing.T) {
+	s := newTestServer(t, config.AuthConfig{})
+	handler := s.requireUser(s.handleAnnotation)
+	save := func(ifMatch string) *httptest.ResponseRecorder {
+		header := map[string]string{}
+		if ifMatch != "" {
+			header["If-Match"] = ifMatch
+		}
+		return testRequest(handler, http.MethodPost, "/api/annotation/intro.txt", "", testAnnotationJSON, header)
+	}
+
+	// "*" 只匹配已有的标注
+	if w := save("*"); w.Code != http.StatusConflict {
+		t.Fatalf("save with If-Match * before the first save = %d, want 409", w.Code)
+	}
+	w := save(noAnnotationETag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first save = %d: %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || etag == noAnnotationETag {
+		t.Fatalf("ETag after save = %q", etag)
+	}
+
+	// 其他人在此之后基于旧版本保存：返回 409 和服务器上的当前标注
+	w = save(noAnnotationETag)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("stale save = %d, want 409", w.Code)
+	}
+	var conflict struct {
+		ETag    string `json:"etag"`
+		Current *struct {
+			Title string `json:"title"`
+		} `json:"current"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&conflict); err != nil {
+		t.Fatal(err)
+	}
+	if conflict.ETag != etag || w.Header().Get("ETag") != etag || conflict.Current == nil || conflict.Current.Title != "Title" {
+		t.Errorf("conflict response = %+v, ETag header %q; want etag %s", conflict, w.Header().Get("ETag"), etag)
+	}
+
+	// 内容相同的保存版本号不变；不带 If-Match 时不检查
+	for _, ifMatch := range []string{etag, `"other", ` + etag, "*", ""} {
+		if w := save(ifMatch); w.Code != http.StatusOK || w.Header().Get("ETag") != etag {
+			t.Errorf("save with If-Match %q = %d, ETag %q", ifMatch, w.Code, w.Header().Get("ETag"))
+		}
+	}
+
+	w = testRequest(handler, http.MethodGet, "/api/annotation/intro.txt", "", "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag {
+		t.Errorf("get = %d, ETag %q; want %s", w.Code, w.Header().Get("ETag"), etag)
+	}
+
+	// 删除同样检查版本
+	del := func(ifMatch string) *httptest.ResponseRecorder {
+		return testRequest(handler, http.MethodDelete, "/api/annotation/intro.txt", "", "", map[string]string{"If-Match": ifMatch})
+	}
+	if w := del("W/" + etag); w.Code != http.StatusConflict {
+		t.Errorf("delete with weak ETag = %d, want 409", w.Code)
+	}
+	if w := del(etag); w.Code != http.StatusOK || w.Header().Get("ETag") != noAnnotationETag {
+		t.Errorf("delete = %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+	w = del(etag)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("delete of a deleted annotation = %d, want 409", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"current":null`) {
+		t.Errorf("conflict after delete = %s, want current null", w.Body)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		ifMatch, current string
+		want             bool
+	}{
+		{`"abc"`, `"abc"`, true},
+		{`"x", "abc"`, `"abc"`, true},
+		{`"x"`, `"abc"`, false},
+		{`W/"abc"`, `"abc"`, false},
+		{"*", `"abc"`, true},
+		{"*", noAnnotationETag, false},
+		{noAnnotationETag, noAnnotationETag, true},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifMatch, tt.current); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifMatch, tt.current, got, tt.want)
+		}
+	}
+}
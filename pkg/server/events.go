@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// sseKeepAlive 无事件时发送注释行的间隔，防止代理断开空闲连接
+const sseKeepAlive = 30 * time.Second
+
+// serverEvent 表示推送给客户端的一条变化事件
+type serverEvent struct {
+	video.ChangeEvent
+	Status *videoStatus `json:"status,omitempty"` // 受影响视频的最新标注状态（仅标注事件）
+	Lock   *videoLock   `json:"lock,omitempty"`   // 新的编辑锁（仅 video_locked 事件）
+}
+
+// eventHub 将变化事件广播给所有订阅的客户端
+type eventHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// newEventHub 创建事件广播器
+func newEventHub() *eventHub {
+	return &eventHub{clients: make(map[chan []byte]struct{})}
+}
+
+// subscribe 注册一个客户端，返回接收事件的 channel
+func (h *eventHub) subscribe() chan []byte {
+	ch := make(chan []byte, 64)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe 注销客户端
+func (h *eventHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// publish 广播一条事件；客户端处理不过来时丢弃，不阻塞其他客户端
+func (h *eventHub) publish(ev serverEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Failed to encode event: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// handleEvents 以 Server-Sent Events 推送视频和标注目录的变化
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// publishVideoChanges 广播视频增减事件（由后台扫描触发）
+func (s *Server) publishVideoChanges(events []video.ChangeEvent) {
+	for _, ev := range events {
+		s.events.publish(serverEvent{ChangeEvent: ev})
+	}
+}
+
+// watchAnnotations 按配置的间隔轮询预标注、输出和模型标注目录，
+// 更新内存中的标注状态并广播变化事件
+func (s *Server) watchAnnotations() {
+	watcher := video.NewAnnotationWatcher()
+	for {
+		cfg := s.currentConfig()
+		s.pollAnnotations(watcher, cfg)
+		time.Sleep(cfg.WatchInterval())
+	}
+}
+
+// pollAnnotations 轮询一次标注目录，更新内存中的标注状态并广播变化事件
+func (s *Server) pollAnnotations(watcher *video.AnnotationWatcher, cfg *config.Config) {
+	for _, ev := range watcher.Poll(annotationDirs(cfg)) {
+		s.events.publish(serverEvent{
+			ChangeEvent: ev,
+			Status:      s.library.annotationChanged(ev, cfg),
+		})
+	}
+}
+
+// annotationDirs 返回需要监视的标注目录（来源 -> 目录）
+func annotationDirs(cfg *config.Config) map[string]string {
+	return map[string]string{
+		video.SourcePreAnnotation:   cfg.PreAnnotationDir,
+		video.SourceOutput:          cfg.OutputDir,
+		video.SourceModelAnnotation: cfg.ModelAnnotationDir,
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+func TestEventStream(t *testing.T) {
+	s := newTestServer(t, config.AuthConfig{})
+	cfg := s.currentConfig()
+	if err := os.WriteFile(filepath.Join(cfg.VideoDir, "intro.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.library.list(cfg); err != nil {
+		t.Fatal(err)
+	}
+	watcher := video.NewAnnotationWatcher()
+	s.pollAnnotations(watcher, cfg) // 建立基线
+
+	ts := httptest.NewServer(s.requireUser(s.handleEvents))
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("response = %d, Content-Type %q", resp.StatusCode, ct)
+	}
+
+	// 读到 retry 行时客户端已订阅，之后的变化都会推送
+	lines := bufio.NewScanner(resp.Body)
+	if !lines.Scan() || lines.Text() != "retry: 3000" {
+		t.Fatalf("first line = %q, %v", lines.Text(), lines.Err())
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "intro.txt"), []byte("T\n\n1) 00:01 a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.pollAnnotations(watcher, cfg)
+
+	var data string
+	for lines.Scan() {
+		if line := lines.Text(); strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+			break
+		}
+	}
+	var ev serverEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatalf("event %q: %v", data, err)
+	}
+	if ev.Type != video.AnnotationCreated || ev.Source != video.SourceOutput || ev.Key != "intro" ||
+		ev.Status == nil || !ev.Status.HasAnnotation {
+		t.Errorf("event = %s", data)
+	}
+	if v := s.library.find("intro"); v == nil || !v.HasAnnotation {
+		t.Errorf("library entry after the event = %+v", v)
+	}
+}
+
+func TestEventHubDropsSlowClients(t *testing.T) {
+	h := newEventHub()
+	slow, fast := h.subscribe(), h.subscribe()
+	for i := 0; i < cap(slow)+10; i++ {
+		h.publish(serverEvent{ChangeEvent: video.ChangeEvent{Key: "k"}})
+		<-fast
+	}
+	if len(slow) != cap(slow) {
+		t.Errorf("slow client has %d queued events, want %d", len(slow), cap(slow))
+	}
+
+	h.unsubscribe(fast)
+	h.publish(serverEvent{})
+	if len(fast) != 0 {
+		t.Errorf("unsubscribed client received an event")
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/config"
+)
+
+func TestExportWithoutVideo(t *testing.T) {
+	s := newTestServer(t, config.AuthConfig{})
+	save := s.requireUser(s.handleAnnotation)
+	export := s.requireUser(s.handleExport)
+
+	if w := testRequest(export, http.MethodGet, "/api/export/intro?format=vtt", "", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("export without annotation = %d, want 404", w.Code)
+	}
+
+	// 没有视频时无法得知时长，最后一步没有结束时间就无法导出，这不是服务器错误
+	if w := testRequest(save, http.MethodPost, "/api/annotation/intro.txt", "", testAnnotationJSON, nil); w.Code != http.StatusOK {
+		t.Fatalf("save = %d: %s", w.Code, w.Body)
+	}
+	w := testRequest(export, http.MethodGet, "/api/export/intro?format=vtt", "", "", nil)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "duration") {
+		t.Errorf("export without video = %d: %s, want 422 explaining the missing duration", w.Code, w.Body)
+	}
+
+	// 最后一步有结束时间时不需要视频时长
+	withEnd := strings.Replace(testAnnotationJSON, `"timestamp":"00:01"`, `"timestamp":"00:01","end":"00:03"`, 1)
+	if w := testRequest(save, http.MethodPost, "/api/annotation/intro.txt", "", withEnd, nil); w.Code != http.StatusOK {
+		t.Fatalf("save = %d: %s", w.Code, w.Body)
+	}
+	w = testRequest(export, http.MethodGet, "/api/export/intro?format=srt", "", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "00:00:01,000 --> 00:00:03,000") {
+		t.Errorf("export with an end time = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// gitChange 表示一个标注的一次修改，对应一次提交或批量提交中的一项
+type gitChange struct {
+	Key          string         // 标注 key
+	Action       string         // annotation.EditSave、EditDelete 或 EditRevert
+	Author       *auth.Identity // 操作人员，单用户模式下为 nil
+	Rev          int            // 修订历史中的修订号，0 表示没有记录
+	RevertedFrom int            // 回滚时恢复的修订号
+	Paths        []string       // 可能受影响的文件（相对输出目录）
+}
+
+// gitStore 将输出目录中的标注修改提交到 git 仓库（配置 git.enabled）
+// 每次修改一个提交，作者为标注人员；配置 batch_minutes 时定期按人员合并提交
+type gitStore struct {
+	cfg config.GitConfig
+
+	mu         sync.Mutex
+	repos      map[string]bool      // 已确认是 git 仓库的输出目录 -> 仓库是否配置了提交者身份
+	pending    map[string]gitChange // 批量模式下等待提交的修改（key 为标注 key，保留最后一次）
+	pendingDir string               // 等待提交的修改所在的输出目录
+}
+
+// newGitStore 根据配置创建 git 存储，未启用时返回 nil
+func newGitStore(cfg config.GitConfig) (*gitStore, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git storage requires the git command: %w", err)
+	}
+	return &gitStore{
+		cfg:     cfg,
+		repos:   make(map[string]bool),
+		pending: make(map[string]gitChange),
+	}, nil
+}
+
+// record 提交一次修改；批量模式下只记录，等待下次 flush。失败时只记录日志
+// 调用方需持有 s.saveMu，保证提交时文件不会被同时修改
+func (g *gitStore) record(dir string, change gitChange) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cfg.BatchInterval() == 0 {
+		if err := g.commit(dir, []gitChange{change}); err != nil {
+			log.Printf("Failed to commit %s of %s: %v", change.Action, change.Key, err)
+		}
+		return
+	}
+
+	// 输出目录在运行中被修改时，先提交旧目录中等待的修改
+	if g.pendingDir != dir && len(g.pending) > 0 {
+		g.flushLocked()
+	}
+	g.pendingDir = dir
+	if prev, ok := g.pending[change.Key]; ok {
+		change.Paths = mergePaths(prev.Paths, change.Paths)
+	}
+	g.pending[change.Key] = change
+}
+
+// flush 提交批量模式下等待的修改：按最后修改的人员分组，每人一个提交
+// 调用方需持有 s.saveMu
+func (g *gitStore) flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.flushLocked()
+}
+
+// flushLocked 同 flush，调用方需持有 g.mu
+func (g *gitStore) flushLocked() {
+	if len(g.pending) == 0 {
+		return
+	}
+	groups := make(map[string][]gitChange)
+	for _, change := range g.pending {
+		id := ""
+		if change.Author != nil {
+			id = change.Author.ID
+		}
+		groups[id] = append(groups[id], change)
+	}
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		changes := groups[id]
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+		if err := g.commit(g.pendingDir, changes); err != nil {
+			log.Printf("Failed to commit %d annotation changes: %v", len(changes), err)
+		}
+	}
+	g.pending = make(map[string]gitChange)
+}
+
+// commit 将修改涉及的文件暂存并提交，没有实际变化时不提交；所有修改的作者相同
+func (g *gitStore) commit(dir string, changes []gitChange) error {
+	hasIdentity, err := g.ensureRepo(dir)
+	if err != nil {
+		return err
+	}
+
+	// 只暂存标注相关的文件，仓库中的其他修改不受影响；已删除的文件从索引中移除
+	var present, missing []string
+	for _, change := range changes {
+		for _, rel := range change.Paths {
+			if _, err := os.Stat(filepath.Join(dir, rel)); err == nil {
+				present = append(present, rel)
+			} else {
+				missing = append(missing, rel)
+			}
+		}
+	}
+	if len(present) > 0 {
+		if _, err := runGit(dir, nil, append([]string{"add", "--"}, present...)...); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := runGit(dir, nil, append([]string{"rm", "--cached", "--quiet", "--ignore-unmatch", "--"}, missing...)...); err != nil {
+			return err
+		}
+	}
+
+	// 只提交实际有变化的文件（相对输出目录），内容与上次提交相同时不提交
+	out, err := runGit(dir, nil, append([]string{"diff", "--cached", "--name-only", "--relative", "-z", "--"}, append(present, missing...)...)...)
+	if err != nil {
+		return err
+	}
+	staged := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if out == "" {
+		return nil
+	}
+
+	name, email := g.author(changes[0].Author)
+	env := []string{"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email}
+	if !hasIdentity {
+		env = append(env, "GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+email)
+	}
+	args := append([]string{"commit", "--quiet", "-m", commitMessage(changes, name, email), "--"}, staged...)
+	_, err = runGit(dir, env, args...)
+	return err
+}
+
+// ensureRepo 确认输出目录位于 git 仓库中，不是时初始化一个新仓库
+// 返回仓库是否配置了提交者身份（user.name 和 user.email），没有时提交者与作者相同
+func (g *gitStore) ensureRepo(dir string) (bool, error) {
+	if hasIdentity, ok := g.repos[dir]; ok {
+		return hasIdentity, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if _, err := runGit(dir, nil, "rev-parse", "--is-inside-work-tree"); err != nil {
+		if _, err := runGit(dir, nil, "init", "--quiet"); err != nil {
+			return false, err
+		}
+		log.Printf("Initialized git repository in %s", dir)
+	}
+	if err := excludeHistoryDir(dir); err != nil {
+		log.Printf("Failed to exclude revision history from git: %v", err)
+	}
+
+	name, _ := runGit(dir, nil, "config", "user.name")
+	email, _ := runGit(dir, nil, "config", "user.email")
+	hasIdentity := strings.TrimSpace(name) != "" && strings.TrimSpace(email) != ""
+	g.repos[dir] = hasIdentity
+	return hasIdentity, nil
+}
+
+// excludeHistoryDir 在仓库本地的 info/exclude 中忽略修订历史目录（git 本身已保存历史），
+// 不修改仓库中的 .gitignore
+func excludeHistoryDir(dir string) error {
+	prefix, err := runGit(dir, nil, "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	excludePath, err := runGit(dir, nil, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	excludePath = strings.TrimSpace(excludePath)
+	if !filepath.IsAbs(excludePath) {
+		excludePath = filepath.Join(dir, excludePath)
+	}
+
+	pattern := "/" + strings.TrimSpace(prefix) + path.Dir(annotation.HistoryDir) + "/"
+	data, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		pattern = "\n" + pattern
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(excludePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(pattern + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// author 返回提交作者：多用户模式下为标注人员（邮箱为 登录名@email_domain），单用户模式下为 mp4label
+func (g *gitStore) author(user *auth.Identity) (string, string) {
+	if user == nil {
+		return "mp4label", "mp4label@" + g.cfg.Domain()
+	}
+	email := user.ID
+	if !strings.Contains(email, "@") {
+		email += "@" + g.cfg.Domain()
+	}
+	return user.DisplayName(), email
+}
+
+// commitMessage 生成结构化的提交说明：第一行概括修改，正文每个标注一行，
+// 结尾为便于脚本读取的 trailer（Annotator、Action、Annotation、Revision）
+func commitMessage(changes []gitChange, name, email string) string {
+	var b strings.Builder
+	if len(changes) == 1 {
+		c := changes[0]
+		switch c.Action {
+		case annotation.EditDelete:
+			fmt.Fprintf(&b, "Delete annotation %s\n\n", c.Key)
+		case annotation.EditRevert:
+			fmt.Fprintf(&b, "Revert annotation %s to revision %d\n\n", c.Key, c.RevertedFrom)
+		default:
+			fmt.Fprintf(&b, "Update annotation %s\n\n", c.Key)
+		}
+	} else {
+		fmt.Fprintf(&b, "Update %d annotations\n\n", len(changes))
+		for _, c := range changes {
+			fmt.Fprintf(&b, "- %s %s\n", c.Action, c.Key)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "Annotator: %s <%s>\n", name, email)
+	for _, c := range changes {
+		fmt.Fprintf(&b, "Action: %s\n", c.Action)
+		fmt.Fprintf(&b, "Annotation: %s\n", c.Key)
+		if c.Rev > 0 {
+			fmt.Fprintf(&b, "Revision: %d\n", c.Rev)
+		}
+	}
+	return b.String()
+}
+
+// runGit 在 dir 中执行 git 命令，返回标准输出；失败时错误中包含 git 的输出
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// mergePaths 合并两组路径并去重
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var paths []string
+	for _, p := range append(append([]string{}, a...), b...) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// recordCommit 将标注的修改提交到 git（启用 git 存储时）；调用方需持有 s.saveMu
+func (s *Server) recordCommit(r *http.Request, cfg *config.Config, stem, action string, rev, revertedFrom int) {
+	if s.git == nil {
+		return
+	}
+	dir := cfg.OutputDir
+
+	// 标注文件的所有格式（包括旧平铺位置）以及元数据文件
+	var paths []string
+	add := func(path string) {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	for _, key := range s.annotationKeys(cfg, stem) {
+		for _, ext := range annotation.Extensions() {
+			add(video.GetAnnotationPathWithExt(key, dir, ext))
+		}
+	}
+	add(video.GetAnnotationPathWithExt(stem, dir, annotation.MetaExt))
+
+	s.git.record(dir, gitChange{
+		Key:          stem,
+		Action:       action,
+		Author:       currentUser(r),
+		Rev:          rev,
+		RevertedFrom: revertedFrom,
+		Paths:        paths,
+	})
+}
+
+// runGitBatches 批量模式下定期提交等待的修改；退出前剩余的修改由 Close 提交
+func (s *Server) runGitBatches() {
+	interval := s.git.cfg.BatchInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.flushGit()
+	}
+}
+
+// flushGit 立即提交批量模式下等待的修改
+func (s *Server) flushGit() {
+	if s.git == nil {
+		return
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.git.flush()
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/config"
+)
+
+// newGitTestServer 创建启用 git 存储的多用户（proxy 模式）测试服务器，没有 git 命令时跳过测试
+// 不读取用户和系统的 git 配置，提交者因此与作者相同
+func newGitTestServer(t *testing.T, batchMinutes int) *Server {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	s := newTestServer(t, config.AuthConfig{Mode: config.AuthProxy})
+	git, err := newGitStore(config.GitConfig{Enabled: true, BatchMinutes: batchMinutes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.git = git
+	return s
+}
+
+// gitOutput 在 dir 中执行 git 命令，返回去掉首尾空白的输出
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, nil, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+// gitCommits 返回仓库中的提交（最新的在前），每项为作者行和提交说明
+func gitCommits(t *testing.T, dir string) []string {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		return nil
+	}
+	var commits []string
+	for _, c := range strings.Split(gitOutput(t, dir, "log", "--format=%x00%an <%ae>%n%B"), "\x00") {
+		if c = strings.TrimSpace(c); c != "" {
+			commits = append(commits, c)
+		}
+	}
+	return commits
+}
+
+func TestGitStoreCommits(t *testing.T) {
+	s := newGitTestServer(t, 0)
+	dir := s.currentConfig().OutputDir
+	handler := s.requireUser(s.handleAnnotation)
+	request := func(method, target, user, body string) {
+		t.Helper()
+		if w := testRequest(handler, method, target, user, body, nil); w.Code != http.StatusOK {
+			t.Fatalf("%s %s = %d: %s", method, target, w.Code, w.Body)
+		}
+	}
+
+	request(http.MethodPost, "/api/annotation/intro.txt", "alice", testAnnotationJSON)
+	commits := gitCommits(t, dir)
+	want := "alice <alice@mp4label.local>\nUpdate annotation intro\n\nAnnotator: alice <alice@mp4label.local>\nAction: save\nAnnotation: intro\nRevision: 1"
+	if len(commits) != 1 || commits[0] != want {
+		t.Fatalf("commits after first save = %q, want %q", commits, want)
+	}
+	// 只提交标注和元数据文件，修订历史目录被忽略
+	if files := gitOutput(t, dir, "ls-files"); files != "intro.meta\nintro.txt" {
+		t.Errorf("tracked files = %q", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(annotation.HistoryDir))); err != nil {
+		t.Errorf("revision history: %v", err)
+	}
+	if status := gitOutput(t, dir, "status", "--porcelain", "--", filepath.FromSlash(annotation.HistoryDir)); status != "" {
+		t.Errorf("revision history is not ignored: %q", status)
+	}
+
+	// 内容没有变化的保存只更新元数据
+	before := gitOutput(t, dir, "show", "HEAD:intro.txt")
+	request(http.MethodPost, "/api/annotation/intro.txt", "alice", testAnnotationJSON)
+	if after := gitOutput(t, dir, "show", "HEAD:intro.txt"); after != before {
+		t.Errorf("intro.txt changed by an identical save: %q", after)
+	}
+
+	request(http.MethodPost, "/api/annotation/intro.txt", "bob", strings.Replace(testAnnotationJSON, "first", "changed", 1))
+	request(http.MethodPost, "/api/annotation/intro/revert?rev=1", "alice", "")
+	request(http.MethodDelete, "/api/annotation/intro.txt", "alice", "")
+
+	commits = gitCommits(t, dir)
+	var subjects []string
+	for _, c := range commits {
+		lines := strings.SplitN(c, "\n", 3)
+		subjects = append(subjects, lines[0]+" | "+lines[1])
+	}
+	wantSubjects := []string{
+		"alice <alice@mp4label.local> | Delete annotation intro",
+		"alice <alice@mp4label.local> | Revert annotation intro to revision 1",
+		"bob <bob@mp4label.local> | Update annotation intro",
+	}
+	if len(subjects) < len(wantSubjects) || strings.Join(subjects[:len(wantSubjects)], "\n") != strings.Join(wantSubjects, "\n") {
+		t.Errorf("latest commits = %q, want %q", subjects, wantSubjects)
+	}
+	if files := gitOutput(t, dir, "ls-files", "intro.txt"); files != "" {
+		t.Errorf("intro.txt still tracked after delete")
+	}
+}
+
+func TestGitStoreBatch(t *testing.T) {
+	s := newGitTestServer(t, 5)
+	dir := s.currentConfig().OutputDir
+	handler := s.requireUser(s.handleAnnotation)
+	for _, save := range []struct{ key, user, description string }{
+		{"intro", "alice", "first"},
+		{"outro", "bob", "first"},
+		{"intro", "alice", "second"},
+		{"course/a", "alice", "first"},
+	} {
+		body := strings.Replace(testAnnotationJSON, "first", save.description, 1)
+		if w := testRequest(handler, http.MethodPost, "/api/annotation/"+save.key+".txt", save.user, body, nil); w.Code != http.StatusOK {
+			t.Fatalf("save %s = %d: %s", save.key, w.Code, w.Body)
+		}
+	}
+	if commits := gitCommits(t, dir); len(commits) != 0 {
+		t.Fatalf("commits before flush = %q", commits)
+	}
+
+	// 退出前提交等待的修改：每个人员一个提交，同一标注的多次修改合并
+	s.Close()
+	commits := gitCommits(t, dir)
+	want := []string{
+		"bob <bob@mp4label.local>\nUpdate annotation outro\n\nAnnotator: bob <bob@mp4label.local>\nAction: save\nAnnotation: outro\nRevision: 1",
+		"alice <alice@mp4label.local>\nUpdate 2 annotations\n\n- save course/a\n- save intro\n\nAnnotator: alice <alice@mp4label.local>\nAction: save\nAnnotation: course/a\nRevision: 1\nAction: save\nAnnotation: intro\nRevision: 2",
+	}
+	if strings.Join(commits, "\n---\n") != strings.Join(want, "\n---\n") {
+		t.Errorf("commits after Close = %q, want %q", commits, want)
+	}
+	if content := gitOutput(t, dir, "show", "HEAD~1:intro.txt"); !strings.Contains(content, "second") {
+		t.Errorf("committed intro.txt = %q, want the last save", content)
+	}
+
+	s.Close()
+	if n := len(gitCommits(t, dir)); n != 2 {
+		t.Errorf("%d commits after a second Close, want 2", n)
+	}
+}
+
+func TestExcludeHistoryDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	// 输出目录位于已有仓库的子目录中时，规则带上子目录前缀，重复调用不重复添加
+	repo := t.TempDir()
+	gitOutput(t, repo, "init", "--quiet")
+	dir := filepath.Join(repo, "labels")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := excludeHistoryDir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(repo, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n/labels/.mp4label/\n"); n != 1 {
+		t.Errorf("exclude file has the history rule %d times:\n%s", n, data)
+	}
+
+	revision := filepath.Join(dir, filepath.FromSlash(annotation.HistoryDir), "intro", "1.json")
+	if err := os.MkdirAll(filepath.Dir(revision), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(revision, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := gitOutput(t, repo, "status", "--porcelain", "--untracked-files=all"); status != "" {
+		t.Errorf("revision history shows up in git status: %q", status)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	alice := &auth.Identity{ID: "alice", Name: "Alice"}
+	tests := []struct {
+		name    string
+		changes []gitChange
+		want    string
+	}{
+		{"delete without revision", []gitChange{{Key: "course/a", Action: annotation.EditDelete, Author: alice}},
+			"Delete annotation course/a\n\nAnnotator: Alice <alice@example.com>\nAction: delete\nAnnotation: course/a\n"},
+		{"revert", []gitChange{{Key: "intro", Action: annotation.EditRevert, Rev: 4, RevertedFrom: 2}},
+			"Revert annotation intro to revision 2\n\nAnnotator: Alice <alice@example.com>\nAction: revert\nAnnotation: intro\nRevision: 4\n"},
+		{"batch", []gitChange{{Key: "a", Action: annotation.EditSave, Rev: 1}, {Key: "b", Action: annotation.EditDelete, Rev: 3}},
+			"Update 2 annotations\n\n- save a\n- delete b\n\nAnnotator: Alice <alice@example.com>\nAction: save\nAnnotation: a\nRevision: 1\nAction: delete\nAnnotation: b\nRevision: 3\n"},
+	}
+	for _, tt := range tests {
+		if got := commitMessage(tt.changes, "Alice", "alice@example.com"); got != tt.want {
+			t.Errorf("%s: commitMessage = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	g := &gitStore{cfg: config.GitConfig{EmailDomain: "example.com"}}
+	for _, tt := range []struct {
+		user        *auth.Identity
+		name, email string
+	}{
+		{nil, "mp4label", "mp4label@example.com"},
+		{alice, "Alice", "alice@example.com"},
+		{&auth.Identity{ID: "bob@corp.test"}, "bob@corp.test", "bob@corp.test"},
+	} {
+		if name, email := g.author(tt.user); name != tt.name || email != tt.email {
+			t.Errorf("author(%+v) = %s <%s>, want %s <%s>", tt.user, name, email, tt.name, tt.email)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// revisionSummary 是修订列表中的一项：修订信息（不含文件内容）和标注概要
+type revisionSummary struct {
+	annotation.Revision
+	Title      string `json:"title,omitempty"`
+	IsTutorial bool   `json:"is_tutorial"`
+	Steps      int    `json:"steps"`
+}
+
+// handleHistory 处理标注的修订历史：
+//   - GET /api/annotation/{key}/history：修订列表（最新的在前）
+//   - GET /api/annotation/{key}/history?rev=N：单个修订及解析后的标注
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cfg.OutputDir == "" {
+		http.Error(w, "Output directory not set", http.StatusBadRequest)
+		return
+	}
+	dir := annotation.HistoryPath(cfg.OutputDir, stem)
+
+	if param := r.URL.Query().Get("rev"); param != "" {
+		revision, ok := s.loadRevision(w, dir, param)
+		if !ok {
+			return
+		}
+		response := map[string]interface{}{"revision": revision}
+		if ann, err := revision.Annotation(); err == nil {
+			response["annotation"] = ann
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	revisions, err := annotation.ListRevisions(dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read history: %v", err), http.StatusInternalServerError)
+		return
+	}
+	summaries := make([]revisionSummary, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		summary := revisionSummary{Revision: revisions[i]}
+		if ann, err := revisions[i].Annotation(); err == nil {
+			summary.Title = ann.Title
+			summary.IsTutorial = ann.IsTutorial
+			summary.Steps = len(ann.Steps)
+		}
+		summary.Content = ""
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"revisions": summaries})
+}
+
+// revertAnnotation 将标注回滚到历史修订（POST /api/annotation/{key}/revert?rev=N）
+// 回滚本身也会记录为一个新修订，可以再次撤销；与保存一样检查编辑锁和 If-Match，
+// 并按当前的验证规则检查修订内容（不对齐到帧，保持修订原样）
+func (s *Server) revertAnnotation(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cfg.OutputDir == "" {
+		http.Error(w, "Output directory not set", http.StatusBadRequest)
+		return
+	}
+
+	opts := s.validateOptions(cfg, s.findVideoPath(cfg, stem))
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if !s.checkLock(w, r, stem) || !s.checkIfMatch(w, r, cfg, stem) {
+		return
+	}
+
+	revision, ok := s.loadRevision(w, annotation.HistoryPath(cfg.OutputDir, stem), r.URL.Query().Get("rev"))
+	if !ok {
+		return
+	}
+	ann, err := revision.Annotation()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot revert: %v", err), http.StatusBadRequest)
+		return
+	}
+	report, ok := validateAnnotation(w, ann, opts)
+	if !ok {
+		return
+	}
+
+	s.recordHistoryBaseline(cfg, stem)
+	outputPath := video.GetAnnotationPathWithExt(stem, cfg.OutputDir, cfg.AnnotationExt())
+	if err := ann.Save(outputPath); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.removeStaleAnnotations(cfg, stem, outputPath)
+	s.library.setAnnotated(stem, true)
+	s.recordEdit(r, cfg, stem, annotation.EditRevert)
+	rev := s.recordRevision(r, cfg, stem, annotation.EditRevert, revision.Rev)
+	s.recordCommit(r, cfg, stem, annotation.EditRevert, rev, revision.Rev)
+	if etag, err := s.annotationETag(cfg, stem); err == nil {
+		w.Header().Set("ETag", etag)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"rev":        rev,
+		"annotation": ann,
+		"report":     report,
+	})
+}
+
+// loadRevision 解析修订号参数并读取修订，失败时写入错误响应
+func (s *Server) loadRevision(w http.ResponseWriter, dir, param string) (*annotation.Revision, bool) {
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 1 {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return nil, false
+	}
+	revision, err := annotation.LoadRevision(dir, n)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read history: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return revision, true
+}
+
+// recordHistoryBaseline 在第一次修改前，把还没有历史的已有标注（启用修订历史之前保存的）记为第一个修订，
+// 这样第一次修改也可以撤销；失败时只记录日志。调用方需持有 s.saveMu
+func (s *Server) recordHistoryBaseline(cfg *config.Config, stem string) {
+	dir := annotation.HistoryPath(cfg.OutputDir, stem)
+	if latest, err := annotation.LatestRevision(dir); err != nil || latest != nil {
+		return
+	}
+	outputPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.OutputDir, cfg.AnnotationExt())
+	if outputPath == "" {
+		return
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return
+	}
+
+	baseline := annotation.Revision{
+		Action:  annotation.EditSave,
+		Time:    info.ModTime().UTC(),
+		Format:  filepath.Ext(outputPath),
+		Content: string(data),
+	}
+	if meta, _ := annotation.LoadMeta(video.GetAnnotationPathWithExt(stem, cfg.OutputDir, annotation.MetaExt)); meta != nil {
+		baseline.Annotator = meta.Annotator
+	}
+	if _, err := annotation.AppendRevision(dir, baseline); err != nil {
+		log.Printf("Failed to record history of %s: %v", stem, err)
+	}
+}
+
+// recordRevision 将保存、删除或回滚后的标注记为新修订，返回修订号；失败时只记录日志并返回 0
+// 内容与最新修订相同的保存（如没有实际修改的自动保存）不产生新修订。调用方需持有 s.saveMu
+func (s *Server) recordRevision(r *http.Request, cfg *config.Config, stem, action string, revertedFrom int) int {
+	dir := annotation.HistoryPath(cfg.OutputDir, stem)
+	revision := annotation.Revision{Action: action, Time: time.Now().UTC(), RevertedFrom: revertedFrom}
+	if user := currentUser(r); user != nil {
+		revision.Annotator = user.ID
+	}
+
+	if action != annotation.EditDelete {
+		outputPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.OutputDir, cfg.AnnotationExt())
+		data, err := os.ReadFile(outputPath)
+		if err != nil {
+			log.Printf("Failed to record history of %s: %v", stem, err)
+			return 0
+		}
+		revision.Format = filepath.Ext(outputPath)
+		revision.Content = string(data)
+	}
+
+	latest, err := annotation.LatestRevision(dir)
+	if err != nil {
+		log.Printf("Failed to read history of %s: %v", stem, err)
+	}
+	if latest != nil && action == annotation.EditSave && latest.Action != annotation.EditDelete &&
+		latest.Format == revision.Format && latest.Content == revision.Content {
+		return latest.Rev
+	}
+
+	rev, err := annotation.AppendRevision(dir, revision)
+	if err != nil {
+		log.Printf("Failed to record history of %s: %v", stem, err)
+		return 0
+	}
+	return rev
+}
+
+// removeStaleAnnotations 移除同一视频其他格式以及旧平铺位置的标注文件，避免读取到过期内容
+// 旧平铺位置的文件只有确定属于该视频（列表中没有其他视频使用同一名称）时才移除
+func (s *Server) removeStaleAnnotations(cfg *config.Config, stem, outputPath string) {
+	for _, key := range s.annotationKeys(cfg, stem) {
+		if key != stem && s.library.sharesKey(key, stem) {
+			continue
+		}
+		for _, ext := range annotation.Extensions() {
+			if stale := video.GetAnnotationPathWithExt(key, cfg.OutputDir, ext); stale != outputPath {
+				if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+					log.Printf("Failed to remove stale annotation %s: %v", stale, err)
+				}
+			}
+		}
+	}
+}
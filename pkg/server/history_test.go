@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/config"
+)
+
+func TestSaveKeepsLegacyAnnotationOfOtherVideo(t *testing.T) {
+	// 任务文件只列出 a/intro；平铺的 intro.txt 可能属于被排除的 b/intro，保存 a/intro 时不能删除
+	s := newTestServer(t, config.AuthConfig{})
+	cfg := s.currentConfig()
+	for _, name := range []string{"a/intro.mp4", "b/intro.mp4"} {
+		path := filepath.Join(cfg.VideoDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.TaskFile = filepath.Join(t.TempDir(), "tasks.txt")
+	if err := os.WriteFile(cfg.TaskFile, []byte("a/intro\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(cfg.OutputDir, "intro.txt")
+	if err := os.WriteFile(legacy, []byte("B\n\n1) 00:01.000 b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := s.requireUser(s.handleAnnotation)
+	if w := testRequest(handler, http.MethodGet, "/api/annotation/a/intro.txt", "", "", nil); w.Code != http.StatusOK || w.Header().Get("ETag") != noAnnotationETag {
+		t.Errorf("get a/intro = %d, ETag %q; want no annotation", w.Code, w.Header().Get("ETag"))
+	}
+	if w := testRequest(handler, http.MethodPost, "/api/annotation/a/intro.txt", "", testAnnotationJSON, nil); w.Code != http.StatusOK {
+		t.Fatalf("save = %d: %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "a", "intro.txt")); err != nil {
+		t.Errorf("saved annotation: %v", err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy annotation of b/intro after saving a/intro: %v", err)
+	}
+}
+
+func TestRevertValidatesRevision(t *testing.T) {
+	// 修订保存后验证规则变严，回滚到不再符合规则的修订与保存一样返回 400 和完整报告
+	s := newTestServer(t, config.AuthConfig{})
+	handler := s.requireUser(s.handleAnnotation)
+	tooClose := `{"title":"Title","is_tutorial":true,"steps":[{"number":1,"timestamp":"00:01","description":"first"},{"number":2,"timestamp":"00:02","description":"second"}]}`
+	for _, body := range []string{tooClose, testAnnotationJSON} {
+		if w := testRequest(handler, http.MethodPost, "/api/annotation/intro.txt", "", body, nil); w.Code != http.StatusOK {
+			t.Fatalf("save = %d: %s", w.Code, w.Body)
+		}
+	}
+	outputPath := filepath.Join(s.currentConfig().OutputDir, "intro.txt")
+	before, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := *s.currentConfig()
+	cfg.Validation.MinGapMs = 5000
+	cfg.Validation.MinGapSeverity = "error"
+	s.config.Store(&cfg)
+
+	w := testRequest(handler, http.MethodPost, "/api/annotation/intro/revert?rev=1", "", "", nil)
+	var resp struct {
+		Error  string                       `json:"error"`
+		Report *annotation.ValidationReport `json:"report"`
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("revert = %d: %s, want 400", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Report == nil || resp.Report.Valid() {
+		t.Errorf("revert response = %s, want a failing report", w.Body)
+	}
+	if after, _ := os.ReadFile(outputPath); string(after) != string(before) {
+		t.Errorf("annotation changed by a rejected revert:\n%s", after)
+	}
+
+	// 符合规则的修订仍可回滚
+	if w := testRequest(handler, http.MethodPost, "/api/annotation/intro/revert?rev=2", "", "", nil); w.Code != http.StatusOK {
+		t.Errorf("revert to a valid revision = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// videoLibrary 在内存中保存最近一次扫描得到的视频列表（含标注状态和元数据）
+// 由后台定期增量刷新，/api/videos 直接从内存返回，不再每次遍历目录
+type videoLibrary struct {
+	index *video.ScanIndex
+
+	scanMu sync.Mutex // 保证同一时间只有一次扫描
+
+	mu           sync.RWMutex
+	videos       []video.VideoInfo
+	taskWarnings []video.TaskWarning
+	keyWarnings  []video.KeyWarning
+	scannedAt    time.Time
+	scope        string // 本次列表对应的目录和扫描选项，变化时不比较新旧列表
+	err          error
+	stale        bool // 为 true 时下次读取前重新扫描（如配置变更后）
+
+	onChange func([]video.ChangeEvent) // 重新扫描后视频增减时调用（可为空）
+}
+
+// newVideoLibrary 创建视频列表，indexPath 为空时索引只保存在内存中
+func newVideoLibrary(indexPath string, onChange func([]video.ChangeEvent)) *videoLibrary {
+	return &videoLibrary{index: video.LoadScanIndex(indexPath), stale: true, onChange: onChange}
+}
+
+// libraryView 表示某次扫描得到的视频列表副本
+type libraryView struct {
+	videos       []video.VideoInfo
+	taskWarnings []video.TaskWarning
+	keyWarnings  []video.KeyWarning
+	scannedAt    time.Time
+}
+
+// list 返回视频列表的副本，尚未扫描或已失效时先同步扫描
+func (l *videoLibrary) list(cfg *config.Config) (libraryView, error) {
+	l.ensureFresh(cfg)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	view := libraryView{taskWarnings: l.taskWarnings, keyWarnings: l.keyWarnings, scannedAt: l.scannedAt}
+	if l.err != nil {
+		return view, l.err
+	}
+	view.videos = make([]video.VideoInfo, len(l.videos))
+	copy(view.videos, l.videos)
+	return view, nil
+}
+
+// ensureFresh 在尚未扫描或列表已失效（如配置变更后）时同步扫描
+func (l *videoLibrary) ensureFresh(cfg *config.Config) {
+	l.mu.RLock()
+	stale := l.stale
+	l.mu.RUnlock()
+	if stale {
+		l.refresh(cfg)
+	}
+}
+
+// refresh 增量扫描视频目录并重新匹配标注，完成后替换内存中的列表
+func (l *videoLibrary) refresh(cfg *config.Config) error {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	result, stats, err := l.index.Scan(cfg.VideoDir, cfg.TaskFile, cfg.ScanOptions())
+	videos := result.Videos
+	if err == nil {
+		video.MatchAnnotations(videos, cfg.PreAnnotationDir, cfg.OutputDir)
+	}
+
+	scope := fmt.Sprint(cfg.VideoDir, "|", cfg.TaskFile, "|", cfg.VideoExtensions)
+
+	l.mu.Lock()
+	l.scannedAt = time.Now()
+	l.err = err
+	l.stale = false
+	if err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	var events []video.ChangeEvent
+	if l.videos != nil && l.scope == scope {
+		events = video.DiffVideos(l.videos, videos)
+	}
+	l.videos = videos
+	l.taskWarnings = result.TaskWarnings
+	l.keyWarnings = result.KeyWarnings
+	l.scope = scope
+	l.mu.Unlock()
+
+	if stats.Probed > 0 || stats.Removed > 0 {
+		log.Printf("Scanned %d videos in %dms (%d probed, %d removed)", stats.Videos, stats.DurationMs, stats.Probed, stats.Removed)
+	}
+	if len(events) > 0 && l.onChange != nil {
+		l.onChange(events)
+	}
+	return nil
+}
+
+// invalidate 使内存中的列表失效，下次读取时重新扫描
+func (l *videoLibrary) invalidate() {
+	l.mu.Lock()
+	l.stale = true
+	l.mu.Unlock()
+}
+
+// find 在内存列表中查找标注 key 对应的视频，返回副本；找不到时返回 nil
+func (l *videoLibrary) find(key string) *video.VideoInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := range l.videos {
+		if l.videos[i].Key == key {
+			v := l.videos[i]
+			return &v
+		}
+	}
+	return nil
+}
+
+// findByRelPath 在内存列表中查找相对路径（含扩展名）对应的视频，返回副本；找不到时返回 nil
+func (l *videoLibrary) findByRelPath(relPath string) *video.VideoInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := range l.videos {
+		if l.videos[i].RelPath == relPath {
+			v := l.videos[i]
+			return &v
+		}
+	}
+	return nil
+}
+
+// sharesKey 判断除 owner 外是否还有视频的 key 或文件名（旧的平铺 key）为 key
+func (l *videoLibrary) sharesKey(key, owner string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := range l.videos {
+		v := &l.videos[i]
+		if v.Key != owner && (v.Key == key || v.Stem == key) {
+			return true
+		}
+	}
+	return false
+}
+
+// setAnnotated 保存或删除标注后立即更新内存中的标注状态，无需等待下次扫描
+func (l *videoLibrary) setAnnotated(key string, annotated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.videos {
+		if l.videos[i].Key == key {
+			l.videos[i].HasAnnotation = annotated
+		}
+	}
+}
+
+// videoStatus 表示一个视频当前的标注状态，随标注变化事件推送给客户端
+type videoStatus struct {
+	Key              string `json:"key"`
+	HasAnnotation    bool   `json:"has_annotation"`
+	HasPreAnnotation bool   `json:"has_pre_annotation"`
+}
+
+// annotationChanged 根据标注文件变化重新检查对应视频的标注状态并更新内存中的列表
+// 返回该视频的最新状态；标注不属于列表中的任何视频时返回 nil
+func (l *videoLibrary) annotationChanged(ev video.ChangeEvent, cfg *config.Config) *videoStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.videos {
+		v := &l.videos[i]
+		keys := v.AnnotationKeys()
+		if !containsString(keys, ev.Key) {
+			continue
+		}
+		switch ev.Source {
+		case video.SourceOutput:
+			v.HasAnnotation = video.FindAnnotationPathForKeys(keys, cfg.OutputDir, "") != ""
+		case video.SourcePreAnnotation:
+			v.HasPreAnnotation = video.FindAnnotationPathForKeys(keys, cfg.PreAnnotationDir, "") != ""
+		}
+		return &videoStatus{Key: v.Key, HasAnnotation: v.HasAnnotation, HasPreAnnotation: v.HasPreAnnotation}
+	}
+	return nil
+}
+
+// containsString 判断 list 中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// run 按配置的间隔在后台刷新，current 返回当前配置（配置可能在运行中被修改）
+func (l *videoLibrary) run(current func() *config.Config) {
+	for {
+		time.Sleep(current().ScanInterval())
+		if err := l.refresh(current()); err != nil {
+			log.Printf("Background scan failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xd/mp4label/pkg/config"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+func TestVideoLibraryRefresh(t *testing.T) {
+	cfg := &config.Config{VideoDir: t.TempDir(), OutputDir: t.TempDir()}
+	var events []string
+	l := newVideoLibrary("", func(changes []video.ChangeEvent) {
+		for _, ev := range changes {
+			events = append(events, string(ev.Type)+" "+ev.Key)
+		}
+	})
+	create := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(cfg.VideoDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := func() []string {
+		t.Helper()
+		view, err := l.list(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, v := range view.videos {
+			keys = append(keys, v.Key)
+		}
+		return keys
+	}
+
+	// 第一次扫描只建立列表，不产生事件
+	create("a.mp4")
+	if got := keys(); !reflect.DeepEqual(got, []string{"a"}) || len(events) != 0 {
+		t.Fatalf("first list = %v, events %v", got, events)
+	}
+
+	// 列表来自内存，刷新前看不到新文件
+	create("b.mp4")
+	if got := keys(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("list before refresh = %v", got)
+	}
+	if err := l.refresh(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(cfg.VideoDir, "a.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.refresh(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("list after refresh = %v", got)
+	}
+	if want := []string{"video_added b", "video_removed a"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	// 换了视频目录后重新扫描，不把目录差异当作视频增减
+	events = nil
+	cfg = &config.Config{VideoDir: t.TempDir(), OutputDir: cfg.OutputDir}
+	l.invalidate()
+	if got := keys(); len(got) != 0 || len(events) != 0 {
+		t.Errorf("list of a new directory = %v, events %v", got, events)
+	}
+
+	// 已有标注的状态在扫描时匹配，保存后立即更新
+	create("c.mp4")
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "c.txt"), []byte("T\n\n1) 00:01 a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.refresh(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if v := l.find("c"); v == nil || !v.HasAnnotation {
+		t.Fatalf("c = %+v, want annotated", v)
+	}
+	l.setAnnotated("c", false)
+	if v := l.find("c"); v == nil || v.HasAnnotation {
+		t.Errorf("c after setAnnotated(false) = %+v", v)
+	}
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/video"
+)
+
+// 编辑锁事件（/api/events）
+const (
+	videoLocked   video.ChangeKind = "video_locked"
+	videoUnlocked video.ChangeKind = "video_unlocked"
+
+	sourceLock = "lock"
+)
+
+// videoLock 表示一个视频的编辑锁：锁定期间只有持有者可以保存或删除标注
+type videoLock struct {
+	Key        string    `json:"key"`
+	Owner      string    `json:"owner"`                // 持有者登录名
+	OwnerName  string    `json:"owner_name,omitempty"` // 持有者显示名称
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // 租约到期时间，持有者需要在此之前续期
+}
+
+// expired 判断租约是否已过期
+func (l *videoLock) expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// lockManager 管理编辑锁（key 为标注 key），每次变化都写入磁盘，服务重启后租约仍然有效
+type lockManager struct {
+	mu    sync.Mutex
+	path  string // 为空时只保存在内存中
+	locks map[string]*videoLock
+}
+
+// newLockManager 创建编辑锁管理器，并加载 path 中未过期的锁
+func newLockManager(path string) *lockManager {
+	m := &lockManager{path: path, locks: make(map[string]*videoLock)}
+	if path == "" {
+		return m
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read locks: %v", err)
+		}
+		return m
+	}
+	var locks []*videoLock
+	if err := json.Unmarshal(data, &locks); err != nil {
+		log.Printf("Failed to parse locks %s: %v", path, err)
+		return m
+	}
+	now := time.Now()
+	for _, l := range locks {
+		if !l.expired(now) {
+			m.locks[l.Key] = l
+		}
+	}
+	return m
+}
+
+// acquire 为用户获取或续期编辑锁；已被其他人持有且未过期时返回对方的锁和 false
+func (m *lockManager) acquire(key string, user *auth.Identity, lease time.Duration) (videoLock, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	current := m.locks[key]
+	if current != nil && !current.expired(now) && current.Owner != user.ID {
+		return *current, false
+	}
+	lock := &videoLock{Key: key, Owner: user.ID, OwnerName: user.Name, AcquiredAt: now, ExpiresAt: now.Add(lease)}
+	if current != nil && current.Owner == user.ID && !current.expired(now) {
+		lock.AcquiredAt = current.AcquiredAt
+	}
+	m.locks[key] = lock
+	m.save()
+	return *lock, true
+}
+
+// renew 延长用户持有的锁；锁已被释放、强制解除或被其他人获取时返回 false
+// 已过期但尚未被其他人获取的锁仍可续期
+func (m *lockManager) renew(key, owner string, lease time.Duration) (videoLock, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.locks[key]
+	if current == nil || current.Owner != owner {
+		return videoLock{}, false
+	}
+	current.ExpiresAt = time.Now().Add(lease)
+	m.save()
+	return *current, true
+}
+
+// release 释放锁；force 为 true 时不论持有者（管理员强制解除）
+// 返回被释放的锁；锁由其他人持有时返回 held
+func (m *lockManager) release(key, owner string, force bool) (released, held *videoLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.locks[key]
+	if current == nil {
+		return nil, nil
+	}
+	if current.Owner != owner && !force && !current.expired(time.Now()) {
+		held := *current
+		return nil, &held
+	}
+	delete(m.locks, key)
+	m.save()
+	return current, nil
+}
+
+// holder 返回视频当前未过期的锁，没有时返回 nil
+func (m *lockManager) holder(key string) *videoLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.locks[key]; l != nil && !l.expired(time.Now()) {
+		lock := *l
+		return &lock
+	}
+	return nil
+}
+
+// active 返回所有未过期的锁（key -> 锁）
+func (m *lockManager) active() map[string]videoLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	locks := make(map[string]videoLock, len(m.locks))
+	for key, l := range m.locks {
+		if !l.expired(now) {
+			locks[key] = *l
+		}
+	}
+	return locks
+}
+
+// save 清理过期的锁并写入磁盘，失败时只记录日志；调用方需持有 m.mu
+func (m *lockManager) save() {
+	now := time.Now()
+	locks := make([]*videoLock, 0, len(m.locks))
+	for key, l := range m.locks {
+		if l.expired(now) {
+			delete(m.locks, key)
+			continue
+		}
+		locks = append(locks, l)
+	}
+	if m.path == "" {
+		return
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Key < locks[j].Key })
+
+	data, err := json.MarshalIndent(locks, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(m.path), 0755); err == nil {
+			tmp := m.path + ".tmp"
+			if err = os.WriteFile(tmp, data, 0644); err == nil {
+				err = os.Rename(tmp, m.path)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save locks: %v", err)
+	}
+}
+
+// handleLock 处理编辑锁请求（需要多用户模式）。锁只阻止其他人的保存和删除，保存本身不要求持有锁：
+//   - POST /api/lock/{key}：获取锁（已持有时续期），被其他人持有时返回 409
+//   - POST /api/lock/{key}/renew：续期，锁已丢失时返回 404
+//   - DELETE /api/lock/{key}：释放锁；?force=1 由管理员强制解除其他人的锁
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/lock/")
+	key, renew := strings.CutSuffix(name, "/renew")
+	if !annotation.ValidKey(key) {
+		http.Error(w, "Invalid annotation key", http.StatusBadRequest)
+		return
+	}
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "Edit locks require multi-user mode", http.StatusBadRequest)
+		return
+	}
+	lease := s.currentConfig().LockLease()
+
+	switch {
+	case renew && r.Method == http.MethodPost:
+		lock, ok := s.locks.renew(key, user.ID, lease)
+		if !ok {
+			http.Error(w, "Lock is not held", http.StatusNotFound)
+			return
+		}
+		writeLock(w, http.StatusOK, lock, lease)
+
+	case !renew && r.Method == http.MethodPost:
+		lock, ok := s.locks.acquire(key, user, lease)
+		if !ok {
+			writeLockConflict(w, http.StatusConflict, lock)
+			return
+		}
+		s.events.publish(serverEvent{ChangeEvent: video.ChangeEvent{Type: videoLocked, Source: sourceLock, Key: key}, Lock: &lock})
+		writeLock(w, http.StatusOK, lock, lease)
+
+	case !renew && r.Method == http.MethodDelete:
+		force := r.URL.Query().Get("force") == "1"
+		if force && !isAdmin(r) {
+			http.Error(w, "Administrator required", http.StatusForbidden)
+			return
+		}
+		released, held := s.locks.release(key, user.ID, force)
+		if held != nil {
+			writeLockConflict(w, http.StatusConflict, *held)
+			return
+		}
+		if released != nil {
+			if released.Owner != user.ID {
+				log.Printf("Lock on %s held by %s was force-released by %s", key, released.Owner, user.ID)
+			}
+			s.events.publish(serverEvent{ChangeEvent: video.ChangeEvent{Type: videoUnlocked, Source: sourceLock, Key: key}})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLocks 返回所有未过期的编辑锁
+func (s *Server) handleLocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	locks := []videoLock{}
+	for _, l := range s.locks.active() {
+		locks = append(locks, l)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Key < locks[j].Key })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"locks": locks})
+}
+
+// checkLock 检查视频是否被其他人锁定，是则返回 423 和锁的信息；调用方需持有 s.saveMu
+// 只有其他人持有未过期的锁时才拒绝，请求者未获取锁时照常放行（单用户模式和不使用锁的客户端不受影响）
+func (s *Server) checkLock(w http.ResponseWriter, r *http.Request, stem string) bool {
+	user := currentUser(r)
+	if user == nil {
+		return true
+	}
+	if lock := s.locks.holder(stem); lock != nil && lock.Owner != user.ID {
+		writeLockConflict(w, http.StatusLocked, *lock)
+		return false
+	}
+	return true
+}
+
+// writeLock 返回获取或续期后的锁
+func writeLock(w http.ResponseWriter, status int, lock videoLock, lease time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lock":          lock,
+		"lease_seconds": int(lease.Seconds()),
+	})
+}
+
+// writeLockConflict 返回视频被其他人锁定的错误
+func writeLockConflict(w http.ResponseWriter, status int, lock videoLock) {
+	holder := lock.Owner
+	if lock.OwnerName != "" {
+		holder = fmt.Sprintf("%s (%s)", lock.OwnerName, lock.Owner)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": fmt.Sprintf("Video is being edited by %s", holder),
+		"lock":  lock,
+	})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xd/mp4label/pkg/auth"
+	"github.com/xd/mp4label/pkg/config"
+)
+
+func TestSaveAnnotationLocked(t *testing.T) {
+	s := newTestServer(t, config.AuthConfig{Mode: config.AuthProxy, ProxyAdmins: []string{"admin"}})
+	annotationHandler := s.requireUser(s.handleAnnotation)
+	lockHandler := s.requireUser(s.handleLock)
+	save := func(user string) int {
+		return testRequest(annotationHandler, http.MethodPost, "/api/annotation/course/intro.txt", user, testAnnotationJSON, nil).Code
+	}
+
+	// 没有人持有锁时，未获取锁的用户也可以保存
+	if code := save("bob"); code != http.StatusOK {
+		t.Fatalf("save without locks = %d, want 200", code)
+	}
+
+	if w := testRequest(lockHandler, http.MethodPost, "/api/lock/course/intro", "alice", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("alice lock = %d: %s", w.Code, w.Body)
+	}
+	if w := testRequest(lockHandler, http.MethodPost, "/api/lock/course/intro", "bob", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("bob lock while alice holds it = %d, want 409", w.Code)
+	}
+	if code := save("bob"); code != http.StatusLocked {
+		t.Errorf("bob save while alice holds the lock = %d, want 423", code)
+	}
+	if w := testRequest(annotationHandler, http.MethodDelete, "/api/annotation/course/intro.txt", "bob", "", nil); w.Code != http.StatusLocked {
+		t.Errorf("bob delete while alice holds the lock = %d, want 423", w.Code)
+	}
+	if code := save("alice"); code != http.StatusOK {
+		t.Errorf("alice save with her lock = %d, want 200", code)
+	}
+	// 锁只针对一个视频
+	if w := testRequest(annotationHandler, http.MethodPost, "/api/annotation/outro.txt", "bob", testAnnotationJSON, nil); w.Code != http.StatusOK {
+		t.Errorf("bob save of another video = %d, want 200", w.Code)
+	}
+
+	// 只有持有者或管理员可以解除锁
+	if w := testRequest(lockHandler, http.MethodDelete, "/api/lock/course/intro", "bob", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("bob unlock = %d, want 409", w.Code)
+	}
+	if w := testRequest(lockHandler, http.MethodDelete, "/api/lock/course/intro?force=1", "bob", "", nil); w.Code != http.StatusForbidden {
+		t.Errorf("bob force unlock = %d, want 403", w.Code)
+	}
+	if w := testRequest(lockHandler, http.MethodDelete, "/api/lock/course/intro?force=1", "admin", "", nil); w.Code != http.StatusOK {
+		t.Errorf("admin force unlock = %d, want 200", w.Code)
+	}
+	if code := save("bob"); code != http.StatusOK {
+		t.Errorf("bob save after force unlock = %d, want 200", code)
+	}
+	// 被强制解除后，原持有者无法续期
+	if w := testRequest(lockHandler, http.MethodPost, "/api/lock/course/intro/renew", "alice", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("alice renew after force unlock = %d, want 404", w.Code)
+	}
+}
+
+func TestLockManagerExpiry(t *testing.T) {
+	m := newLockManager("")
+	alice, bob := &auth.Identity{ID: "alice"}, &auth.Identity{ID: "bob"}
+
+	if _, ok := m.acquire("intro", alice, -time.Second); !ok {
+		t.Fatal("acquire failed")
+	}
+	// 租约已过期：其他人可以获取，持有者不能再续期
+	if m.holder("intro") != nil {
+		t.Error("expired lock is still held")
+	}
+	if lock, ok := m.acquire("intro", bob, time.Minute); !ok || lock.Owner != "bob" {
+		t.Fatalf("bob acquire after expiry = %+v, %v", lock, ok)
+	}
+	if _, ok := m.renew("intro", "alice", time.Minute); ok {
+		t.Error("alice renewed a lock now held by bob")
+	}
+
+	// 续期保留获取时间
+	first, _ := m.acquire("intro", bob, time.Minute)
+	again, ok := m.renew("intro", "bob", time.Hour)
+	if !ok || !again.AcquiredAt.Equal(first.AcquiredAt) || !again.ExpiresAt.After(first.ExpiresAt) {
+		t.Errorf("renew = %+v, %v; first %+v", again, ok, first)
+	}
+}
+
+func TestLockManagerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks.json")
+	m := newLockManager(path)
+	m.acquire("intro", &auth.Identity{ID: "alice", Name: "Alice"}, time.Minute)
+	m.acquire("outro", &auth.Identity{ID: "bob"}, time.Minute)
+	m.release("outro", "bob", false)
+
+	// 服务重启后未到期的锁仍然有效
+	restarted := newLockManager(path)
+	if lock := restarted.holder("intro"); lock == nil || lock.Owner != "alice" || lock.OwnerName != "Alice" {
+		t.Errorf("intro lock after restart = %+v", lock)
+	}
+	if lock := restarted.holder("outro"); lock != nil {
+		t.Errorf("released lock after restart = %+v", lock)
+	}
+}
@@ -1,17 +1,27 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/xd/mp4label/pkg/annotation"
 	"github.com/xd/mp4label/pkg/config"
@@ -20,8 +30,17 @@ import (
 
 // Server 表示 Web 服务器
 type Server struct {
-	config *config.Config
-	webFS  embed.FS
+	config    atomic.Pointer[config.Config] // 当前配置，保存设置时整体替换（见 currentConfig）
+	configMu  sync.Mutex                    // 串行化配置的保存
+	webFS     embed.FS
+	keyframes *video.KeyframeCache // 关键帧磁盘缓存
+	library   *videoLibrary        // 内存中的视频列表，由后台增量刷新
+	events    *eventHub            // 目录变化事件（/api/events）
+	auth      *authenticator       // 多用户模式的认证，单用户模式下为 nil
+	locks     *lockManager         // 多用户模式下的编辑锁
+	git       *gitStore            // 输出目录的 git 存储，未启用时为 nil
+	saveMu    sync.Mutex           // 串行化标注的版本检查、保存、删除和元数据写入
+	http      *http.Server         // 由 Start 启动，Shutdown 停止
 }
 
 // NewServer 创建新的服务器实例
@@ -31,21 +50,82 @@ func NewServer(webFS embed.FS) (*Server, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	return &Server{
-		config: cfg,
-		webFS:  webFS,
-	}, nil
+	// 缓存目录不可用时不缓存，每次重新读取
+	var keyframeDir, indexPath string
+	if cacheDir, err := config.GetCacheDir(); err != nil {
+		log.Printf("Keyframe cache and scan index disabled: %v", err)
+	} else {
+		keyframeDir = filepath.Join(cacheDir, "keyframes")
+		indexPath = filepath.Join(cacheDir, "scan-index.json")
+	}
+
+	// 编辑锁保存在配置目录中，服务重启后未到期的锁仍然有效
+	var locksPath string
+	if configPath, err := config.GetConfigPath(); err != nil {
+		log.Printf("Edit locks will not persist across restarts: %v", err)
+	} else {
+		locksPath = filepath.Join(filepath.Dir(configPath), "locks.json")
+	}
+
+	authenticator, err := newAuthenticator(cfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up authentication: %w", err)
+	}
+
+	gitStore, err := newGitStore(cfg.Git)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up git storage: %w", err)
+	}
+
+	s := &Server{
+		webFS:     webFS,
+		keyframes: video.NewKeyframeCache(keyframeDir),
+		events:    newEventHub(),
+		auth:      authenticator,
+		locks:     newLockManager(locksPath),
+		git:       gitStore,
+	}
+	s.config.Store(cfg)
+	s.library = newVideoLibrary(indexPath, s.publishVideoChanges)
+
+	// 停止服务时取消所有请求的 context，让 /api/events 等长连接结束，Shutdown 才不会一直等待
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s.http = &http.Server{BaseContext: func(net.Listener) context.Context { return baseCtx }}
+	s.http.RegisterOnShutdown(cancel)
+	return s, nil
+}
+
+// currentConfig 返回当前配置。配置只会被整体替换而不会被原地修改，
+// 每个请求开始时取一次，之后整个请求都使用这一份，避免中途读到新旧混合的设置
+func (s *Server) currentConfig() *config.Config {
+	return s.config.Load()
 }
 
-// Start 启动服务器
+// Start 启动服务器，直到出错或被 Shutdown 停止（此时返回 http.ErrServerClosed）
 func (s *Server) Start(port string) error {
 	http.HandleFunc("/", s.handleIndex)
-	http.HandleFunc("/api/videos", s.handleVideos)
-	http.HandleFunc("/api/annotation/", s.handleAnnotation)
-	http.HandleFunc("/api/model-annotation/", s.handleModelAnnotation)
-	http.HandleFunc("/api/video/", s.handleVideo)
-	http.HandleFunc("/api/config", s.handleConfig)
-	http.HandleFunc("/api/dialog", s.handleDialog)
+	http.HandleFunc("/api/login", s.handleLogin)
+	http.HandleFunc("/api/logout", s.handleLogout)
+	http.HandleFunc("/api/me", s.handleMe)
+
+	// 多用户模式下其余接口需要登录，修改配置和打开本机文件对话框只允许管理员
+	http.HandleFunc("/api/videos", s.requireUser(s.handleVideos))
+	http.HandleFunc("/api/annotation/", s.requireUser(s.handleAnnotation))
+	http.HandleFunc("/api/model-annotation/", s.requireUser(s.handleModelAnnotation))
+	http.HandleFunc("/api/video/", s.requireUser(s.handleVideo))
+	http.HandleFunc("/api/export/", s.requireUser(s.handleExport))
+	http.HandleFunc("/api/config", s.requireUser(s.handleConfig))
+	http.HandleFunc("/api/dialog", s.requireAdmin(s.handleDialog))
+	http.HandleFunc("/api/events", s.requireUser(s.handleEvents))
+	http.HandleFunc("/api/lock/", s.requireUser(s.handleLock))
+	http.HandleFunc("/api/locks", s.requireUser(s.handleLocks))
+
+	// 后台定期增量扫描视频目录，并轮询标注目录的变化
+	go s.library.run(s.currentConfig)
+	go s.watchAnnotations()
+	if s.git != nil {
+		go s.runGitBatches()
+	}
 
 	// 静态文件服务 - 使用嵌入的文件系统
 	staticFS, err := fs.Sub(s.webFS, "web/static")
@@ -57,7 +137,18 @@ func (s *Server) Start(port string) error {
 	addr := ":" + port
 	log.Printf("服务器启动在 http://localhost%s", addr)
 	log.Printf("请在浏览器中访问: http://localhost%s", addr)
-	return http.ListenAndServe(addr, nil)
+	s.http.Addr = addr
+	return s.http.ListenAndServe()
+}
+
+// Shutdown 停止接受新请求，并等待正在处理的请求（如保存标注）完成或 ctx 到期
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// Close 在退出前提交 git 批量模式下等待的修改，应在 Shutdown 之后调用
+func (s *Server) Close() {
+	s.flushGit()
 }
 
 // handleIndex 处理主页
@@ -86,19 +177,52 @@ func (s *Server) handleVideos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videos, err := video.ScanVideos(s.config.VideoDir, s.config.TaskFile)
+	// 从内存返回最近一次扫描的结果（已匹配标注并读取元数据）；?refresh=1 强制立即重新扫描
+	if r.URL.Query().Get("refresh") == "1" {
+		s.library.invalidate()
+	}
+	view, err := s.library.list(s.currentConfig())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to scan videos: %v", err), http.StatusInternalServerError)
 		return
 	}
+	videos := view.videos
 
-	// 匹配预标注和已有标注
-	video.MatchAnnotations(videos, s.config.PreAnnotationDir, s.config.OutputDir)
+	// 任务文件中出现的所有负责人，供页面筛选
+	assignees := taskAssignees(videos)
+
+	// ?assignee= 只返回分配给该负责人的视频（不区分大小写），统计信息也只统计这些视频
+	if assignee := strings.TrimSpace(r.URL.Query().Get("assignee")); assignee != "" {
+		filtered := videos[:0]
+		for _, v := range videos {
+			if strings.EqualFold(v.Assignee, assignee) {
+				filtered = append(filtered, v)
+			}
+		}
+		videos = filtered
+	}
+
+	// 排序：?sort=duration 按时长从长到短，?sort=priority 按任务优先级和截止日期，默认保持扫描顺序
+	switch r.URL.Query().Get("sort") {
+	case "priority":
+		sort.SliceStable(videos, func(i, j int) bool {
+			return video.ComparePriority(&videos[i].TaskInfo, &videos[j].TaskInfo) < 0
+		})
+	case "duration":
+		sort.SliceStable(videos, func(i, j int) bool {
+			return videos[i].DurationMs > videos[j].DurationMs
+		})
+	case "name":
+		sort.SliceStable(videos, func(i, j int) bool {
+			return videos[i].Stem < videos[j].Stem
+		})
+	}
 
 	// 计算统计信息
 	totalCount := len(videos)
 	annotatedCount := 0
 	preAnnotatedCount := 0
+	var totalDurationMs, remainingDurationMs int64
 	
 	for _, v := range videos {
 		if v.HasAnnotation {
@@ -106,16 +230,39 @@ func (s *Server) handleVideos(w http.ResponseWriter, r *http.Request) {
 		} else if v.HasPreAnnotation {
 			preAnnotatedCount++
 		}
+		totalDurationMs += v.DurationMs
+		if !v.HasAnnotation {
+			remainingDurationMs += v.DurationMs
+		}
+	}
+
+	// 附上未过期的编辑锁，页面据此显示正在编辑的人员
+	locks := s.locks.active()
+	entries := make([]videoListEntry, len(videos))
+	for i, v := range videos {
+		entries[i].VideoInfo = v
+		if lock, ok := locks[v.Key]; ok {
+			entries[i].Lock = &lock
+		}
 	}
 
-	// 返回视频列表和统计信息
+	// 返回视频列表和统计信息；collisions 列出不同子目录中的同名视频（stem -> key 列表）
 	response := map[string]interface{}{
-		"videos": videos,
-		"stats": map[string]int{
-			"total":        totalCount,
-			"annotated":    annotatedCount,
-			"pre_annotated": preAnnotatedCount,
-			"unannotated":  totalCount - annotatedCount - preAnnotatedCount,
+		"videos":     entries,
+		"collisions": video.FindCollisions(videos),
+		"assignees":  assignees,
+		"scanned_at": view.scannedAt,
+		// 任务文件中没有对应视频、重复或大小写不一致的项
+		"task_warnings": view.taskWarnings,
+		// 同一目录中只差扩展名的视频，只有其中一个保留原来的 key 和已有的标注
+		"key_warnings": view.keyWarnings,
+		"stats": map[string]int64{
+			"total":                 int64(totalCount),
+			"annotated":             int64(annotatedCount),
+			"pre_annotated":         int64(preAnnotatedCount),
+			"unannotated":           int64(totalCount - annotatedCount - preAnnotatedCount),
+			"total_duration_ms":     totalDurationMs,     // 视频总时长
+			"remaining_duration_ms": remainingDurationMs, // 尚未标注视频的总时长，用于估算工作量
 		},
 	}
 
@@ -123,6 +270,26 @@ func (s *Server) handleVideos(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// videoListEntry 是视频列表中的一项：视频信息及其编辑锁
+type videoListEntry struct {
+	video.VideoInfo
+	Lock *videoLock `json:"lock,omitempty"` // 正在编辑该视频的人员，没有时省略
+}
+
+// taskAssignees 返回视频列表中出现的负责人（按名称排序，去重）
+func taskAssignees(videos []video.VideoInfo) []string {
+	seen := make(map[string]bool)
+	assignees := []string{}
+	for _, v := range videos {
+		if v.Assignee != "" && !seen[v.Assignee] {
+			seen[v.Assignee] = true
+			assignees = append(assignees, v.Assignee)
+		}
+	}
+	sort.Strings(assignees)
+	return assignees
+}
+
 // handleAnnotation 处理标注请求
 func (s *Server) handleAnnotation(w http.ResponseWriter, r *http.Request) {
 	// 提取文件名
@@ -132,63 +299,131 @@ func (s *Server) handleAnnotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 修订历史：/api/annotation/{key}/history 和 /api/annotation/{key}/revert
+	// 请求标注本身时路径总是带扩展名，不会与名为 history 或 revert 的视频混淆
+	cfg := s.currentConfig()
+	for suffix, handler := range map[string]func(http.ResponseWriter, *http.Request, *config.Config, string){
+		"/history": s.handleHistory,
+		"/revert":  s.revertAnnotation,
+	} {
+		if key, ok := strings.CutSuffix(filename, suffix); ok {
+			if !annotation.ValidKey(key) {
+				http.Error(w, "Invalid annotation key", http.StatusBadRequest)
+				return
+			}
+			handler(w, r, cfg, key)
+			return
+		}
+	}
+
+	// stem 为标注 key：视频相对视频目录的路径（不含扩展名），如 course-a/intro
 	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if !annotation.ValidKey(stem) {
+		http.Error(w, "Invalid annotation key", http.StatusBadRequest)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
-		s.getAnnotation(w, r, stem)
+		s.getAnnotation(w, r, cfg, stem)
 	case http.MethodPost:
-		s.saveAnnotation(w, r, stem)
+		s.saveAnnotation(w, r, cfg, stem)
 	case http.MethodDelete:
-		s.deleteAnnotation(w, r, stem)
+		s.deleteAnnotation(w, r, cfg, stem)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-// getAnnotation 获取标注
-func (s *Server) getAnnotation(w http.ResponseWriter, r *http.Request, stem string) {
-	var ann *annotation.Annotation
-	var err error
+// annotationResponse 标注内容及其解析诊断信息
+type annotationResponse struct {
+	*annotation.Annotation
+	Diagnostics annotation.Diagnostics `json:"diagnostics"`    // 被拒绝或可疑的行
+	Meta        *annotation.Meta       `json:"meta,omitempty"` // 最后保存的人员和时间（多用户模式）
+}
+
+// getAnnotation 获取标注；ETag 响应头为输出目录中标注的版本号，保存和删除时通过 If-Match 传回
+func (s *Server) getAnnotation(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
+	// 先计算版本号再读取内容：两者之间文件被修改时，页面拿到的是较新的内容和较旧的版本号，
+	// 下次保存只会误报冲突，而不会覆盖别人的修改
+	etag, err := s.annotationETag(cfg, stem)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read annotation: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
 
 	// 优先从输出目录读取
-	if s.config.OutputDir != "" {
-		outputPath := video.GetAnnotationPath(stem, s.config.OutputDir)
-		if outputPath != "" {
-			if ann, err = annotation.ParseFile(outputPath); err == nil {
-				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(ann)
-				return
-			}
-		}
+	if saved := s.loadOutputAnnotation(cfg, stem); saved != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(saved)
+		return
 	}
 
 	// 从预标注目录读取
-	if s.config.PreAnnotationDir != "" {
-		prePath := video.GetPreAnnotationPath(stem, s.config.PreAnnotationDir)
+	keys := s.annotationKeys(cfg, stem)
+	if cfg.PreAnnotationDir != "" {
+		prePath := video.FindAnnotationPathForKeys(keys, cfg.PreAnnotationDir, "")
 		if prePath != "" {
-			if ann, err = annotation.ParseFile(prePath); err == nil {
+			if ann, diags, err := annotation.ParseFileWithOptions(prePath, annotation.ParseOptions{}); err == nil {
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(ann)
+				json.NewEncoder(w).Encode(annotationResponse{Annotation: ann, Diagnostics: diags})
 				return
 			}
 		}
 	}
 
 	// 都没有，返回空标注
-	ann = &annotation.Annotation{
+	ann := &annotation.Annotation{
 		Title:      "",
 		IsTutorial: true,
 		Steps:      []annotation.Step{},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ann)
+	json.NewEncoder(w).Encode(annotationResponse{Annotation: ann, Diagnostics: annotation.Diagnostics{}})
+}
+
+// validateOptions 返回保存标注时的验证选项：配置中的规则，加上视频时长和场景切换点（videoPath 为空时不检查）
+func (s *Server) validateOptions(cfg *config.Config, videoPath string) annotation.ValidateOptions {
+	opts := cfg.ValidateOptions()
+	if videoPath == "" {
+		return opts
+	}
+	duration, err := video.ReadDuration(videoPath)
+	if err != nil {
+		log.Printf("Failed to read duration of %s: %v", videoPath, err)
+	}
+	opts.VideoDuration = duration
+
+	if opts.SceneChangeDistance > 0 {
+		if keyframes, err := s.keyframes.Get(videoPath); err != nil {
+			log.Printf("Failed to read keyframes of %s: %v", videoPath, err)
+		} else {
+			opts.SceneChanges = video.SceneChanges(keyframes)
+		}
+	}
+	return opts
+}
+
+// validateAnnotation 验证标注，失败时写入包含完整报告的 400 响应并返回 false，便于页面一次性标出所有问题
+func validateAnnotation(w http.ResponseWriter, ann *annotation.Annotation, opts annotation.ValidateOptions) (*annotation.ValidationReport, bool) {
+	report := annotation.Validate(ann, opts)
+	if !report.Valid() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  fmt.Sprintf("Annotation validation failed: %v", report.Err()),
+			"report": report,
+		})
+		return report, false
+	}
+	return report, true
 }
 
 // saveAnnotation 保存标注
-func (s *Server) saveAnnotation(w http.ResponseWriter, r *http.Request, stem string) {
-	if s.config.OutputDir == "" {
+func (s *Server) saveAnnotation(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
+	if cfg.OutputDir == "" {
 		http.Error(w, "Output directory not set", http.StatusBadRequest)
 		return
 	}
@@ -199,46 +434,98 @@ func (s *Server) saveAnnotation(w http.ResponseWriter, r *http.Request, stem str
 		return
 	}
 
-	// 验证标注
-	if err := annotation.ValidateAnnotation(&ann); err != nil {
-		http.Error(w, fmt.Sprintf("Annotation validation failed: %v", err), http.StatusBadRequest)
+	videoPath := s.findVideoPath(cfg, stem)
+	opts := s.validateOptions(cfg, videoPath)
+	snapped := false
+	// 将步骤时间对齐到帧，消除不同标注人员之间的毫秒级差异
+	if videoPath != "" && cfg.SnapToFrames && ann.IsTutorial {
+		if table, err := video.ReadFrameTable(videoPath); err != nil {
+			log.Printf("Failed to read frames of %s: %v", videoPath, err)
+		} else {
+			video.SnapSteps(&ann, table)
+			snapped = true
+		}
+	}
+
+	report, ok := validateAnnotation(w, &ann, opts)
+	if !ok {
+		return
+	}
+
+	// 从版本检查到写完元数据之间不允许其他保存或删除
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if !s.checkLock(w, r, stem) || !s.checkIfMatch(w, r, cfg, stem) {
 		return
 	}
 
-	// 保存文件
-	outputPath := video.GetAnnotationPath(stem, s.config.OutputDir)
+	// 保存文件（格式由配置决定，按视频目录结构存放）
+	s.recordHistoryBaseline(cfg, stem)
+	outputPath := video.GetAnnotationPathWithExt(stem, cfg.OutputDir, cfg.AnnotationExt())
 	if err := ann.Save(outputPath); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to save: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.removeStaleAnnotations(cfg, stem, outputPath)
+	s.library.setAnnotated(stem, true)
+	s.recordEdit(r, cfg, stem, annotation.EditSave)
+	rev := s.recordRevision(r, cfg, stem, annotation.EditSave, 0)
+	s.recordCommit(r, cfg, stem, annotation.EditSave, rev, 0)
+	if etag, err := s.annotationETag(cfg, stem); err == nil {
+		w.Header().Set("ETag", etag)
+	}
 
+	response := map[string]interface{}{
+		"status": "success",
+		"report": report,
+	}
+	if snapped {
+		response["annotation"] = ann
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(response)
 }
 
 // deleteAnnotation 删除标注
-func (s *Server) deleteAnnotation(w http.ResponseWriter, r *http.Request, stem string) {
-	if s.config.OutputDir == "" {
+func (s *Server) deleteAnnotation(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
+	if cfg.OutputDir == "" {
 		http.Error(w, "Output directory not set", http.StatusBadRequest)
 		return
 	}
 
-	outputPath := video.GetAnnotationPath(stem, s.config.OutputDir)
-	if outputPath == "" {
-		http.Error(w, "Annotation file does not exist", http.StatusNotFound)
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if !s.checkLock(w, r, stem) || !s.checkIfMatch(w, r, cfg, stem) {
 		return
 	}
 
-	// 删除文件
-	if err := os.Remove(outputPath); err != nil {
-		if os.IsNotExist(err) {
-			http.Error(w, "Annotation file does not exist", http.StatusNotFound)
-		} else {
-			http.Error(w, fmt.Sprintf("Failed to delete: %v", err), http.StatusInternalServerError)
+	// 删除所有格式的标注文件（包括旧平铺位置），删除前的内容保留在修订历史中
+	s.recordHistoryBaseline(cfg, stem)
+	removed := 0
+	for _, key := range s.annotationKeys(cfg, stem) {
+		for _, ext := range annotation.Extensions() {
+			outputPath := video.GetAnnotationPathWithExt(key, cfg.OutputDir, ext)
+			if err := os.Remove(outputPath); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				http.Error(w, fmt.Sprintf("Failed to delete: %v", err), http.StatusInternalServerError)
+				return
+			}
+			removed++
 		}
+	}
+
+	if removed == 0 {
+		http.Error(w, "Annotation file does not exist", http.StatusNotFound)
 		return
 	}
+	s.library.setAnnotated(stem, false)
+	s.recordEdit(r, cfg, stem, annotation.EditDelete)
+	rev := s.recordRevision(r, cfg, stem, annotation.EditDelete, 0)
+	s.recordCommit(r, cfg, stem, annotation.EditDelete, rev, 0)
 
+	w.Header().Set("ETag", noAnnotationETag)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
@@ -256,10 +543,24 @@ func (s *Server) handleVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videoPath := filepath.Join(s.config.VideoDir, filename)
+	cfg := s.currentConfig()
+
+	// /api/video/{name}/frames 返回帧时间信息
+	if name, ok := strings.CutSuffix(filename, "/frames"); ok {
+		s.getFrames(w, r, cfg, name)
+		return
+	}
+
+	// /api/video/{name}/keyframes 返回关键帧时间
+	if name, ok := strings.CutSuffix(filename, "/keyframes"); ok {
+		s.getKeyframes(w, r, cfg, name)
+		return
+	}
+
+	videoPath := filepath.Join(cfg.VideoDir, filename)
 	
 	// 安全检查：确保文件在视频目录内（使用绝对路径比较）
-	absVideoDir, err := filepath.Abs(s.config.VideoDir)
+	absVideoDir, err := filepath.Abs(cfg.VideoDir)
 	if err != nil {
 		http.Error(w, "Failed to get video directory path", http.StatusInternalServerError)
 		return
@@ -274,9 +575,212 @@ func (s *Server) handleVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 系统 MIME 表不一定包含 .webm、.mkv 等扩展名
+	w.Header().Set("Content-Type", video.MIMETypeForPath(videoPath))
 	http.ServeFile(w, r, videoPath)
 }
 
+// getFrames 返回视频的帧信息：/api/video/{name}/frames?near=mm:ss.SSS|秒数&count=N
+// 给出 near 时返回最接近的帧及其前后各 count 帧（默认 2），否则只返回汇总信息
+func (s *Server) getFrames(w http.ResponseWriter, r *http.Request, cfg *config.Config, name string) {
+	videoPath := s.findVideoPathByName(cfg, name)
+	if videoPath == "" {
+		http.Error(w, "Video file does not exist", http.StatusNotFound)
+		return
+	}
+
+	table, err := video.ReadFrameTable(videoPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read frames: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"timescale":    table.Timescale,
+		"total_frames": len(table.Times),
+	}
+
+	if near := r.URL.Query().Get("near"); near != "" {
+		at, err := parseNearTime(near)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		count := 2
+		if c := r.URL.Query().Get("count"); c != "" {
+			if count, err = strconv.Atoi(c); err != nil || count < 0 || count > 100 {
+				http.Error(w, "Invalid count, must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+		}
+
+		index, timestamp := table.Snap(at)
+		frames := []map[string]interface{}{}
+		for i := max(0, index-count); i <= min(len(table.Times)-1, index+count); i++ {
+			frames = append(frames, frameInfo(table, i))
+		}
+		response["nearest"] = frameInfo(table, index)
+		response["snapped"] = timestamp
+		response["frames"] = frames
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getKeyframes 返回视频的关键帧时间和推测的场景切换点（微秒），结果缓存在磁盘上
+func (s *Server) getKeyframes(w http.ResponseWriter, r *http.Request, cfg *config.Config, name string) {
+	videoPath := s.findVideoPathByName(cfg, name)
+	if videoPath == "" {
+		http.Error(w, "Video file does not exist", http.StatusNotFound)
+		return
+	}
+
+	keyframes, err := s.keyframes.Get(videoPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read keyframes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":            len(keyframes),
+		"keyframes_us":     microseconds(keyframes),
+		"scene_changes_us": microseconds(video.SceneChanges(keyframes)),
+	})
+}
+
+// microseconds 将时间列表转换为微秒，空列表输出 []
+func microseconds(times []time.Duration) []int64 {
+	result := make([]int64, len(times))
+	for i, t := range times {
+		result[i] = t.Microseconds()
+	}
+	return result
+}
+
+// frameInfo 返回单帧的序号和显示时间
+func frameInfo(table *video.FrameTable, index int) map[string]interface{} {
+	return map[string]interface{}{
+		"index":   index,
+		"time_us": table.Times[index].Microseconds(),
+	}
+}
+
+// parseNearTime 解析 near 参数：时间戳（mm:ss.SSS）或秒数（如 12.5）
+func parseNearTime(s string) (time.Duration, error) {
+	if ts, err := annotation.ParseTimestamp(s); err == nil {
+		return ts.Duration(), nil
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid near %q, should be a timestamp or seconds", s)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// handleExport 将已保存的标注导出为章节文件：/api/export/{stem}?format=vtt|srt
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stem := strings.TrimPrefix(r.URL.Path, "/api/export/")
+	if stem == "" {
+		http.Error(w, "Filename cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if !annotation.ValidKey(stem) {
+		http.Error(w, "Invalid annotation key", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = annotation.ChapterFormatWebVTT
+	}
+	var contentType string
+	switch format {
+	case annotation.ChapterFormatWebVTT:
+		contentType = "text/vtt; charset=utf-8"
+	case annotation.ChapterFormatSRT:
+		contentType = "application/x-subrip; charset=utf-8"
+	default:
+		http.Error(w, "Invalid format, must be 'vtt' or 'srt'", http.StatusBadRequest)
+		return
+	}
+
+	cfg := s.currentConfig()
+	annotationPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.OutputDir, cfg.AnnotationExt())
+	if annotationPath == "" {
+		http.Error(w, "Annotation file does not exist", http.StatusNotFound)
+		return
+	}
+	ann, err := annotation.ParseFile(annotationPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse annotation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// 最后一个章节持续到视频结束；视频不存在或无法读取时长时，只有最后一步有结束时间才能导出
+	var duration time.Duration
+	if videoPath := s.findVideoPath(cfg, stem); videoPath != "" {
+		if duration, err = video.ReadDuration(videoPath); err != nil {
+			log.Printf("Failed to read duration of %s: %v", videoPath, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := annotation.EncodeChapters(&buf, ann, format, duration); err != nil {
+		// 导出失败是标注内容或视频的问题，不是服务器错误
+		if errors.Is(err, annotation.ErrDurationRequired) {
+			http.Error(w, "Cannot export: the video is missing or its duration cannot be read, so the final step needs an end time", http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Cannot export: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(stem)+"."+format))
+	w.Write(buf.Bytes())
+}
+
+// findVideo 在内存列表中查找标注 key 对应的视频，找不到时返回 nil
+// 不在列表中的视频（如不在任务文件中）不会另外扫描目录：保存时会多次查找且持有 s.saveMu
+func (s *Server) findVideo(cfg *config.Config, key string) *video.VideoInfo {
+	s.library.ensureFresh(cfg)
+	return s.library.find(key)
+}
+
+// findVideoPath 查找标注 key 对应的视频文件路径，找不到时返回空字符串
+func (s *Server) findVideoPath(cfg *config.Config, key string) string {
+	if v := s.findVideo(cfg, key); v != nil {
+		return v.Path
+	}
+	return ""
+}
+
+// findVideoPathByName 按视频相对路径（含扩展名，页面请求视频时使用）查找视频文件路径，
+// 不在内存列表中时按去掉扩展名的 key 查找
+func (s *Server) findVideoPathByName(cfg *config.Config, name string) string {
+	s.library.ensureFresh(cfg)
+	if v := s.library.findByRelPath(name); v != nil {
+		return v.Path
+	}
+	return s.findVideoPath(cfg, strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// annotationKeys 返回查找 key 对应标注时依次尝试的 key（见 VideoInfo.AnnotationKeys）
+func (s *Server) annotationKeys(cfg *config.Config, key string) []string {
+	if v := s.findVideo(cfg, key); v != nil {
+		return v.AnnotationKeys()
+	}
+	return []string{key}
+}
+
 // handleModelAnnotation 处理模型标注请求（只读）
 func (s *Server) handleModelAnnotation(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -292,13 +796,17 @@ func (s *Server) handleModelAnnotation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
-	s.getModelAnnotation(w, r, stem)
+	if !annotation.ValidKey(stem) {
+		http.Error(w, "Invalid annotation key", http.StatusBadRequest)
+		return
+	}
+	s.getModelAnnotation(w, r, s.currentConfig(), stem)
 }
 
 // getModelAnnotation 获取模型标注
-func (s *Server) getModelAnnotation(w http.ResponseWriter, r *http.Request, stem string) {
+func (s *Server) getModelAnnotation(w http.ResponseWriter, r *http.Request, cfg *config.Config, stem string) {
 	// 如果没有配置模型标注目录，返回空结果
-	if s.config.ModelAnnotationDir == "" {
+	if cfg.ModelAnnotationDir == "" {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"available": false,
@@ -308,7 +816,7 @@ func (s *Server) getModelAnnotation(w http.ResponseWriter, r *http.Request, stem
 	}
 
 	// 从模型标注目录读取
-	modelPath := video.GetAnnotationPath(stem, s.config.ModelAnnotationDir)
+	modelPath := video.FindAnnotationPathForKeys(s.annotationKeys(cfg, stem), cfg.ModelAnnotationDir, "")
 	if modelPath == "" {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -329,7 +837,7 @@ func (s *Server) getModelAnnotation(w http.ResponseWriter, r *http.Request, stem
 	}
 
 	// 解析标注文件
-	ann, err := annotation.ParseFile(modelPath)
+	ann, diags, err := annotation.ParseFileWithOptions(modelPath, annotation.ParseOptions{})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to parse model annotation: %v", err), http.StatusInternalServerError)
 		return
@@ -337,8 +845,9 @@ func (s *Server) getModelAnnotation(w http.ResponseWriter, r *http.Request, stem
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"available": true,
-		"annotation": ann,
+		"available":   true,
+		"annotation":  ann,
+		"diagnostics": diags,
 	})
 }
 
@@ -348,25 +857,42 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		s.getConfig(w, r)
 	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Administrator required", http.StatusForbidden)
+			return
+		}
 		s.saveConfig(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-// getConfig 获取配置
+// getConfig 获取配置；认证设置（用户文件路径、代理请求头和管理员名单）只返回给管理员
 func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := *s.currentConfig()
+	if !isAdmin(r) {
+		cfg.Auth = config.AuthConfig{}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s.config)
+	json.NewEncoder(w).Encode(cfg)
 }
 
 // saveConfig 保存配置
 func (s *Server) saveConfig(w http.ResponseWriter, r *http.Request) {
-	var cfg config.Config
+	// 以当前配置为基础解码，页面未提交的字段（如高级选项）保持不变
+	// 解码结果是新的副本，保存成功后整体替换，正在处理的请求仍使用旧配置
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	current := s.currentConfig()
+	cfg := *current
 	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to parse request: %v", err), http.StatusBadRequest)
 		return
 	}
+	// 认证设置只能通过配置文件修改，避免通过页面关闭登录或把自己设为管理员
+	cfg.Auth = current.Auth
+	// git 存储在服务启动时初始化，同样只能通过配置文件修改
+	cfg.Git = current.Git
 
 	// 验证配置
 	if err := cfg.Validate(); err != nil {
@@ -380,7 +906,8 @@ func (s *Server) saveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.config = &cfg
+	s.config.Store(&cfg)
+	s.library.invalidate() // 目录或扫描选项可能已变化
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
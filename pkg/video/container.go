@@ -0,0 +1,88 @@
+package video
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultExtensions 未配置时扫描的视频扩展名
+var DefaultExtensions = []string{".mp4"}
+
+// containerInfo 描述一种视频容器格式
+type containerInfo struct {
+	Name     string // 容器名称
+	MIMEType string // 播放时使用的 MIME 类型
+	Playable bool   // 主流浏览器能否直接播放
+	ISOBMFF  bool   // 是否为 ISO-BMFF（MP4/QuickTime）结构，可由 Probe 读取元数据
+}
+
+// 已知的容器格式，按扩展名（小写、含点）索引
+// QuickTime 和 Matroska 只有部分浏览器支持，视为不可播放
+var containers = map[string]containerInfo{
+	".mp4":  {Name: "mp4", MIMEType: "video/mp4", Playable: true, ISOBMFF: true},
+	".m4v":  {Name: "mp4", MIMEType: "video/mp4", Playable: true, ISOBMFF: true},
+	".mov":  {Name: "quicktime", MIMEType: "video/quicktime", Playable: false, ISOBMFF: true},
+	".webm": {Name: "webm", MIMEType: "video/webm", Playable: true},
+	".mkv":  {Name: "matroska", MIMEType: "video/x-matroska", Playable: false},
+}
+
+// containerForExt 返回扩展名对应的容器格式，未知扩展名视为不可播放
+func containerForExt(ext string) containerInfo {
+	ext = NormalizeExtension(ext)
+	if c, ok := containers[ext]; ok {
+		return c
+	}
+	return containerInfo{Name: strings.TrimPrefix(ext, "."), MIMEType: "application/octet-stream"}
+}
+
+// MIMETypeForPath 根据扩展名返回视频文件的 MIME 类型
+func MIMETypeForPath(path string) string {
+	return containerForExt(filepath.Ext(path)).MIMEType
+}
+
+// NormalizeExtension 规范化扩展名：小写并带前导点
+func NormalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// ScanOptions 控制视频扫描行为
+type ScanOptions struct {
+	Extensions []string // 接受的视频扩展名（如 ".mp4"），为空时使用 DefaultExtensions
+	Filter     []string // 额外的包含/排除规则（见 Filter），在任务文件之后应用，为空时不过滤
+}
+
+// extensionSet 返回规范化后的扩展名集合
+func (o ScanOptions) extensionSet() map[string]bool {
+	set := make(map[string]bool)
+	for _, ext := range o.extensionList() {
+		set[ext] = true
+	}
+	return set
+}
+
+// extensionList 返回规范化后的扩展名，保持配置中的顺序
+func (o ScanOptions) extensionList() []string {
+	exts := o.Extensions
+	if len(exts) == 0 {
+		exts = DefaultExtensions
+	}
+	list := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		if ext = NormalizeExtension(ext); ext != "" {
+			list = append(list, ext)
+		}
+	}
+	return list
+}
+
+// trimVideoExt 去掉文件名中已接受的视频扩展名（不区分大小写），其他扩展名保持不变
+func trimVideoExt(name string, exts map[string]bool) string {
+	if dot := strings.LastIndex(name, "."); dot > 0 && exts[strings.ToLower(name[dot:])] {
+		return name[:dot]
+	}
+	return name
+}
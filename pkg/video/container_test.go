@@ -0,0 +1,107 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestContainerForExt(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want containerInfo
+	}{
+		{".mp4", containerInfo{Name: "mp4", MIMEType: "video/mp4", Playable: true, ISOBMFF: true}},
+		// 扩展名不区分大小写，可以省略前导点
+		{".M4V", containerInfo{Name: "mp4", MIMEType: "video/mp4", Playable: true, ISOBMFF: true}},
+		{"mov", containerInfo{Name: "quicktime", MIMEType: "video/quicktime", ISOBMFF: true}},
+		{" .WebM ", containerInfo{Name: "webm", MIMEType: "video/webm", Playable: true}},
+		{".mkv", containerInfo{Name: "matroska", MIMEType: "video/x-matroska"}},
+		// 配置中添加的未知扩展名按不可播放处理
+		{".AVI", containerInfo{Name: "avi", MIMEType: "application/octet-stream"}},
+		{"", containerInfo{MIMEType: "application/octet-stream"}},
+	}
+	for _, tt := range tests {
+		if got := containerForExt(tt.ext); got != tt.want {
+			t.Errorf("containerForExt(%q) = %+v, want %+v", tt.ext, got, tt.want)
+		}
+	}
+
+	if got := MIMETypeForPath("course/a.Mov"); got != "video/quicktime" {
+		t.Errorf("MIMETypeForPath = %q", got)
+	}
+}
+
+func TestExtensionList(t *testing.T) {
+	tests := []struct {
+		exts []string
+		want []string
+	}{
+		{nil, []string{".mp4"}},
+		{[]string{"MOV", " .webm", "", ".mp4"}, []string{".mov", ".webm", ".mp4"}},
+	}
+	for _, tt := range tests {
+		if got := (ScanOptions{Extensions: tt.exts}).extensionList(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extensionList(%q) = %q, want %q", tt.exts, got, tt.want)
+		}
+	}
+
+	exts := ScanOptions{Extensions: []string{"mov", ".mp4"}}.extensionSet()
+	for name, want := range map[string]string{
+		"clip.MOV":     "clip",
+		"clip.mp4":     "clip",
+		"clip.webm":    "clip.webm",
+		"v1.2.mp4":     "v1.2",
+		".mp4":         ".mp4",
+		"archive.tar":  "archive.tar",
+		"clip.mov.bak": "clip.mov.bak",
+	} {
+		if got := trimVideoExt(name, exts); got != want {
+			t.Errorf("trimVideoExt(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestScanContainers(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "a.MOV", "b.webm", "c.avi", "d.mp4", "e.mkv")
+
+	videos, err := ScanVideosWithOptions(dir, "", ScanOptions{Extensions: []string{"mov", ".WEBM", "avi", "mkv"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(videos, func(i, j int) bool { return videos[i].Key < videos[j].Key })
+	type container struct {
+		Key, Container, MIMEType string
+		Playable                 bool
+	}
+	var got []container
+	for _, v := range videos {
+		got = append(got, container{v.Key, v.Container, v.MIMEType, v.Playable})
+	}
+	// d.mp4 不在配置的扩展名中，不被扫描
+	want := []container{
+		{"a", "quicktime", "video/quicktime", false},
+		{"b", "webm", "video/webm", true},
+		{"c", "avi", "application/octet-stream", false},
+		{"e", "matroska", "video/x-matroska", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("videos = %+v, want %+v", got, want)
+	}
+
+	// 只有 MP4/QuickTime 结构的容器读取元数据，其他容器不报告读取失败
+	for _, v := range videos {
+		if err := os.WriteFile(v.Path, []byte("not a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ProbeVideos(videos)
+	for _, v := range videos {
+		if wantErr := filepath.Ext(v.Path) == ".MOV"; (v.ProbeError != "") != wantErr {
+			t.Errorf("%s: probe error = %q, want error %v", v.Filename, v.ProbeError, wantErr)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package video
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Filter 是一组按顺序求值的包含/排除规则，语义与 .gitignore 类似，后面的规则优先：
+//   - 以 ! 开头表示排除
+//   - 以 re: 开头表示正则表达式（RE2 语法），匹配相对视频目录的路径（含或不含扩展名均可）
+//   - 其余为 glob（可加 glob: 前缀）：* 和 ? 不跨越 /，** 匹配任意层目录，[abc] 匹配字符集合
+//   - 不含 / 的 glob 只匹配文件名（任意层级），含 / 或以 / 开头的 glob 匹配完整相对路径
+//
+// 没有任何规则匹配的视频：存在包含规则时排除，只有排除规则时保留
+type Filter struct {
+	rules      []filterRule
+	hasInclude bool
+}
+
+// filterRule 表示一条包含或排除规则
+type filterRule struct {
+	text    string         // 原始文本
+	exclude bool           // 是否为排除规则
+	re      *regexp.Regexp // 编译后的匹配表达式
+	base    bool           // 只匹配文件名
+	order   int            // 求值顺序（任务文件中为行号）
+	entry   *TaskEntry     // 来自任务文件时对应的任务项
+}
+
+// IsPattern 判断任务文件中的名称是否为规则：以 glob: 或 re: 开头，排除规则再加 !（如 !glob:*_test）
+// 其余都是普通视频名称，其中的 * ? [ ! 按原样匹配，已有任务文件中的 lesson[1] 等名称不受影响
+func IsPattern(s string) bool {
+	s = strings.TrimSpace(strings.TrimPrefix(s, "!"))
+	return strings.HasPrefix(s, "glob:") || strings.HasPrefix(s, "re:")
+}
+
+// hasGlobChars 判断名称中是否含有 glob 字符，用于提示任务文件中漏写了 glob: 前缀
+func hasGlobChars(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// ParseFilter 解析规则列表（如命令行的 -filter），按给出的顺序求值；空列表返回 nil（不过滤）
+func ParseFilter(patterns []string) (*Filter, error) {
+	var f *Filter
+	for i, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		rule, err := compileRule(p, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			f = &Filter{}
+		}
+		f.add(rule)
+	}
+	return f, nil
+}
+
+// add 追加一条规则
+func (f *Filter) add(rule filterRule) {
+	f.rules = append(f.rules, rule)
+	if !rule.exclude {
+		f.hasInclude = true
+	}
+}
+
+// Match 判断视频（key 为相对路径去掉扩展名，relPath 为相对路径，均以 / 分隔）是否通过过滤；nil 表示不过滤
+func (f *Filter) Match(key, relPath string) bool {
+	if f == nil {
+		return true
+	}
+	if rule := f.last(key, relPath); rule != nil {
+		return !rule.exclude
+	}
+	return !f.hasInclude
+}
+
+// last 返回最后一条匹配的规则，没有时返回 nil
+func (f *Filter) last(key, relPath string) *filterRule {
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		if f.rules[i].match(key, relPath) {
+			return &f.rules[i]
+		}
+	}
+	return nil
+}
+
+// compileRule 将一条规则编译为正则表达式
+func compileRule(text string, order int) (filterRule, error) {
+	rule := filterRule{text: text, order: order}
+	pattern := text
+	if strings.HasPrefix(pattern, "!") {
+		rule.exclude = true
+		pattern = strings.TrimSpace(pattern[1:])
+	}
+	if pattern == "" {
+		return rule, fmt.Errorf("empty pattern %q", text)
+	}
+
+	if strings.HasPrefix(pattern, "re:") {
+		re, err := regexp.Compile(pattern[len("re:"):])
+		if err != nil {
+			return rule, fmt.Errorf("invalid regular expression %q: %w", text, err)
+		}
+		rule.re = re
+		return rule, nil
+	}
+
+	pattern = strings.TrimPrefix(pattern, "glob:")
+	if pattern == "" {
+		return rule, fmt.Errorf("empty pattern %q", text)
+	}
+	rule.base = !strings.Contains(pattern, "/")
+	re, err := regexp.Compile("^" + globToRegexp(strings.TrimPrefix(pattern, "/")) + "$")
+	if err != nil {
+		return rule, fmt.Errorf("invalid pattern %q: %w", text, err)
+	}
+	rule.re = re
+	return rule, nil
+}
+
+// globToRegexp 将 glob 转换为正则表达式（不含首尾锚点）
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); {
+		c, size := utf8.DecodeRuneInString(glob[i:])
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?") // 零层或多层目录
+			size = 3
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			size = 2
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				break
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			size = end + 2
+		default:
+			// 按字符（而不是字节）转义，保证中文等多字节文件名不被拆开
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// match 判断规则是否匹配视频
+func (r *filterRule) match(key, relPath string) bool {
+	if r.base {
+		return r.re.MatchString(path.Base(key)) || r.re.MatchString(path.Base(relPath))
+	}
+	return r.re.MatchString(key) || r.re.MatchString(relPath)
+}
@@ -0,0 +1,83 @@
+package video
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		relPath  string
+		want     bool
+	}{
+		{"star matches basename", []string{"intro*"}, "course/intro01.mp4", true},
+		{"star does not match other names", []string{"intro*"}, "course/outro.mp4", false},
+		{"star does not cross slash", []string{"course/*"}, "course/a/b.mp4", false},
+		{"star within directory", []string{"course/*"}, "course/b.mp4", true},
+		{"question mark matches one char", []string{"ep?.mp4"}, "ep1.mp4", true},
+		{"question mark needs one char", []string{"ep?.mp4"}, "ep10.mp4", false},
+		{"character class", []string{"ep[12].mp4"}, "ep2.mp4", true},
+		{"character class miss", []string{"ep[12].mp4"}, "ep3.mp4", false},
+		{"negated character class", []string{"ep[!12].mp4"}, "ep3.mp4", true},
+		{"unclosed bracket is literal", []string{"ep[1"}, "ep[1.mp4", true},
+		{"double star any depth", []string{"course/**/intro"}, "course/a/b/intro.mp4", true},
+		{"double star zero depth", []string{"course/**/intro"}, "course/intro.mp4", true},
+		{"trailing double star", []string{"course/**"}, "course/a/b.mp4", true},
+		{"leading slash anchors to root", []string{"/intro"}, "course/intro.mp4", false},
+		{"dot is literal", []string{"a.b"}, "axb.mp4", false},
+		{"non-ASCII prefix", []string{"教程*"}, "课程/教程01.mp4", true},
+		{"non-ASCII exact", []string{"课程/教程01"}, "课程/教程01.mp4", true},
+		{"non-ASCII question mark is one rune", []string{"教程?"}, "教程一.mp4", true},
+		{"non-ASCII character class", []string{"[甲乙]篇"}, "乙篇.mp4", true},
+		{"non-ASCII miss", []string{"教程*"}, "课程/练习01.mp4", false},
+		{"exclude only keeps others", []string{"!*_test"}, "a.mp4", true},
+		{"exclude only removes match", []string{"!*_test"}, "a_test.mp4", false},
+		{"later rule wins", []string{"course/**", "!course/draft/**"}, "course/draft/a.mp4", false},
+		{"later include wins", []string{"!course/**", "course/keep"}, "course/keep.mp4", true},
+		{"regular expression", []string{`re:^ep\d+$`}, "ep12.mp4", true},
+		{"glob prefix", []string{"glob:ep?"}, "ep1.mp4", true},
+		{"excluded glob prefix", []string{"!glob:ep?"}, "ep1.mp4", false},
+		{"no rules", nil, "a.mp4", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilter(tt.patterns)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q): %v", tt.patterns, err)
+			}
+			key := tt.relPath[:len(tt.relPath)-len(".mp4")]
+			if got := f.Match(key, tt.relPath); got != tt.want {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.relPath, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, p := range []string{"!", "re:(", "re:[a", "glob:", "!glob:"} {
+		if _, err := ParseFilter([]string{p}); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want error", p)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	// 任务文件中只有带 glob: 或 re: 前缀的项是规则，其余按原样匹配视频名称
+	tests := map[string]bool{
+		"course/intro":  false,
+		"教程01":          false,
+		"intro*":        false,
+		"lesson[1]":     false,
+		"!draft":        false,
+		"glob:intro*":   true,
+		"!glob:*_test":  true,
+		"! glob:*_test": true,
+		"re:^a":         true,
+		"!re:^a":        true,
+	}
+	for s, want := range tests {
+		if got := IsPattern(s); got != want {
+			t.Errorf("IsPattern(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
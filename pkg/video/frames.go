@@ -0,0 +1,329 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+// FrameTable 表示视频轨道每一帧的显示时间（按时间升序排列）
+type FrameTable struct {
+	Timescale uint32          // 视频轨道时间刻度
+	Times     []time.Duration // 第 i 帧（显示顺序）的显示时间
+}
+
+// ReadFrameTable 根据视频轨道的 stts/ctts（分片 MP4 为 trun）计算每一帧的显示时间
+// 已考虑 edts/elst 编辑列表带来的整体偏移
+func ReadFrameTable(path string) (*FrameTable, error) {
+	track, samples, err := readVideoSamples(path)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &FrameTable{Timescale: track.Timescale, Times: make([]time.Duration, len(samples))}
+	for i, s := range samples {
+		table.Times[i] = track.presentationTime(s.PTS)
+	}
+	sort.Slice(table.Times, func(i, j int) bool { return table.Times[i] < table.Times[j] })
+	return table, nil
+}
+
+// videoSample 表示视频轨道中的一个采样（按解码顺序）
+type videoSample struct {
+	PTS  int64 // 显示时间（以轨道 timescale 为单位，未应用编辑列表）
+	Sync bool  // 是否为同步采样（关键帧）
+}
+
+// readVideoSamples 读取第一个视频轨道的所有采样
+// 同步采样来自 stss（不存在 stss 时所有采样都是同步采样），分片 MP4 来自采样标志
+func readVideoSamples(path string) (*mp4Track, []videoSample, error) {
+	m, err := openMP4(path, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var track *mp4Track
+	tracks := parseTracks(m.Moov)
+	for i := range tracks {
+		if tracks[i].Handler == "vide" {
+			track = &tracks[i]
+			break
+		}
+	}
+	if track == nil || track.Timescale == 0 {
+		return nil, nil, fmt.Errorf("video track not found in %s", filepath.Base(path))
+	}
+
+	// stts 中的采样数必须与 stsz 一致，采样数由 stsz 确定，避免按损坏的 stts 分配过多内存
+	stts := parseStts(track.Stbl)
+	count, err := parseSampleCount(track.Stbl, m.Size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sample table in %s: %w", filepath.Base(path), err)
+	}
+	var sttsCount uint64
+	for _, e := range stts {
+		sttsCount += uint64(e.Count)
+	}
+	if sttsCount != uint64(count) {
+		return nil, nil, fmt.Errorf("invalid sample table in %s: stts has %d samples, stsz has %d", filepath.Base(path), sttsCount, count)
+	}
+
+	// 解码时间 + 组合偏移 = 显示时间（以轨道 timescale 为单位）
+	samples := make([]videoSample, 0, count)
+	var dts int64
+	offsets := parseCtts(track.Stbl)
+	syncSamples, hasStss := parseStss(track.Stbl)
+	for _, e := range stts {
+		for i := uint32(0); i < e.Count; i++ {
+			number := uint32(len(samples) + 1) // stss 中的采样编号从 1 开始
+			samples = append(samples, videoSample{
+				PTS:  dts + offsets.next(),
+				Sync: !hasStss || syncSamples[number],
+			})
+			dts += int64(e.Delta)
+		}
+	}
+
+	if len(m.Moofs) > 0 {
+		mvex, _ := childBox(m.Moov, "mvex")
+		for _, run := range fragmentRuns(m.Moofs, track.ID, parseTrex(mvex)[track.ID]) {
+			if run.BaseDecodeTime != ^uint64(0) {
+				dts = int64(run.BaseDecodeTime)
+			}
+			for _, s := range run.Samples {
+				samples = append(samples, videoSample{
+					PTS:  dts + int64(s.CTO),
+					Sync: s.Flags&sampleIsNonSync == 0,
+				})
+				dts += int64(s.Duration)
+			}
+		}
+	}
+
+	if len(samples) == 0 {
+		return nil, nil, fmt.Errorf("no video frames found in %s", filepath.Base(path))
+	}
+	return track, samples, nil
+}
+
+// parseSampleCount 返回 stsz（或 stz2）中的采样数；采样数超过 maxTrackSamples、
+// 超出采样大小表或按固定采样大小超出文件大小时返回错误
+func parseSampleCount(stbl []byte, fileSize int64) (int, error) {
+	box, ok := childBox(stbl, "stsz")
+	compact := false
+	if !ok {
+		if box, ok = childBox(stbl, "stz2"); !ok {
+			return 0, fmt.Errorf("missing stsz box")
+		}
+		compact = true
+	}
+	_, _, body, err := fullBoxHeader(box)
+	if err != nil || len(body) < 8 {
+		return 0, fmt.Errorf("truncated stsz box")
+	}
+	// stsz：sample_size(4) sample_count(4) [entry_size(4)...]
+	// stz2：reserved(3) field_size(1) sample_count(4) entry_size...
+	sampleSize := binary.BigEndian.Uint32(body[0:4])
+	count := uint64(binary.BigEndian.Uint32(body[4:8]))
+	table := uint64(len(body) - 8)
+
+	switch {
+	case count > maxTrackSamples:
+		return 0, fmt.Errorf("too many samples (%d)", count)
+	case compact:
+		if fieldSize := uint64(body[3]); count*fieldSize > table*8 {
+			return 0, fmt.Errorf("truncated stz2 box")
+		}
+	case sampleSize == 0:
+		if count > table/4 {
+			return 0, fmt.Errorf("truncated stsz box")
+		}
+	case count*uint64(sampleSize) > uint64(fileSize):
+		return 0, fmt.Errorf("%d samples of %d bytes exceed the file size", count, sampleSize)
+	}
+	return int(count), nil
+}
+
+// 分片采样标志中的 sample_is_non_sync_sample 位
+const sampleIsNonSync = 0x10000
+
+// presentationTime 将轨道内的显示时间按编辑列表换算为影片时间
+func (t *mp4Track) presentationTime(pts int64) time.Duration {
+	pts -= t.EditMediaTime
+	if pts < 0 {
+		pts = 0
+	}
+	return t.EditDelay + scaleDuration(uint64(pts), t.Timescale)
+}
+
+// parseStss 解析 stbl 中的 stss（同步采样）表，第二个返回值表示是否存在 stss
+func parseStss(stbl []byte) (map[uint32]bool, bool) {
+	stss, ok := childBox(stbl, "stss")
+	if !ok {
+		return nil, false
+	}
+	_, _, body, err := fullBoxHeader(stss)
+	if err != nil || len(body) < 4 {
+		return nil, true
+	}
+	n := int(binary.BigEndian.Uint32(body[0:4]))
+	body = body[4:]
+	if n > len(body)/4 {
+		n = len(body) / 4
+	}
+
+	samples := make(map[uint32]bool, n)
+	for i := 0; i < n; i++ {
+		samples[binary.BigEndian.Uint32(body[i*4:i*4+4])] = true
+	}
+	return samples, true
+}
+
+// Nearest 返回与 d 最接近的帧序号（从 0 开始）
+func (t *FrameTable) Nearest(d time.Duration) int {
+	i := sort.Search(len(t.Times), func(i int) bool { return t.Times[i] >= d })
+	if i == len(t.Times) {
+		return len(t.Times) - 1
+	}
+	if i > 0 && d-t.Times[i-1] <= t.Times[i]-d {
+		return i - 1
+	}
+	return i
+}
+
+// Snap 将 d 对齐到最接近的帧，返回帧序号和对齐后的时间戳
+// 时间戳向上取整到毫秒，保证按该时间定位时显示的正是这一帧
+func (t *FrameTable) Snap(d time.Duration) (int, annotation.Timestamp) {
+	index := t.Nearest(d)
+	return index, t.timestamp(index)
+}
+
+// timestamp 返回第 index 帧向上取整到毫秒的时间戳
+func (t *FrameTable) timestamp(index int) annotation.Timestamp {
+	return annotation.NewTimestamp(t.Times[index] + time.Millisecond - 1)
+}
+
+// SnapSteps 将标注中每个步骤的开始（及结束）时间对齐到最接近的帧，并记录开始帧序号
+// 结束晚于开始的步骤，结束时间至少对齐到开始帧的下一帧，避免很短的步骤对齐后区间为空；
+// 结束早于开始的步骤保持原样，由验证报告错误
+func SnapSteps(ann *annotation.Annotation, table *FrameTable) {
+	for i := range ann.Steps {
+		step := &ann.Steps[i]
+		start := step.Timestamp.Duration()
+		frame, ts := table.Snap(start)
+		step.Timestamp = ts
+		step.Frame = &frame
+		if step.End != nil {
+			endFrame, end := table.Snap(step.End.Duration())
+			if endFrame <= frame && step.End.Duration() > start && frame+1 < len(table.Times) {
+				end = table.timestamp(frame + 1)
+			}
+			step.End = &end
+		}
+	}
+}
+
+// cttsReader 按采样顺序读取 ctts（composition time offset）表
+type cttsReader struct {
+	entries []cttsEntry
+	pos     int
+	used    uint32
+}
+
+// cttsEntry 表示 ctts 中的一项：Count 个采样使用相同的偏移
+type cttsEntry struct {
+	Count  uint32
+	Offset int64
+}
+
+// next 返回下一个采样的组合偏移，表已用完时返回 0
+func (r *cttsReader) next() int64 {
+	for r.pos < len(r.entries) && r.used >= r.entries[r.pos].Count {
+		r.pos++
+		r.used = 0
+	}
+	if r.pos >= len(r.entries) {
+		return 0
+	}
+	r.used++
+	return r.entries[r.pos].Offset
+}
+
+// parseCtts 解析 stbl 中的 ctts 表，不存在时所有偏移为 0
+func parseCtts(stbl []byte) *cttsReader {
+	r := &cttsReader{}
+	ctts, ok := childBox(stbl, "ctts")
+	if !ok {
+		return r
+	}
+	version, _, body, err := fullBoxHeader(ctts)
+	if err != nil || len(body) < 4 {
+		return r
+	}
+	n := int(binary.BigEndian.Uint32(body[0:4]))
+	body = body[4:]
+	if n > len(body)/8 {
+		n = len(body) / 8
+	}
+
+	r.entries = make([]cttsEntry, n)
+	for i := 0; i < n; i++ {
+		r.entries[i].Count = binary.BigEndian.Uint32(body[i*8 : i*8+4])
+		raw := binary.BigEndian.Uint32(body[i*8+4 : i*8+8])
+		if version == 1 {
+			r.entries[i].Offset = int64(int32(raw))
+		} else {
+			r.entries[i].Offset = int64(raw)
+		}
+	}
+	return r
+}
+
+// parseEditList 解析 edts/elst：返回第一个非空编辑的媒体起始时间（轨道 timescale 单位）
+// 以及其前面空编辑造成的延迟（按 mvhd timescale 换算）
+func parseEditList(trak, moov []byte) (int64, time.Duration) {
+	elst, ok := boxPath(trak, "edts", "elst")
+	if !ok {
+		return 0, 0
+	}
+	version, _, body, err := fullBoxHeader(elst)
+	if err != nil || len(body) < 4 {
+		return 0, 0
+	}
+
+	var movieTimescale uint32
+	if mvhd, ok := childBox(moov, "mvhd"); ok {
+		movieTimescale, _, _ = parseTimescaleDuration(mvhd)
+	}
+
+	entrySize := 12
+	if version == 1 {
+		entrySize = 20
+	}
+	n := int(binary.BigEndian.Uint32(body[0:4]))
+	body = body[4:]
+
+	var delay time.Duration
+	for i := 0; i < n && len(body) >= (i+1)*entrySize; i++ {
+		entry := body[i*entrySize:]
+		var segmentDuration uint64
+		var mediaTime int64
+		if version == 1 {
+			segmentDuration = binary.BigEndian.Uint64(entry[0:8])
+			mediaTime = int64(binary.BigEndian.Uint64(entry[8:16]))
+		} else {
+			segmentDuration = uint64(binary.BigEndian.Uint32(entry[0:4]))
+			mediaTime = int64(int32(binary.BigEndian.Uint32(entry[4:8])))
+		}
+		if mediaTime == -1 { // 空编辑：延迟显示
+			delay += scaleDuration(segmentDuration, movieTimescale)
+			continue
+		}
+		return mediaTime, delay
+	}
+	return 0, delay
+}
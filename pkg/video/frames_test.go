@@ -0,0 +1,143 @@
+package video
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+func TestReadFrameTable(t *testing.T) {
+	// 4 帧，每帧 40ms；ctts 让第 2、3 帧交换显示顺序（B 帧）
+	tr := testTrack{
+		timescale: 1000,
+		stts:      [][2]uint32{{4, 40}},
+		stsz:      4,
+		ctts:      [][2]uint32{{1, 40}, {1, 80}, {1, 0}, {1, 40}},
+		stss:      []uint32{1},
+	}
+	path := writeTestFile(t, testMoov(160, tr))
+
+	table, err := ReadFrameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{40, 80, 120, 160}
+	if len(table.Times) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(table.Times), len(want))
+	}
+	for i, d := range want {
+		if table.Times[i] != d*time.Millisecond {
+			t.Errorf("frame %d at %v, want %v", i, table.Times[i], d*time.Millisecond)
+		}
+	}
+
+	keyframes, err := ReadKeyframes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyframes) != 1 || keyframes[0] != 40*time.Millisecond {
+		t.Errorf("keyframes = %v, want [40ms]", keyframes)
+	}
+}
+
+func TestReadFrameTableFragmented(t *testing.T) {
+	moov := testMoov(0, testTrack{timescale: 1000}, testMvex(40))
+	path := writeTestFile(t, moov,
+		testMoof(40, 0, 0, u32(2)),
+		testMoof(40, 1000, 0, u32(2)),
+	)
+	table, err := ReadFrameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{0, 40 * time.Millisecond, time.Second, time.Second + 40*time.Millisecond}
+	if len(table.Times) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(table.Times), len(want))
+	}
+	for i, d := range want {
+		if table.Times[i] != d {
+			t.Errorf("frame %d at %v, want %v", i, table.Times[i], d)
+		}
+	}
+}
+
+func TestReadFrameTableInvalidSampleTable(t *testing.T) {
+	tests := []struct {
+		name string
+		tr   testTrack
+		want string
+	}{
+		{"stts longer than stsz", testTrack{timescale: 1000, stts: [][2]uint32{{10, 40}}, stsz: 4}, "stts has 10 samples"},
+		{"stts shorter than stsz", testTrack{timescale: 1000, stts: [][2]uint32{{2, 40}}, stsz: 4}, "stts has 2 samples"},
+		{"huge stts count", testTrack{timescale: 1000, stts: [][2]uint32{{0xFFFFFFFF, 1}, {0xFFFFFFFF, 1}}, stsz: 4}, "stts has 8589934590 samples"},
+		{"stsz exceeds file size", testTrack{timescale: 1000, stts: [][2]uint32{{1 << 20, 1}}, stsz: 1 << 20}, "exceed the file size"},
+		{"stsz over limit", testTrack{timescale: 1000, stts: [][2]uint32{{1 << 30, 1}}, stsz: 1 << 30}, "too many samples"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrameTable(writeTestFile(t, testMoov(0, tt.tr)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadFrameTable error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameTableSnap(t *testing.T) {
+	table := &FrameTable{Timescale: 30000, Times: []time.Duration{
+		0,
+		33366666 * time.Nanosecond,
+		66733333 * time.Nanosecond,
+		100100000 * time.Nanosecond,
+	}}
+	tests := []struct {
+		at        time.Duration
+		wantFrame int
+		wantTS    string
+	}{
+		{0, 0, "00:00.000"},
+		{10 * time.Millisecond, 0, "00:00.000"},
+		{20 * time.Millisecond, 1, "00:00.034"},
+		{50 * time.Millisecond, 1, "00:00.034"},
+		{60 * time.Millisecond, 2, "00:00.067"},
+		{time.Second, 3, "00:00.101"},
+	}
+	for _, tt := range tests {
+		frame, ts := table.Snap(tt.at)
+		if frame != tt.wantFrame || ts.String() != tt.wantTS {
+			t.Errorf("Snap(%v) = %d, %s; want %d, %s", tt.at, frame, ts, tt.wantFrame, tt.wantTS)
+		}
+	}
+
+	end := annotation.NewTimestamp(90 * time.Millisecond)
+	ann := &annotation.Annotation{Steps: []annotation.Step{
+		{Number: 1, Timestamp: annotation.NewTimestamp(40 * time.Millisecond), End: &end},
+	}}
+	SnapSteps(ann, table)
+	step := ann.Steps[0]
+	if step.Frame == nil || *step.Frame != 1 || step.Timestamp.String() != "00:00.034" || step.End.String() != "00:00.101" {
+		t.Errorf("SnapSteps = %+v", step)
+	}
+
+	// 开始和结束对齐到同一帧时，结束推到下一帧；结束早于开始或已是最后一帧时只做对齐
+	spans := []struct {
+		start, end         time.Duration
+		wantStart, wantEnd string
+	}{
+		{40 * time.Millisecond, 45 * time.Millisecond, "00:00.034", "00:00.067"},
+		{40 * time.Millisecond, 10 * time.Millisecond, "00:00.034", "00:00.000"},
+		{time.Second, time.Second + time.Millisecond, "00:00.101", "00:00.101"},
+	}
+	for _, tt := range spans {
+		end := annotation.NewTimestamp(tt.end)
+		ann := &annotation.Annotation{Steps: []annotation.Step{
+			{Number: 1, Timestamp: annotation.NewTimestamp(tt.start), End: &end},
+		}}
+		SnapSteps(ann, table)
+		if step := ann.Steps[0]; step.Timestamp.String() != tt.wantStart || step.End.String() != tt.wantEnd {
+			t.Errorf("SnapSteps(%v-%v) = %s-%s, want %s-%s", tt.start, tt.end, step.Timestamp, step.End, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
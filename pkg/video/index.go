@@ -0,0 +1,164 @@
+package video
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanIndexVersion 索引文件格式版本，不一致时丢弃旧索引
+const scanIndexVersion = 1
+
+// ScanIndex 是持久化的视频扫描索引，按文件绝对路径记录大小、修改时间和元数据
+// 重新扫描时只读取大小或修改时间发生变化的视频，其余直接使用索引中的元数据
+type ScanIndex struct {
+	mu      sync.Mutex
+	path    string // 索引文件路径，为空时只保存在内存中
+	entries map[string]indexEntry
+	dirty   bool
+}
+
+// indexEntry 表示索引中的一个视频
+type indexEntry struct {
+	Size       int64    `json:"size"`
+	ModTime    int64    `json:"mod_time"` // Unix 纳秒
+	Metadata   Metadata `json:"metadata"`
+	ProbeError string   `json:"probe_error,omitempty"`
+}
+
+// indexFile 表示索引文件内容
+type indexFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]indexEntry `json:"entries"`
+}
+
+// ScanStats 表示一次扫描的统计信息
+type ScanStats struct {
+	Videos     int   `json:"videos"`      // 扫描到的视频数
+	Probed     int   `json:"probed"`      // 新增或变化、重新读取元数据的视频数
+	Removed    int   `json:"removed"`     // 已不存在、从索引中移除的视频数
+	DurationMs int64 `json:"duration_ms"` // 扫描耗时（毫秒）
+}
+
+// LoadScanIndex 加载索引文件，文件不存在或无法解析时返回空索引
+func LoadScanIndex(path string) *ScanIndex {
+	ix := &ScanIndex{path: path, entries: make(map[string]indexEntry)}
+	if path == "" {
+		return ix
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ix
+	}
+	var file indexFile
+	if json.Unmarshal(data, &file) == nil && file.Version == scanIndexVersion && file.Entries != nil {
+		ix.entries = file.Entries
+	}
+	return ix
+}
+
+// Scan 扫描视频目录（同 Scan），并用索引填充元数据
+// 只有新增或变化的视频会被重新读取；未使用任务文件和规则时，目录下已不存在的视频会从索引中移除
+// 索引有变化时写回磁盘，写入失败不影响扫描结果
+func (ix *ScanIndex) Scan(videoDir, taskFile string, opts ScanOptions) (*ScanResult, ScanStats, error) {
+	start := time.Now()
+	result, err := Scan(videoDir, taskFile, opts)
+	if err != nil {
+		return result, ScanStats{}, err
+	}
+
+	stats := ScanStats{Videos: len(result.Videos)}
+	stats.Probed = ix.Probe(result.Videos)
+	if taskFile == "" && len(opts.Filter) == 0 {
+		stats.Removed = ix.prune(videoDir, result.Videos)
+	}
+	_ = ix.Save()
+	stats.DurationMs = time.Since(start).Milliseconds()
+	return result, stats, nil
+}
+
+// Probe 为视频填充元数据（同 ProbeVideos），大小和修改时间与索引一致的视频不再读取文件
+// 返回实际读取的视频数
+func (ix *ScanIndex) Probe(videos []VideoInfo) int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	probed := 0
+	for i := range videos {
+		v := &videos[i]
+		key := indexKey(v.Path)
+		if entry, ok := ix.entries[key]; ok && entry.Size == v.Size && entry.ModTime == v.modTime {
+			v.Metadata = entry.Metadata
+			v.ProbeError = entry.ProbeError
+			continue
+		}
+
+		ProbeVideos(videos[i : i+1])
+		probed++
+		ix.entries[key] = indexEntry{
+			Size:       v.Size,
+			ModTime:    v.modTime,
+			Metadata:   v.Metadata,
+			ProbeError: v.ProbeError,
+		}
+		ix.dirty = true
+	}
+	return probed
+}
+
+// prune 移除 videoDir 下本次扫描中未出现的索引项，返回移除数量
+// 其他目录的索引项保留，切换视频目录后再切回时无需重新读取
+func (ix *ScanIndex) prune(videoDir string, videos []VideoInfo) int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	seen := make(map[string]bool, len(videos))
+	for _, v := range videos {
+		seen[indexKey(v.Path)] = true
+	}
+	prefix := indexKey(videoDir) + string(filepath.Separator)
+	removed := 0
+	for key := range ix.entries {
+		if strings.HasPrefix(key, prefix) && !seen[key] {
+			delete(ix.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		ix.dirty = true
+	}
+	return removed
+}
+
+// Len 返回索引中的视频数
+func (ix *ScanIndex) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.entries)
+}
+
+// Save 将索引写回磁盘，没有变化时不写入
+func (ix *ScanIndex) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.path == "" || !ix.dirty {
+		return nil
+	}
+	if err := writeCacheFile(ix.path, indexFile{Version: scanIndexVersion, Entries: ix.entries}); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// indexKey 返回索引使用的路径（绝对路径），无法转换时使用原路径
+func indexKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
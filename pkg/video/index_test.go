@@ -0,0 +1,126 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanIndex(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(t.TempDir(), "scan-index.json")
+	write := func(name string, durationMs uint32) {
+		t.Helper()
+		data, err := os.ReadFile(writeTestFile(t, testMoov(durationMs, testTrack{timescale: 1000, stts: [][2]uint32{{10, durationMs / 10}}, stsz: 10})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan := func(ix *ScanIndex, wantProbed, wantRemoved int) map[string]VideoInfo {
+		t.Helper()
+		result, stats, err := ix.Scan(dir, "", ScanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Probed != wantProbed || stats.Removed != wantRemoved || stats.Videos != len(result.Videos) {
+			t.Errorf("stats = %+v, want %d probed, %d removed", stats, wantProbed, wantRemoved)
+		}
+		videos := make(map[string]VideoInfo)
+		for _, v := range result.Videos {
+			videos[v.Key] = v
+		}
+		return videos
+	}
+
+	write("a.mp4", 400)
+	write("b.mp4", 800)
+	ix := LoadScanIndex(indexPath)
+	if videos := scan(ix, 2, 0); videos["a"].DurationMs != 400 || videos["b"].DurationMs != 800 {
+		t.Fatalf("first scan = %+v", videos)
+	}
+
+	// 大小和修改时间不变的文件不再读取：内容被破坏也仍使用索引中的元数据
+	a := filepath.Join(dir, "a.mp4")
+	info, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a, make([]byte, info.Size()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(a, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if videos := scan(ix, 0, 0); videos["a"].DurationMs != 400 || videos["a"].ProbeError != "" {
+		t.Errorf("unchanged a.mp4 = %+v, want cached metadata", videos["a"])
+	}
+
+	// 索引写回磁盘，重新加载后同样不再读取
+	ix = LoadScanIndex(indexPath)
+	if ix.Len() != 2 {
+		t.Fatalf("reloaded index has %d entries, want 2", ix.Len())
+	}
+	scan(ix, 0, 0)
+
+	// 修改时间变化的文件重新读取
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(a, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if videos := scan(ix, 1, 0); videos["a"].ProbeError == "" {
+		t.Errorf("changed a.mp4 = %+v, want a probe error", videos["a"])
+	}
+
+	// 删除的文件从索引中移除，并写回磁盘
+	if err := os.Remove(filepath.Join(dir, "b.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	if videos := scan(ix, 0, 1); len(videos) != 1 {
+		t.Errorf("videos after removing b.mp4 = %v", videos)
+	}
+	if n := LoadScanIndex(indexPath).Len(); n != 1 {
+		t.Errorf("saved index has %d entries after removing b.mp4, want 1", n)
+	}
+}
+
+func TestScanIndexKeepsEntriesOutsideScan(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "a.mp4", "b.mp4")
+	ix := LoadScanIndex("")
+	if _, stats, err := ix.Scan(dir, "", ScanOptions{}); err != nil || stats.Probed != 2 {
+		t.Fatalf("Scan = %+v, %v", stats, err)
+	}
+
+	// 使用规则时只扫描部分视频，其他视频的索引项保留
+	if _, stats, err := ix.Scan(dir, "", ScanOptions{Filter: []string{"a"}}); err != nil || stats.Removed != 0 || ix.Len() != 2 {
+		t.Errorf("filtered Scan = %+v, %v; %d entries", stats, err, ix.Len())
+	}
+	// 其他视频目录的索引项同样保留
+	other := t.TempDir()
+	if _, stats, err := ix.Scan(other, "", ScanOptions{}); err != nil || stats.Removed != 0 || ix.Len() != 2 {
+		t.Errorf("Scan of another directory = %+v, %v; %d entries", stats, err, ix.Len())
+	}
+}
+
+func TestLoadScanIndexInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"old.json":     `{"version":0,"entries":{"/v/a.mp4":{"size":1}}}`,
+		"corrupt.json": `{"version":`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if n := LoadScanIndex(path).Len(); n != 0 {
+			t.Errorf("%s: %d entries, want an empty index", name, n)
+		}
+	}
+	if n := LoadScanIndex(filepath.Join(dir, "missing.json")).Len(); n != 0 {
+		t.Errorf("missing index: %d entries", n)
+	}
+}
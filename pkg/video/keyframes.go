@@ -0,0 +1,163 @@
+package video
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReadKeyframes 读取视频轨道中所有关键帧（同步采样）的显示时间，按时间升序排列
+func ReadKeyframes(path string) ([]time.Duration, error) {
+	track, samples, err := readVideoSamples(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyframes []time.Duration
+	for _, s := range samples {
+		if s.Sync {
+			keyframes = append(keyframes, track.presentationTime(s.PTS))
+		}
+	}
+	sort.Slice(keyframes, func(i, j int) bool { return keyframes[i] < keyframes[j] })
+	return keyframes, nil
+}
+
+// SceneChanges 根据关键帧分布推测场景切换点
+// 编码器按固定间隔（GOP）插入关键帧，遇到场景切换时会提前插入；
+// 因此间隔明显短于常规 GOP 的关键帧视为场景切换。
+// 常规 GOP 取出现次数过半的间隔，没有时取最大间隔；关键帧完全等间隔时返回空
+func SceneChanges(keyframes []time.Duration) []time.Duration {
+	if len(keyframes) < 3 {
+		return nil
+	}
+
+	// 按毫秒统计间隔，避免换算误差
+	counts := make(map[time.Duration]int)
+	var longest time.Duration
+	for i := 1; i < len(keyframes); i++ {
+		interval := (keyframes[i] - keyframes[i-1]).Round(time.Millisecond)
+		counts[interval]++
+		if interval > longest {
+			longest = interval
+		}
+	}
+
+	gop := longest
+	for interval, n := range counts {
+		if n*2 > len(keyframes)-1 {
+			gop = interval
+		}
+	}
+
+	var changes []time.Duration
+	for i := 1; i < len(keyframes); i++ {
+		if interval := keyframes[i] - keyframes[i-1]; interval < gop*9/10 {
+			changes = append(changes, keyframes[i])
+		}
+	}
+	return changes
+}
+
+// KeyframeCache 将关键帧列表缓存到磁盘，文件大小或修改时间变化时自动失效
+type KeyframeCache struct {
+	dir string
+}
+
+// keyframeCacheEntry 表示一个视频的关键帧缓存文件内容
+type keyframeCacheEntry struct {
+	Path        string  `json:"path"`
+	Size        int64   `json:"size"`
+	ModTime     int64   `json:"mod_time"` // Unix 纳秒
+	KeyframesUs []int64 `json:"keyframes_us"`
+}
+
+// NewKeyframeCache 创建关键帧缓存，dir 为空时不缓存
+func NewKeyframeCache(dir string) *KeyframeCache {
+	return &KeyframeCache{dir: dir}
+}
+
+// Get 返回视频的关键帧时间，优先使用缓存
+func (c *KeyframeCache) Get(path string) ([]time.Duration, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := c.entryPath(absPath)
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var entry keyframeCacheEntry
+			if json.Unmarshal(data, &entry) == nil && entry.Path == absPath &&
+				entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+				keyframes := make([]time.Duration, len(entry.KeyframesUs))
+				for i, us := range entry.KeyframesUs {
+					keyframes[i] = time.Duration(us) * time.Microsecond
+				}
+				return keyframes, nil
+			}
+		}
+	}
+
+	keyframes, err := ReadKeyframes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		entry := keyframeCacheEntry{
+			Path:        absPath,
+			Size:        info.Size(),
+			ModTime:     info.ModTime().UnixNano(),
+			KeyframesUs: make([]int64, len(keyframes)),
+		}
+		for i, k := range keyframes {
+			entry.KeyframesUs[i] = k.Microseconds()
+		}
+		// 缓存写入失败不影响结果
+		_ = writeCacheFile(cachePath, entry)
+	}
+	return keyframes, nil
+}
+
+// entryPath 返回视频对应的缓存文件路径（以绝对路径的哈希命名）
+func (c *KeyframeCache) entryPath(absPath string) string {
+	if c == nil || c.dir == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(absPath))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// writeCacheFile 先写临时文件再重命名，避免并发读取到不完整的内容
+func writeCacheFile(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,115 @@
+package video
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSceneChanges(t *testing.T) {
+	s := func(v ...float64) []time.Duration {
+		d := make([]time.Duration, len(v))
+		for i, x := range v {
+			d[i] = time.Duration(x * float64(time.Second))
+		}
+		return d
+	}
+	tests := []struct {
+		name      string
+		keyframes []time.Duration
+		want      []time.Duration
+	}{
+		{"too few keyframes", s(0, 1), nil},
+		{"regular gop", s(0, 2, 4, 6, 8), nil},
+		// 常规 GOP 为 2 秒，提前插入的关键帧是场景切换
+		{"early keyframes", s(0, 2, 3, 5, 7, 7.5, 9.5), s(3, 7.5)},
+		// 没有过半的间隔时以最大间隔为 GOP
+		{"no dominant interval", s(0, 1, 3, 6), s(1, 3)},
+	}
+	for _, tt := range tests {
+		if got := SceneChanges(tt.keyframes); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SceneChanges = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKeyframeCache(t *testing.T) {
+	// 4 帧，每帧 40ms，第 1、3 帧为关键帧
+	track := testTrack{timescale: 1000, stts: [][2]uint32{{4, 40}}, stsz: 4, stss: []uint32{1, 3}}
+	path := writeTestFile(t, testMoov(160, track))
+	cache := NewKeyframeCache(t.TempDir())
+	want := []time.Duration{0, 80 * time.Millisecond}
+
+	get := func(want []time.Duration) {
+		t.Helper()
+		got, err := cache.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Get = %v, want %v", got, want)
+		}
+	}
+	get(want)
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryPath := cache.entryPath(absPath)
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		t.Fatalf("cache entry: %v", err)
+	}
+	var entry keyframeCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// 大小和修改时间未变时使用缓存，不重新读取视频
+	entry.KeyframesUs = []int64{123}
+	if err := writeCacheFile(entryPath, entry); err != nil {
+		t.Fatal(err)
+	}
+	get([]time.Duration{123 * time.Microsecond})
+
+	// 修改时间变化后缓存失效，重新读取并更新缓存
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	get(want)
+	if err := json.Unmarshal(readFile(t, entryPath), &entry); err != nil || entry.ModTime != later.UnixNano() || len(entry.KeyframesUs) != 2 {
+		t.Errorf("cache entry after invalidation = %+v, %v", entry, err)
+	}
+
+	// 内容变化（大小不同）后同样失效，即使修改时间被恢复
+	track.stss = []uint32{1, 2, 4}
+	if err := os.WriteFile(path, readFile(t, writeTestFile(t, testMoov(160, track))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	get([]time.Duration{0, 40 * time.Millisecond, 120 * time.Millisecond})
+
+	// 不缓存时直接读取视频
+	if got, err := NewKeyframeCache("").Get(path); err != nil || len(got) != 3 {
+		t.Errorf("uncached Get = %v, %v", got, err)
+	}
+	if _, err := cache.Get(filepath.Join(t.TempDir(), "missing.mp4")); err == nil {
+		t.Error("Get succeeded for a missing video")
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
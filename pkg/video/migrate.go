@@ -0,0 +1,81 @@
+package video
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+// MigrationAction 表示迁移一个旧标注文件的结果
+type MigrationAction struct {
+	From    string `json:"from"`
+	To      string `json:"to,omitempty"`
+	Skipped string `json:"skipped,omitempty"` // 跳过原因，为空表示已迁移（或 dryRun 时将迁移）
+}
+
+// MigrateFlatAnnotations 将平铺在 outputDir 下的旧标注文件（<stem>.txt）移动到
+// 镜像视频目录结构的位置（<子目录>/<stem>.txt），只处理位于子目录中的视频
+// 多个子目录中存在同名视频时无法确定归属，记录为跳过；目标文件已存在时同样跳过
+// filter 不为 nil 时只迁移通过规则的视频，videos 仍应包含全部视频以便判断同名冲突
+// dryRun 为 true 时只返回计划，不移动文件
+func MigrateFlatAnnotations(videos []VideoInfo, outputDir string, filter *Filter, dryRun bool) ([]MigrationAction, error) {
+	if outputDir == "" {
+		return nil, fmt.Errorf("output directory not set")
+	}
+
+	// 同名视频中有顶层视频时，平铺文件本就属于它，无需迁移
+	owned := make(map[string]bool)
+	for _, v := range videos {
+		if v.Key == v.Stem {
+			owned[v.Stem] = true
+		}
+	}
+
+	collisions := FindCollisions(videos)
+	var actions []MigrationAction
+	reported := make(map[string]bool)
+	for _, v := range videos {
+		if v.Key == v.Stem || owned[v.Stem] || !filter.Match(v.Key, v.RelPath) {
+			continue
+		}
+
+		for _, ext := range annotation.Extensions() {
+			from := GetAnnotationPathWithExt(v.Stem, outputDir, ext)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+
+			if v.StemCollision {
+				if !reported[from] {
+					reported[from] = true
+					keys := collisions[v.Stem]
+					actions = append(actions, MigrationAction{
+						From:    from,
+						Skipped: fmt.Sprintf("ambiguous, %d videos share this name: %s", len(keys), strings.Join(keys, ", ")),
+					})
+				}
+				continue
+			}
+
+			to := GetAnnotationPathWithExt(v.Key, outputDir, ext)
+			if _, err := os.Stat(to); err == nil {
+				actions = append(actions, MigrationAction{From: from, To: to, Skipped: "target already exists"})
+				continue
+			}
+
+			if !dryRun {
+				if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+					return actions, fmt.Errorf("failed to create directory: %w", err)
+				}
+				if err := os.Rename(from, to); err != nil {
+					return actions, fmt.Errorf("failed to move %s: %w", from, err)
+				}
+			}
+			actions = append(actions, MigrationAction{From: from, To: to})
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,255 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// moov 读入内存的大小上限，超出视为文件损坏
+const maxMoovSize = 256 << 20
+
+// boxHeader 表示 ISO-BMFF（MP4）box 的头部
+type boxHeader struct {
+	Type       string // 四字符类型，如 "moov"
+	Size       int64  // box 总大小（含头部）
+	HeaderSize int64  // 头部大小（8 或 16）
+}
+
+// readBoxHeader 从当前位置读取 box 头部，remaining 为父容器剩余字节数
+func readBoxHeader(r io.Reader, remaining int64) (boxHeader, error) {
+	var buf [16]byte
+	if _, err := io.ReadFull(r, buf[:8]); err != nil {
+		return boxHeader{}, err
+	}
+
+	h := boxHeader{
+		Type:       string(buf[4:8]),
+		Size:       int64(binary.BigEndian.Uint32(buf[0:4])),
+		HeaderSize: 8,
+	}
+
+	switch h.Size {
+	case 1: // 64 位 largesize
+		if _, err := io.ReadFull(r, buf[8:16]); err != nil {
+			return boxHeader{}, err
+		}
+		h.Size = int64(binary.BigEndian.Uint64(buf[8:16]))
+		h.HeaderSize = 16
+	case 0: // 延伸到文件（或父容器）末尾
+		h.Size = remaining
+	}
+
+	if h.Size < h.HeaderSize || h.Size > remaining {
+		return boxHeader{}, fmt.Errorf("invalid size %d for box %q", h.Size, h.Type)
+	}
+	return h, nil
+}
+
+// forEachBox 依次遍历 [start, start+size) 范围内的子 box
+func forEachBox(rs io.ReadSeeker, start, size int64, fn func(h boxHeader, contentStart int64) error) error {
+	offset := start
+	end := start + size
+	for offset < end {
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		h, err := readBoxHeader(rs, end-offset)
+		if err != nil {
+			return err
+		}
+		if err := fn(h, offset+h.HeaderSize); err != nil {
+			return err
+		}
+		offset += h.Size
+	}
+	return nil
+}
+
+// readPayload 读取 box 内容（不含头部）
+func readPayload(rs io.ReadSeeker, h boxHeader, contentStart int64, limit int64) ([]byte, error) {
+	size := h.Size - h.HeaderSize
+	if size > limit {
+		return nil, fmt.Errorf("box %q too large (%d bytes)", h.Type, size)
+	}
+	if _, err := rs.Seek(contentStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(rs, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// mp4Box 表示已读入内存的 box
+type mp4Box struct {
+	Type string
+	Data []byte // 内容（不含头部）
+}
+
+// parseBoxes 解析内存中连续排列的 box
+func parseBoxes(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return boxes, fmt.Errorf("truncated box header")
+		}
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 1:
+			if len(data) < 16 {
+				return boxes, fmt.Errorf("truncated box header")
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		case 0:
+			size = uint64(len(data))
+		}
+		if size < header || size > uint64(len(data)) {
+			return boxes, fmt.Errorf("invalid size %d for box %q", size, typ)
+		}
+		boxes = append(boxes, mp4Box{Type: typ, Data: data[header:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// childBox 返回第一个指定类型的子 box 内容
+func childBox(data []byte, typ string) ([]byte, bool) {
+	boxes, _ := parseBoxes(data)
+	for _, b := range boxes {
+		if b.Type == typ {
+			return b.Data, true
+		}
+	}
+	return nil, false
+}
+
+// childBoxes 返回所有指定类型的子 box 内容
+func childBoxes(data []byte, typ string) [][]byte {
+	boxes, _ := parseBoxes(data)
+	var result [][]byte
+	for _, b := range boxes {
+		if b.Type == typ {
+			result = append(result, b.Data)
+		}
+	}
+	return result
+}
+
+// boxPath 按路径逐层查找子 box，如 boxPath(trak, "mdia", "minf", "stbl")
+func boxPath(data []byte, path ...string) ([]byte, bool) {
+	for _, typ := range path {
+		var ok bool
+		if data, ok = childBox(data, typ); !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// fullBoxHeader 解析 FullBox 的 version 和 flags
+func fullBoxHeader(data []byte) (uint8, uint32, []byte, error) {
+	if len(data) < 4 {
+		return 0, 0, nil, fmt.Errorf("truncated full box")
+	}
+	return data[0], binary.BigEndian.Uint32(data[0:4]) & 0xFFFFFF, data[4:], nil
+}
+
+// parseTimescaleDuration 解析 mvhd/mdhd 共同的 timescale 和 duration 字段
+func parseTimescaleDuration(data []byte) (uint32, uint64, error) {
+	version, _, body, err := fullBoxHeader(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if version == 1 {
+		// creation_time(8) modification_time(8) timescale(4) duration(8)
+		if len(body) < 28 {
+			return 0, 0, fmt.Errorf("truncated box")
+		}
+		return binary.BigEndian.Uint32(body[16:20]), binary.BigEndian.Uint64(body[20:28]), nil
+	}
+
+	// creation_time(4) modification_time(4) timescale(4) duration(4)
+	if len(body) < 16 {
+		return 0, 0, fmt.Errorf("truncated box")
+	}
+	return binary.BigEndian.Uint32(body[8:12]), uint64(binary.BigEndian.Uint32(body[12:16])), nil
+}
+
+// scaleDuration 将以 timescale 为单位的时长转换为 time.Duration
+// 时长字段全为 1 表示未知，返回 0
+func scaleDuration(value uint64, timescale uint32) time.Duration {
+	if timescale == 0 || value == 0xFFFFFFFF || value == 0xFFFFFFFFFFFFFFFF {
+		return 0
+	}
+	seconds := value / uint64(timescale)
+	rest := value % uint64(timescale)
+	return time.Duration(seconds)*time.Second + time.Duration(rest)*time.Second/time.Duration(timescale)
+}
+
+// mp4File 表示读取了顶层结构的 MP4 文件
+type mp4File struct {
+	Size  int64
+	Moov  []byte   // moov 内容
+	Moofs [][]byte // 分片 MP4 的 moof 内容（按文件顺序）
+}
+
+// openMP4 打开文件并读取顶层 box：moov 读入内存，moov 位于文件末尾也能处理
+func openMP4(path string, withFragments bool) (*mp4File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mp4File{Size: info.Size()}
+	err = forEachBox(file, 0, info.Size(), func(h boxHeader, start int64) error {
+		switch {
+		case h.Type == "moov":
+			data, err := readPayload(file, h, start, maxMoovSize)
+			if err != nil {
+				return fmt.Errorf("failed to read moov: %w", err)
+			}
+			m.Moov = data
+		case h.Type == "moof" && withFragments:
+			data, err := readPayload(file, h, start, maxMoovSize)
+			if err != nil {
+				return fmt.Errorf("failed to read moof: %w", err)
+			}
+			m.Moofs = append(m.Moofs, data)
+		}
+		return nil
+	})
+	if err != nil && m.Moov == nil {
+		return nil, err
+	}
+	if m.Moov == nil {
+		return nil, fmt.Errorf("moov box not found in %s", filepath.Base(path))
+	}
+	return m, nil
+}
+
+// ReadDuration 读取 MP4 文件的时长（纯 Go 实现，不依赖 ffmpeg）
+func ReadDuration(path string) (time.Duration, error) {
+	meta, err := Probe(path)
+	if err != nil {
+		return 0, err
+	}
+	if meta.Duration() == 0 {
+		return 0, fmt.Errorf("duration not found in %s", filepath.Base(path))
+	}
+	return meta.Duration(), nil
+}
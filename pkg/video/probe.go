@@ -0,0 +1,429 @@
+package video
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Metadata 表示从 MP4 文件头中读取的视频元数据
+type Metadata struct {
+	DurationMs int64   `json:"duration_ms"`          // 时长（毫秒）
+	Timescale  uint32  `json:"timescale,omitempty"`  // 视频轨道时间刻度（每秒单位数）
+	Width      int     `json:"width,omitempty"`      // 宽度（像素）
+	Height     int     `json:"height,omitempty"`     // 高度（像素）
+	FrameRate  float64 `json:"frame_rate,omitempty"` // 平均帧率
+	Codec      string  `json:"codec,omitempty"`      // 视频编码 fourcc，如 avc1、hvc1
+	HasAudio   bool    `json:"has_audio"`            // 是否包含音频轨道
+	Size       int64   `json:"size"`                 // 文件大小（字节）
+	Fragmented bool    `json:"fragmented,omitempty"` // 是否为分片 MP4
+}
+
+// Duration 返回视频时长
+func (m *Metadata) Duration() time.Duration {
+	return time.Duration(m.DurationMs) * time.Millisecond
+}
+
+// mp4Track 表示 moov 中的一个轨道
+type mp4Track struct {
+	ID        uint32
+	Handler   string // vide、soun 等
+	Timescale uint32
+	Duration  uint64 // 以 Timescale 为单位
+	Codec     string
+	Width     int
+	Height    int
+	Stbl      []byte // 采样表 stbl 内容
+
+	EditMediaTime int64         // 编辑列表中的媒体起始时间（以 Timescale 为单位）
+	EditDelay     time.Duration // 编辑列表中空编辑造成的显示延迟
+}
+
+// trackDefaults 表示 mvex/trex 中的分片默认值
+type trackDefaults struct {
+	SampleDuration uint32
+	SampleFlags    uint32
+}
+
+// Probe 读取 MP4 文件的元数据（纯 Go 实现，不依赖 ffmpeg）
+// 支持 moov 位于文件末尾以及分片 MP4（fMP4）
+func Probe(path string) (*Metadata, error) {
+	m, err := openMP4(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{Size: m.Size}
+
+	var movieDuration time.Duration
+	if mvhd, ok := childBox(m.Moov, "mvhd"); ok {
+		if timescale, duration, err := parseTimescaleDuration(mvhd); err == nil {
+			movieDuration = scaleDuration(duration, timescale)
+		}
+	}
+
+	mvex, fragmented := childBox(m.Moov, "mvex")
+	meta.Fragmented = fragmented || len(m.Moofs) > 0
+
+	tracks := parseTracks(m.Moov)
+	var videoTrack *mp4Track
+	var longestTrack time.Duration
+	for i := range tracks {
+		t := &tracks[i]
+		switch t.Handler {
+		case "vide":
+			if videoTrack == nil {
+				videoTrack = t
+			}
+		case "soun":
+			meta.HasAudio = true
+		}
+		if d := scaleDuration(t.Duration, t.Timescale); d > longestTrack {
+			longestTrack = d
+		}
+	}
+
+	var fragmentDuration time.Duration
+	if videoTrack != nil {
+		meta.Timescale = videoTrack.Timescale
+		meta.Width = videoTrack.Width
+		meta.Height = videoTrack.Height
+		meta.Codec = videoTrack.Codec
+
+		count, total := stblSampleTotals(videoTrack.Stbl)
+		if meta.Fragmented {
+			defaults := parseTrex(mvex)[videoTrack.ID]
+			fragCount, fragTotal := fragmentSampleTotals(m.Moofs, videoTrack.ID, defaults)
+			count += fragCount
+			total += fragTotal
+			fragmentDuration = scaleDuration(total, videoTrack.Timescale)
+		}
+		if total > 0 {
+			meta.FrameRate = float64(count) * float64(videoTrack.Timescale) / float64(total)
+		}
+	}
+
+	// 时长优先级：mvhd > mvex/mehd > 最长轨道 > 分片累计
+	duration := movieDuration
+	if duration == 0 && fragmented {
+		if mehd, ok := childBox(mvex, "mehd"); ok {
+			duration = parseMehd(mehd, m.Moov)
+		}
+	}
+	if duration == 0 {
+		duration = longestTrack
+	}
+	if duration == 0 {
+		duration = fragmentDuration
+	}
+	meta.DurationMs = duration.Milliseconds()
+
+	return meta, nil
+}
+
+// parseTracks 解析 moov 中所有 trak
+func parseTracks(moov []byte) []mp4Track {
+	var tracks []mp4Track
+	for _, trak := range childBoxes(moov, "trak") {
+		var t mp4Track
+		t.EditMediaTime, t.EditDelay = parseEditList(trak, moov)
+
+		if tkhd, ok := childBox(trak, "tkhd"); ok {
+			if version, _, body, err := fullBoxHeader(tkhd); err == nil {
+				idOffset := 8
+				if version == 1 {
+					idOffset = 16
+				}
+				if len(body) >= idOffset+4 {
+					t.ID = binary.BigEndian.Uint32(body[idOffset : idOffset+4])
+				}
+				// 宽高为 16.16 定点数，位于 tkhd 末尾
+				if len(body) >= 8 {
+					t.Width = int(binary.BigEndian.Uint32(body[len(body)-8:]) >> 16)
+					t.Height = int(binary.BigEndian.Uint32(body[len(body)-4:]) >> 16)
+				}
+			}
+		}
+
+		mdia, ok := childBox(trak, "mdia")
+		if !ok {
+			continue
+		}
+		if mdhd, ok := childBox(mdia, "mdhd"); ok {
+			t.Timescale, t.Duration, _ = parseTimescaleDuration(mdhd)
+		}
+		if hdlr, ok := childBox(mdia, "hdlr"); ok {
+			if _, _, body, err := fullBoxHeader(hdlr); err == nil && len(body) >= 8 {
+				t.Handler = string(body[4:8])
+			}
+		}
+		if stbl, ok := boxPath(mdia, "minf", "stbl"); ok {
+			t.Stbl = stbl
+			parseSampleEntry(&t, stbl)
+		}
+
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// parseSampleEntry 从 stsd 的第一个采样描述中读取编码和（视频轨道的）宽高
+func parseSampleEntry(t *mp4Track, stbl []byte) {
+	stsd, ok := childBox(stbl, "stsd")
+	if !ok {
+		return
+	}
+	_, _, body, err := fullBoxHeader(stsd)
+	if err != nil || len(body) < 4 {
+		return
+	}
+	entries, _ := parseBoxes(body[4:])
+	if len(entries) == 0 {
+		return
+	}
+
+	entry := entries[0]
+	t.Codec = entry.Type
+	// VisualSampleEntry：reserved(6) data_reference_index(2) pre_defined/reserved(16) width(2) height(2)
+	if t.Handler == "vide" && len(entry.Data) >= 28 {
+		if w := int(binary.BigEndian.Uint16(entry.Data[24:26])); w > 0 {
+			t.Width = w
+		}
+		if h := int(binary.BigEndian.Uint16(entry.Data[26:28])); h > 0 {
+			t.Height = h
+		}
+	}
+}
+
+// stblSampleTotals 根据 stts 计算采样数和总时长（以轨道 timescale 为单位）
+func stblSampleTotals(stbl []byte) (uint64, uint64) {
+	var count, total uint64
+	for _, e := range parseStts(stbl) {
+		count += uint64(e.Count)
+		total += uint64(e.Count) * uint64(e.Delta)
+	}
+	return count, total
+}
+
+// sttsEntry 表示 stts 中的一项：Count 个采样，每个持续 Delta
+type sttsEntry struct {
+	Count uint32
+	Delta uint32
+}
+
+// parseStts 解析 stbl 中的 stts（time-to-sample）表
+func parseStts(stbl []byte) []sttsEntry {
+	stts, ok := childBox(stbl, "stts")
+	if !ok {
+		return nil
+	}
+	_, _, body, err := fullBoxHeader(stts)
+	if err != nil || len(body) < 4 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint32(body[0:4]))
+	body = body[4:]
+	if n > len(body)/8 {
+		n = len(body) / 8
+	}
+
+	entries := make([]sttsEntry, n)
+	for i := 0; i < n; i++ {
+		entries[i].Count = binary.BigEndian.Uint32(body[i*8 : i*8+4])
+		entries[i].Delta = binary.BigEndian.Uint32(body[i*8+4 : i*8+8])
+	}
+	return entries
+}
+
+// parseTrex 解析 mvex 中每个轨道的分片默认值
+func parseTrex(mvex []byte) map[uint32]trackDefaults {
+	defaults := make(map[uint32]trackDefaults)
+	for _, trex := range childBoxes(mvex, "trex") {
+		_, _, body, err := fullBoxHeader(trex)
+		if err != nil || len(body) < 20 {
+			continue
+		}
+		defaults[binary.BigEndian.Uint32(body[0:4])] = trackDefaults{
+			SampleDuration: binary.BigEndian.Uint32(body[8:12]),
+			SampleFlags:    binary.BigEndian.Uint32(body[16:20]),
+		}
+	}
+	return defaults
+}
+
+// parseMehd 解析 mehd 中的分片总时长（以 mvhd timescale 为单位）
+func parseMehd(mehd, moov []byte) time.Duration {
+	mvhd, ok := childBox(moov, "mvhd")
+	if !ok {
+		return 0
+	}
+	timescale, _, err := parseTimescaleDuration(mvhd)
+	if err != nil {
+		return 0
+	}
+
+	version, _, body, err := fullBoxHeader(mehd)
+	if err != nil {
+		return 0
+	}
+	if version == 1 && len(body) >= 8 {
+		return scaleDuration(binary.BigEndian.Uint64(body[0:8]), timescale)
+	}
+	if len(body) >= 4 {
+		return scaleDuration(uint64(binary.BigEndian.Uint32(body[0:4])), timescale)
+	}
+	return 0
+}
+
+// fragmentSampleTotals 累计分片中指定轨道的采样数和总时长
+func fragmentSampleTotals(moofs [][]byte, trackID uint32, defaults trackDefaults) (uint64, uint64) {
+	var count, total uint64
+	for _, run := range fragmentRuns(moofs, trackID, defaults) {
+		count += uint64(len(run.Samples))
+		for _, s := range run.Samples {
+			total += uint64(s.Duration)
+		}
+	}
+	return count, total
+}
+
+// 一个轨道的采样数上限（60fps 约 19 小时），超出视为文件损坏，避免按损坏或恶意的采样数分配过多内存
+const maxTrackSamples = 1 << 22
+
+// fragmentSample 表示分片中的一个采样
+type fragmentSample struct {
+	Duration uint32
+	Flags    uint32
+	CTO      int32 // 组合时间偏移（composition time offset）
+}
+
+// fragmentRun 表示 traf 中的一组连续采样
+type fragmentRun struct {
+	BaseDecodeTime uint64 // tfdt 给出的起始解码时间，无 tfdt 时为 ^uint64(0)
+	Samples        []fragmentSample
+}
+
+// fragmentRuns 解析分片中指定轨道的所有 trun；采样总数超过 maxTrackSamples 时忽略之后的 trun
+func fragmentRuns(moofs [][]byte, trackID uint32, defaults trackDefaults) []fragmentRun {
+	var runs []fragmentRun
+	budget := maxTrackSamples
+	for _, moof := range moofs {
+		for _, traf := range childBoxes(moof, "traf") {
+			tfhd, ok := childBox(traf, "tfhd")
+			if !ok {
+				continue
+			}
+			_, flags, body, err := fullBoxHeader(tfhd)
+			if err != nil || len(body) < 4 || binary.BigEndian.Uint32(body[0:4]) != trackID {
+				continue
+			}
+
+			// tfhd 可选字段按顺序排列
+			trackDefault := defaults
+			pos := 4
+			if flags&0x01 != 0 { // base_data_offset
+				pos += 8
+			}
+			if flags&0x02 != 0 { // sample_description_index
+				pos += 4
+			}
+			if flags&0x08 != 0 && len(body) >= pos+4 {
+				trackDefault.SampleDuration = binary.BigEndian.Uint32(body[pos : pos+4])
+				pos += 4
+			}
+			if flags&0x10 != 0 { // default_sample_size
+				pos += 4
+			}
+			if flags&0x20 != 0 && len(body) >= pos+4 {
+				trackDefault.SampleFlags = binary.BigEndian.Uint32(body[pos : pos+4])
+			}
+
+			baseDecodeTime := ^uint64(0)
+			if tfdt, ok := childBox(traf, "tfdt"); ok {
+				if version, _, b, err := fullBoxHeader(tfdt); err == nil {
+					if version == 1 && len(b) >= 8 {
+						baseDecodeTime = binary.BigEndian.Uint64(b[0:8])
+					} else if len(b) >= 4 {
+						baseDecodeTime = uint64(binary.BigEndian.Uint32(b[0:4]))
+					}
+				}
+			}
+
+			for i, trun := range childBoxes(traf, "trun") {
+				run, ok := parseTrun(trun, trackDefault, budget)
+				if !ok {
+					return runs
+				}
+				budget -= len(run.Samples)
+				if i == 0 {
+					run.BaseDecodeTime = baseDecodeTime
+				} else {
+					run.BaseDecodeTime = ^uint64(0)
+				}
+				runs = append(runs, run)
+			}
+		}
+	}
+	return runs
+}
+
+// parseTrun 解析 trun 中的采样信息，缺失字段使用默认值
+// 采样数超过 limit 时视为损坏，返回 false；每个采样的字段超出 box 时只解析完整的采样
+func parseTrun(trun []byte, defaults trackDefaults, limit int) (fragmentRun, bool) {
+	version, flags, body, err := fullBoxHeader(trun)
+	if err != nil || len(body) < 4 {
+		return fragmentRun{}, true
+	}
+	n := int(binary.BigEndian.Uint32(body[0:4]))
+	pos := 4
+	if flags&0x01 != 0 { // data_offset
+		pos += 4
+	}
+	firstFlags, hasFirstFlags := uint32(0), false
+	if flags&0x04 != 0 && len(body) >= pos+4 {
+		firstFlags, hasFirstFlags = binary.BigEndian.Uint32(body[pos:pos+4]), true
+		pos += 4
+	}
+
+	fieldSize := 0
+	for _, f := range []uint32{0x100, 0x200, 0x400, 0x800} {
+		if flags&f != 0 {
+			fieldSize += 4
+		}
+	}
+	if fieldSize > 0 && n > (len(body)-pos)/fieldSize {
+		n = max((len(body)-pos)/fieldSize, 0)
+	}
+	if n > limit {
+		return fragmentRun{}, false
+	}
+
+	run := fragmentRun{Samples: make([]fragmentSample, n)}
+	for i := 0; i < n; i++ {
+		s := fragmentSample{Duration: defaults.SampleDuration, Flags: defaults.SampleFlags}
+		if flags&0x100 != 0 {
+			s.Duration = binary.BigEndian.Uint32(body[pos : pos+4])
+			pos += 4
+		}
+		if flags&0x200 != 0 { // sample_size
+			pos += 4
+		}
+		if flags&0x400 != 0 {
+			s.Flags = binary.BigEndian.Uint32(body[pos : pos+4])
+			pos += 4
+		}
+		if flags&0x800 != 0 {
+			raw := binary.BigEndian.Uint32(body[pos : pos+4])
+			if version == 0 {
+				s.CTO = int32(raw & 0x7FFFFFFF)
+			} else {
+				s.CTO = int32(raw)
+			}
+			pos += 4
+		}
+		if i == 0 && hasFirstFlags {
+			s.Flags = firstFlags
+		}
+		run.Samples[i] = s
+	}
+	return run, true
+}
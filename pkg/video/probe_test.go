@@ -0,0 +1,206 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testBox 和 testFullBox 在测试中拼装 MP4 box
+func testBox(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b[0:4], uint32(8+len(body)))
+	copy(b[4:8], typ)
+	return append(b, body...)
+}
+
+func testFullBox(typ string, version uint8, flags uint32, parts ...[]byte) []byte {
+	header := u32(uint32(version)<<24 | flags&0xFFFFFF)
+	return testBox(typ, append([][]byte{header}, parts...)...)
+}
+
+func u32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[i*4:], v)
+	}
+	return b
+}
+
+// testTrack 描述测试用视频轨道
+type testTrack struct {
+	timescale uint32
+	stts      [][2]uint32 // {count, delta}
+	stsz      uint32      // stsz 中的采样数
+	stss      []uint32    // 同步采样编号，nil 表示没有 stss
+	ctts      [][2]uint32 // {count, offset}
+}
+
+// testMoov 生成只含一个视频轨道（ID 为 1）的 moov，extra 追加到 moov 末尾（如 mvex）
+func testMoov(movieDuration uint32, tr testTrack, extra ...[]byte) []byte {
+	tkhd := make([]byte, 80)
+	binary.BigEndian.PutUint32(tkhd[8:12], 1)        // track_ID
+	binary.BigEndian.PutUint32(tkhd[72:76], 640<<16) // width
+	binary.BigEndian.PutUint32(tkhd[76:80], 360<<16) // height
+
+	visual := make([]byte, 78)
+	binary.BigEndian.PutUint16(visual[24:26], 640)
+	binary.BigEndian.PutUint16(visual[26:28], 360)
+
+	stts := u32(uint32(len(tr.stts)))
+	for _, e := range tr.stts {
+		stts = append(stts, u32(e[0], e[1])...)
+	}
+	stbl := [][]byte{
+		testFullBox("stsd", 0, 0, u32(1), testBox("avc1", visual)),
+		testFullBox("stts", 0, 0, stts),
+		testFullBox("stsz", 0, 0, u32(1, tr.stsz)), // 固定采样大小 1 字节
+	}
+	if tr.stss != nil {
+		stbl = append(stbl, testFullBox("stss", 0, 0, u32(uint32(len(tr.stss))), u32(tr.stss...)))
+	}
+	if tr.ctts != nil {
+		ctts := u32(uint32(len(tr.ctts)))
+		for _, e := range tr.ctts {
+			ctts = append(ctts, u32(e[0], e[1])...)
+		}
+		stbl = append(stbl, testFullBox("ctts", 0, 0, ctts))
+	}
+
+	trak := testBox("trak",
+		testFullBox("tkhd", 0, 3, tkhd),
+		testBox("mdia",
+			testFullBox("mdhd", 0, 0, u32(0, 0, tr.timescale, 0), make([]byte, 4)),
+			testFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 13)),
+			testBox("minf", testBox("stbl", stbl...)),
+		),
+	)
+	mvhd := testFullBox("mvhd", 0, 0, u32(0, 0, 1000, movieDuration), make([]byte, 80))
+	return testBox("moov", append([][]byte{mvhd, trak}, extra...)...)
+}
+
+// testMoof 生成视频轨道的 moof：tfhd 带默认采样时长，trun 为给定的 flags 和内容
+func testMoof(defaultDuration, baseDecodeTime uint32, trunFlags uint32, trun ...[]byte) []byte {
+	return testBox("moof",
+		testFullBox("mfhd", 0, 0, u32(1)),
+		testBox("traf",
+			testFullBox("tfhd", 0, 0x08, u32(1, defaultDuration)),
+			testFullBox("tfdt", 0, 0, u32(baseDecodeTime)),
+			testFullBox("trun", 0, trunFlags, trun...),
+		),
+	)
+}
+
+// testMvex 生成视频轨道的 mvex/trex
+func testMvex(defaultDuration uint32) []byte {
+	return testBox("mvex", testFullBox("trex", 0, 0, u32(1, 1, defaultDuration, 0, 0)))
+}
+
+func writeTestFile(t *testing.T, boxes ...[]byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mp4")
+	data := append(testBox("ftyp", []byte("isom"), u32(0)), bytes.Join(boxes, nil)...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbe(t *testing.T) {
+	moov := testMoov(400, testTrack{timescale: 1000, stts: [][2]uint32{{10, 40}}, stsz: 10})
+
+	tests := []struct {
+		name  string
+		boxes [][]byte
+	}{
+		{"moov first", [][]byte{moov, testBox("mdat", make([]byte, 64))}},
+		{"moov last", [][]byte{testBox("mdat", make([]byte, 64)), moov}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := Probe(writeTestFile(t, tt.boxes...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.DurationMs != 400 || meta.Width != 640 || meta.Height != 360 ||
+				meta.Codec != "avc1" || meta.FrameRate != 25 || meta.Timescale != 1000 || meta.Fragmented {
+				t.Errorf("Probe = %+v", meta)
+			}
+		})
+	}
+}
+
+func TestProbeFragmented(t *testing.T) {
+	moov := testMoov(0, testTrack{timescale: 1000}, testMvex(40))
+	moofs := [][]byte{
+		testMoof(40, 0, 0, u32(5)),                                // 5 个采样，使用默认时长
+		testMoof(40, 200, 0x100, u32(5), u32(40, 40, 40, 40, 40)), // 5 个采样，逐个给出时长
+	}
+	meta, err := Probe(writeTestFile(t, append([][]byte{moov}, moofs...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Fragmented || meta.DurationMs != 400 || meta.FrameRate != 25 {
+		t.Errorf("Probe = %+v", meta)
+	}
+}
+
+func TestParseTrun(t *testing.T) {
+	defaults := trackDefaults{SampleDuration: 40}
+	tests := []struct {
+		name    string
+		flags   uint32
+		body    []byte
+		limit   int
+		want    int // 解析出的采样数
+		wantOK  bool
+		wantDur uint32 // 第一个采样的时长
+	}{
+		{"defaults only", 0, u32(3), 10, 3, true, 40},
+		{"per-sample durations", 0x100, u32(2, 33, 34), 10, 2, true, 33},
+		{"truncated per-sample fields", 0x100 | 0x200, u32(100, 33, 1, 34), 10, 1, true, 33},
+		{"data offset past end", 0x01 | 0x100, u32(100), 10, 0, true, 0},
+		{"oversized count without fields", 0, u32(0xFFFFFFFF), maxTrackSamples, 0, false, 0},
+		{"count over limit", 0, u32(11), 10, 0, false, 0},
+		{"empty box", 0, nil, 10, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trun := append(u32(tt.flags), tt.body...)
+			run, ok := parseTrun(trun, defaults, tt.limit)
+			if ok != tt.wantOK || len(run.Samples) != tt.want {
+				t.Fatalf("parseTrun = %d samples, %v; want %d, %v", len(run.Samples), ok, tt.want, tt.wantOK)
+			}
+			if tt.want > 0 && run.Samples[0].Duration != tt.wantDur {
+				t.Errorf("first sample duration = %d, want %d", run.Samples[0].Duration, tt.wantDur)
+			}
+		})
+	}
+}
+
+func TestFragmentRunsLimit(t *testing.T) {
+	// 每个 trun 都声称有 2^31 个采样但没有逐个采样的字段：应被视为损坏而不分配内存
+	moof := testMoof(40, 0, 0, u32(1<<31))
+	moofs, _ := parseBoxes(bytes.Repeat(moof, 4))
+	var data [][]byte
+	for _, b := range moofs {
+		data = append(data, b.Data)
+	}
+	if runs := fragmentRuns(data, 1, trackDefaults{}); len(runs) != 0 {
+		t.Errorf("fragmentRuns returned %d runs, want 0", len(runs))
+	}
+
+	// 多个 trun 合计超过上限时，之后的 trun 被忽略
+	moof = testMoof(40, 0, 0, u32(maxTrackSamples/2+1))
+	moofs, _ = parseBoxes(bytes.Repeat(moof, 3))
+	data = data[:0]
+	for _, b := range moofs {
+		data = append(data, b.Data)
+	}
+	if runs := fragmentRuns(data, 1, trackDefaults{}); len(runs) != 1 {
+		t.Errorf("fragmentRuns returned %d runs, want 1", len(runs))
+	}
+}
@@ -1,134 +1,439 @@
 package video
 
 import (
-	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/xd/mp4label/pkg/annotation"
 )
 
 // VideoInfo 表示视频文件信息
 type VideoInfo struct {
-	Filename      string `json:"filename"`       // 文件名（含扩展名）
-	Stem         string `json:"stem"`          // 文件名（不含扩展名）
-	Path         string `json:"path"`          // 完整路径
-	HasPreAnnotation bool `json:"has_pre_annotation"` // 是否有预标注
-	HasAnnotation    bool `json:"has_annotation"`     // 是否已有标注
+	Filename         string `json:"filename"`                 // 文件名（含扩展名）
+	Stem             string `json:"stem"`                     // 文件名（不含扩展名）
+	Path             string `json:"path"`                     // 完整路径
+	RelPath          string `json:"rel_path"`                 // 相对视频目录的路径（以 / 分隔）
+	Key              string `json:"key"`                      // 标注 key：RelPath 去掉扩展名，顶层视频与 Stem 相同（例外见 uniqueKeys）
+	StemCollision    bool   `json:"stem_collision,omitempty"` // 其他子目录中存在同名视频（包括被任务文件和规则排除的视频）
+	HasPreAnnotation bool   `json:"has_pre_annotation"`       // 是否有预标注
+	HasAnnotation    bool   `json:"has_annotation"`           // 是否已有标注
+
+	Container string `json:"container"` // 容器格式，如 mp4、quicktime、webm、matroska
+	MIMEType  string `json:"mime_type"` // 播放时使用的 MIME 类型
+	Playable  bool   `json:"playable"`  // 浏览器能否直接播放，不能时页面会给出提示
+
+	Metadata          // 视频元数据（由 ProbeVideos 填充，Size 在扫描时即可获得）
+	ProbeError string `json:"probe_error,omitempty"` // 读取元数据失败的原因
+
+	TaskInfo // 任务文件中的分配信息（负责人、优先级、截止日期、说明）
+
+	modTime int64 // 文件修改时间（Unix 纳秒），供 ScanIndex 判断文件是否变化
+}
+
+// ScanResult 表示一次扫描的结果
+type ScanResult struct {
+	Videos       []VideoInfo   `json:"videos"`
+	TaskWarnings []TaskWarning `json:"task_warnings"` // 任务文件中的问题（按行号排序），没有任务文件时为空
+	KeyWarnings  []KeyWarning  `json:"key_warnings"`  // 只差扩展名的视频（按 key 排序），见 uniqueKeys
 }
 
-// ScanVideos 扫描视频目录，返回视频列表
+// KeyWarning 表示同一目录中去掉扩展名后同名的一组视频（如 intro.mp4 和 intro.mov）
+// 只有 Owner 继续使用不带扩展名的 key 及已有的标注、修订历史和编辑锁，其他视频改用带扩展名的 key
+type KeyWarning struct {
+	Key     string   `json:"key"`     // 不带扩展名的 key
+	Owner   string   `json:"owner"`   // 保留该 key 的视频（相对路径）
+	Others  []string `json:"others"`  // 改用带扩展名 key 的视频（相对路径）
+	Message string   `json:"message"` // 说明
+}
+
+// ScanVideos 扫描视频目录，返回视频列表（只接受 DefaultExtensions）
 // 如果 taskFile 不为空，则只返回 taskFile 中列出的视频
 func ScanVideos(videoDir string, taskFile string) ([]VideoInfo, error) {
+	return ScanVideosWithOptions(videoDir, taskFile, ScanOptions{})
+}
+
+// ScanVideosWithOptions 按指定选项扫描视频目录
+func ScanVideosWithOptions(videoDir string, taskFile string, opts ScanOptions) ([]VideoInfo, error) {
+	result, err := Scan(videoDir, taskFile, opts)
+	return result.Videos, err
+}
+
+// Scan 按指定选项扫描视频目录，同时检查任务文件：
+// 没有对应视频的项、重复的项，以及只有忽略大小写才能匹配的项会记录在 TaskWarnings 中
+func Scan(videoDir string, taskFile string, opts ScanOptions) (*ScanResult, error) {
+	exts := opts.extensionSet()
+	result := &ScanResult{Videos: []VideoInfo{}, TaskWarnings: []TaskWarning{}, KeyWarnings: []KeyWarning{}}
+
 	if videoDir == "" {
-		return []VideoInfo{}, nil
+		return result, nil
 	}
 
 	if _, err := os.Stat(videoDir); os.IsNotExist(err) {
-		return []VideoInfo{}, fmt.Errorf("video directory does not exist: %s", videoDir)
+		return result, fmt.Errorf("video directory does not exist: %s", videoDir)
+	}
+
+	filter, err := ParseFilter(opts.Filter)
+	if err != nil {
+		return result, err
 	}
 
 	// 读取任务文件（如果提供）
-	var taskVideos map[string]bool
+	var tasks *taskSet
 	if taskFile != "" {
 		var err error
-		taskVideos, err = loadTaskFile(taskFile)
+		tasks, err = loadTaskFile(taskFile, exts)
 		if err != nil {
-			return []VideoInfo{}, fmt.Errorf("failed to load task file: %w", err)
+			return result, fmt.Errorf("failed to load task file: %w", err)
 		}
+		result.TaskWarnings = tasks.warnings
 	}
+	names := make(map[string]string)      // 小写名称 -> 实际的视频 key 或 stem，用于提示大小写错误
+	keyPaths := make(map[string][]string) // 去掉扩展名的 key -> 视频相对路径（包括被任务文件和规则排除的视频）
+	stemCount := make(map[string]int)     // stem -> 视频数（同上），用于判断同名冲突
 
 	var videos []VideoInfo
-	err := filepath.Walk(videoDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(videoDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() && strings.ToLower(filepath.Ext(path)) == ".mp4" {
+		if !info.IsDir() && exts[strings.ToLower(filepath.Ext(path))] {
 			filename := filepath.Base(path)
-			stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+			stem := trimVideoExt(filename, exts)
+			relPath, err := filepath.Rel(videoDir, path)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+			key := trimVideoExt(relPath, exts)
+			keyPaths[key] = append(keyPaths[key], relPath)
+			stemCount[stem]++
+
+			// 如果有任务文件，检查该视频是否在任务列表中（可写文件名、相对路径或规则）
+			var task TaskInfo
+			if tasks != nil {
+				names[strings.ToLower(key)] = key
+				if _, ok := names[strings.ToLower(stem)]; !ok {
+					names[strings.ToLower(stem)] = stem
+				}
 
-			// 如果有任务文件，检查该视频是否在任务列表中
-			if taskVideos != nil {
-				if !taskVideos[stem] {
+				entry, ok := tasks.lookup(key, stem, relPath)
+				if !ok {
 					return nil // 跳过不在任务列表中的视频
 				}
+				if entry != nil {
+					task = entry.TaskInfo
+				}
+			}
+
+			// 命令行等处额外指定的规则
+			if !filter.Match(key, relPath) {
+				return nil
 			}
 
+			container := containerForExt(filepath.Ext(path))
 			videos = append(videos, VideoInfo{
-				Filename: filename,
-				Stem:     stem,
-				Path:     path,
+				Filename:  filename,
+				Stem:      stem,
+				Path:      path,
+				RelPath:   relPath,
+				Key:       key,
+				Container: container.Name,
+				MIMEType:  container.MIMEType,
+				Playable:  container.Playable,
+				Metadata:  Metadata{Size: info.Size()},
+				TaskInfo:  task,
+				modTime:   info.ModTime().UnixNano(),
 			})
 		}
 
 		return nil
 	})
 
-	return videos, err
+	result.KeyWarnings = uniqueKeys(videos, keyPaths, opts.extensionList())
+	// 同名冲突同样按目录中所有视频判断：被排除的同名视频仍可能拥有平铺的旧标注文件
+	for i := range videos {
+		videos[i].StemCollision = stemCount[videos[i].Stem] > 1
+	}
+	if videos != nil {
+		result.Videos = videos
+	}
+
+	if tasks != nil {
+		result.TaskWarnings = append(result.TaskWarnings, tasks.unmatched(names)...)
+	}
+	sort.SliceStable(result.TaskWarnings, func(i, j int) bool {
+		return result.TaskWarnings[i].Line < result.TaskWarnings[j].Line
+	})
+
+	return result, err
 }
 
-// loadTaskFile 加载任务文件，返回视频名称（不含扩展名）的集合
-func loadTaskFile(taskFile string) (map[string]bool, error) {
-	file, err := os.Open(taskFile)
-	if err != nil {
-		return nil, err
+// uniqueKeys 让每个视频的 key 唯一：同一目录中只差扩展名的视频（如 intro.mp4 和 intro.mov）
+// 去掉扩展名后 key 相同，会读写同一个标注文件。扩展名在 exts 中最靠前的视频保留原来的 key，
+// 已有的标注、修订历史和编辑锁仍属于它（只支持 .mp4 时保存的标注都属于 mp4 视频），
+// 其他视频改用带扩展名的相对路径作为 key，每组返回一个 KeyWarning
+// paths 包含目录中所有视频，key 因此不会随任务文件或规则的变化而改变
+func uniqueKeys(videos []VideoInfo, paths map[string][]string, exts []string) []KeyWarning {
+	rank := func(relPath string) int {
+		ext := strings.ToLower(filepath.Ext(relPath))
+		for i, e := range exts {
+			if e == ext {
+				return i
+			}
+		}
+		return len(exts)
 	}
-	defer file.Close()
 
-	taskVideos := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// 跳过空行
-		if line == "" {
+	owners := make(map[string]string)
+	warnings := []KeyWarning{}
+	for key, group := range paths {
+		if len(group) < 2 {
 			continue
 		}
-		// 移除可能的 .mp4 后缀
-		stem := strings.TrimSuffix(line, ".mp4")
-		taskVideos[stem] = true
+		sort.Slice(group, func(i, j int) bool {
+			if ri, rj := rank(group[i]), rank(group[j]); ri != rj {
+				return ri < rj
+			}
+			return group[i] < group[j]
+		})
+		owners[key] = group[0]
+		warnings = append(warnings, KeyWarning{
+			Key:    key,
+			Owner:  group[0],
+			Others: group[1:],
+			Message: fmt.Sprintf("%s differ only by extension: %s keeps annotation key %q and its existing annotation; %s use their file name with extension as the key",
+				strings.Join(group, ", "), group[0], key, strings.Join(group[1:], ", ")),
+		})
 	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Key < warnings[j].Key })
 
-	if err := scanner.Err(); err != nil {
+	for i := range videos {
+		if owner, ok := owners[videos[i].Key]; ok && owner != videos[i].RelPath {
+			videos[i].Key = videos[i].RelPath
+		}
+	}
+	return warnings
+}
+
+// unmatched 返回扫描后没有匹配到任何视频的名称和规则；names 为小写名称到实际视频名称的映射
+func (t *taskSet) unmatched(names map[string]string) []TaskWarning {
+	var warnings []TaskWarning
+	for name, entry := range t.names {
+		if t.nameHits[name] {
+			continue
+		}
+		if actual, ok := names[strings.ToLower(name)]; ok {
+			warnings = append(warnings, TaskWarning{
+				Kind:       TaskCaseMismatch,
+				Line:       entry.Line,
+				Video:      entry.Video,
+				Message:    fmt.Sprintf("no video named %q; did you mean %q? names are case-sensitive", name, actual),
+				Suggestion: actual,
+			})
+			continue
+		}
+		message := fmt.Sprintf("no video named %q", name)
+		if hasGlobChars(name) {
+			message += fmt.Sprintf("; write %q to use it as a pattern", "glob:"+entry.Video)
+		}
+		warnings = append(warnings, TaskWarning{
+			Kind:    TaskMissing,
+			Line:    entry.Line,
+			Video:   entry.Video,
+			Message: message,
+		})
+	}
+	for i, rule := range t.rules.rules {
+		if !t.ruleHits[i] {
+			warnings = append(warnings, TaskWarning{
+				Kind:    TaskUnmatchedPattern,
+				Line:    rule.order,
+				Video:   rule.text,
+				Message: fmt.Sprintf("pattern %q matches no video", rule.text),
+			})
+		}
+	}
+	return warnings
+}
+
+// FindCollisions 找出位于不同子目录但文件名（stem）相同的视频，返回 stem -> 各视频的 key
+// 这些视频无法通过平铺的旧标注文件（<stem>.txt）区分
+func FindCollisions(videos []VideoInfo) map[string][]string {
+	byStem := make(map[string][]string)
+	for _, v := range videos {
+		byStem[v.Stem] = append(byStem[v.Stem], v.Key)
+	}
+	collisions := make(map[string][]string)
+	for stem, keys := range byStem {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			collisions[stem] = keys
+		}
+	}
+	return collisions
+}
+
+// AnnotationKeys 返回在标注目录中查找该视频标注时使用的 key：
+// 先按相对路径（镜像视频目录结构），没有同名冲突时再按旧的平铺 stem
+func (v *VideoInfo) AnnotationKeys() []string {
+	if v.Key == v.Stem || v.StemCollision {
+		return []string{v.Key}
+	}
+	return []string{v.Key, v.Stem}
+}
+
+// ProbeVideos 读取每个视频的元数据（时长、分辨率、帧率等），失败时记录在 ProbeError 中
+// 只支持 MP4/QuickTime 结构的容器，其他容器（WebM、MKV）保留扫描时的信息
+func ProbeVideos(videos []VideoInfo) {
+	for i := range videos {
+		if !containerForExt(filepath.Ext(videos[i].Path)).ISOBMFF {
+			continue
+		}
+		meta, err := Probe(videos[i].Path)
+		if err != nil {
+			videos[i].ProbeError = err.Error()
+			continue
+		}
+		videos[i].Metadata = *meta
+		videos[i].ProbeError = ""
+	}
+}
+
+// ValidateTaskFile 检查任务文件能否解析（格式、优先级、日期和规则）
+func ValidateTaskFile(taskFile string) error {
+	_, err := loadTaskFile(taskFile, ScanOptions{}.extensionSet())
+	return err
+}
+
+// loadTaskFile 加载任务文件（格式见 LoadTaskFile），区分普通视频名称和规则（见 IsPattern）
+// 名称中带有已接受的视频扩展名时会被去掉；同一视频出现多次时使用第一项，其余记录为重复
+func loadTaskFile(taskFile string, exts map[string]bool) (*taskSet, error) {
+	entries, err := LoadTaskFile(taskFile)
+	if err != nil {
 		return nil, err
 	}
 
-	return taskVideos, nil
+	tasks := &taskSet{
+		names:    make(map[string]*TaskEntry, len(entries)),
+		warnings: []TaskWarning{},
+		nameHits: make(map[string]bool),
+	}
+	for i := range entries {
+		if IsPattern(entries[i].Video) {
+			rule, err := compileRule(entries[i].Video, entries[i].Line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", entries[i].Line, err)
+			}
+			rule.entry = &entries[i]
+			tasks.rules.add(rule)
+			continue
+		}
+
+		// 移除可能的视频扩展名后缀
+		stem := trimVideoExt(filepath.ToSlash(entries[i].Video), exts)
+		if first, ok := tasks.names[stem]; ok {
+			tasks.warnings = append(tasks.warnings, TaskWarning{
+				Kind:    TaskDuplicate,
+				Line:    entries[i].Line,
+				Video:   entries[i].Video,
+				Message: fmt.Sprintf("%q is already listed on line %d; this entry is ignored", stem, first.Line),
+			})
+			continue
+		}
+		tasks.names[stem] = &entries[i]
+	}
+	tasks.ruleHits = make([]bool, len(tasks.rules.rules))
+	return tasks, nil
 }
 
 // MatchAnnotations 匹配预标注和已有标注
 func MatchAnnotations(videos []VideoInfo, preAnnotationDir, outputDir string) {
 	// 创建预标注文件映射
-	preAnnotationMap := make(map[string]bool)
-	if preAnnotationDir != "" {
-		if entries, err := os.ReadDir(preAnnotationDir); err == nil {
-			for _, entry := range entries {
-				if !entry.IsDir() && strings.ToLower(filepath.Ext(entry.Name())) == ".txt" {
-					stem := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
-					preAnnotationMap[stem] = true
-				}
-			}
+	preAnnotationMap := listAnnotationKeys(preAnnotationDir)
+
+	// 创建已有标注文件映射
+	annotationMap := listAnnotationKeys(outputDir)
+
+	// 更新视频信息
+	for i := range videos {
+		videos[i].HasPreAnnotation = false
+		videos[i].HasAnnotation = false
+		for _, key := range videos[i].AnnotationKeys() {
+			videos[i].HasPreAnnotation = videos[i].HasPreAnnotation || preAnnotationMap[key]
+			videos[i].HasAnnotation = videos[i].HasAnnotation || annotationMap[key]
 		}
 	}
+}
 
-	// 创建已有标注文件映射
-	annotationMap := make(map[string]bool)
-	if outputDir != "" {
-		if entries, err := os.ReadDir(outputDir); err == nil {
-			for _, entry := range entries {
-				if !entry.IsDir() && strings.ToLower(filepath.Ext(entry.Name())) == ".txt" {
-					stem := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
-					annotationMap[stem] = true
-				}
+// listAnnotationKeys 递归列出目录中所有标注文件（任意已注册格式）的 key，跳过修订历史和 .git 目录
+func listAnnotationKeys(dir string) map[string]bool {
+	keys := make(map[string]bool)
+	if dir == "" {
+		return keys
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // 跳过无法读取的子目录
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if annotation.SkipDir(rel) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		if annotation.IsAnnotationFile(d.Name()) {
+			keys[strings.TrimSuffix(rel, filepath.Ext(rel))] = true
 		}
+		return nil
+	})
+	return keys
+}
+
+// FindAnnotationPath 查找已存在的标注文件路径，优先使用 preferredExt 格式
+// stem 可以是带子目录的 key；不存在任何格式的标注文件时返回空字符串
+func FindAnnotationPath(stem, dir, preferredExt string) string {
+	if dir == "" {
+		return ""
 	}
+	exts := append([]string{preferredExt}, annotation.Extensions()...)
+	for _, ext := range exts {
+		if ext == "" {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(stem)+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
 
-	// 更新视频信息
-	for i := range videos {
-		videos[i].HasPreAnnotation = preAnnotationMap[videos[i].Stem]
-		videos[i].HasAnnotation = annotationMap[videos[i].Stem]
+// FindAnnotationPathForKeys 依次按 keys 查找已存在的标注文件路径（见 VideoInfo.AnnotationKeys）
+func FindAnnotationPathForKeys(keys []string, dir, preferredExt string) string {
+	for _, key := range keys {
+		if path := FindAnnotationPath(key, dir, preferredExt); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// GetAnnotationPathWithExt 获取指定格式的标注文件路径，stem 可以是带子目录的 key
+func GetAnnotationPathWithExt(stem, dir, ext string) string {
+	if dir == "" {
+		return ""
 	}
+	return filepath.Join(dir, filepath.FromSlash(stem)+ext)
 }
 
 // GetAnnotationPath 获取标注文件路径
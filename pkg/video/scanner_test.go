@@ -0,0 +1,247 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanKeys(t *testing.T) {
+	// 同一目录中只差扩展名的视频中，扩展名靠前的保留原来的 key，其他使用带扩展名的 key
+	// 不同目录中的同名视频按相对路径区分
+	dir := t.TempDir()
+	for _, name := range []string{"intro.mp4", "course/intro.mp4", "course/intro.mov", "other/intro.mp4", "other/outro.mp4"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter []string
+		want   map[string]string // 相对路径 -> key
+	}{
+		{"all videos", nil, map[string]string{
+			"intro.mp4":        "intro",
+			"course/intro.mp4": "course/intro",
+			"course/intro.mov": "course/intro.mov",
+			"other/intro.mp4":  "other/intro",
+			"other/outro.mp4":  "other/outro",
+		}},
+		// 被规则排除的视频仍然参与判断，key 不随规则变化
+		{"filtered", []string{"course/intro.mov"}, map[string]string{
+			"course/intro.mov": "course/intro.mov",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			videos, err := ScanVideosWithOptions(dir, "", ScanOptions{Extensions: []string{".mp4", ".mov"}, Filter: tt.filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, v := range videos {
+				got[v.RelPath] = v.Key
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTaskWarnings(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Intro.mp4", "outro.mp4", "course/a.mp4"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	taskFile := filepath.Join(t.TempDir(), "tasks.txt")
+	tasks := "intro\noutro.mp4\nmissing\noutro\ncourse/a\nglob:drafts/*\n"
+	if err := os.WriteFile(taskFile, []byte(tasks), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Scan(dir, taskFile, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, v := range result.Videos {
+		keys = append(keys, v.Key)
+	}
+	if want := []string{"course/a", "outro"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("videos = %v, want %v", keys, want)
+	}
+
+	type warning struct {
+		Kind       TaskWarningKind
+		Line       int
+		Suggestion string
+	}
+	var got []warning
+	for _, w := range result.TaskWarnings {
+		got = append(got, warning{w.Kind, w.Line, w.Suggestion})
+	}
+	want := []warning{
+		{TaskCaseMismatch, 1, "Intro"},
+		{TaskMissing, 3, ""},
+		{TaskDuplicate, 4, ""},
+		{TaskUnmatchedPattern, 6, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %+v, want %+v", got, want)
+	}
+}
+
+func TestScanStemCollisionWithTaskFile(t *testing.T) {
+	// 任务文件只列出 a/intro，b/intro 被排除，但平铺的 intro.txt 仍可能属于它
+	dir := t.TempDir()
+	createFiles(t, dir, "a/intro.mp4", "b/intro.mp4", "b/outro.mp4")
+	taskFile := filepath.Join(t.TempDir(), "tasks.txt")
+	if err := os.WriteFile(taskFile, []byte("a/intro\nb/outro\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	videos, err := ScanVideos(dir, taskFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, v := range videos {
+		got[v.Key] = v.AnnotationKeys()
+	}
+	want := map[string][]string{
+		"a/intro": {"a/intro"},
+		"b/outro": {"b/outro", "outro"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotation keys = %v, want %v", got, want)
+	}
+}
+
+// createFiles 在 dir 下创建空文件，names 为以 / 分隔的相对路径
+func createFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanKeyWarnings(t *testing.T) {
+	// 已有 intro.mp4 的标注时新增 intro.mov：intro.mp4 保留 key 和标注，并给出提示
+	dir := t.TempDir()
+	createFiles(t, dir, "intro.mov", "intro.mp4", "course/a.webm", "course/a.mov", "course/a.mp4", "course/b.mp4")
+
+	tests := []struct {
+		exts []string
+		want []KeyWarning
+	}{
+		{[]string{".mp4", ".mov", ".webm"}, []KeyWarning{
+			{Key: "course/a", Owner: "course/a.mp4", Others: []string{"course/a.mov", "course/a.webm"}},
+			{Key: "intro", Owner: "intro.mp4", Others: []string{"intro.mov"}},
+		}},
+		// 按配置中的顺序选择保留 key 的视频
+		{[]string{".webm", ".mov", ".mp4"}, []KeyWarning{
+			{Key: "course/a", Owner: "course/a.webm", Others: []string{"course/a.mov", "course/a.mp4"}},
+			{Key: "intro", Owner: "intro.mov", Others: []string{"intro.mp4"}},
+		}},
+		{[]string{".mp4"}, []KeyWarning{}},
+	}
+	for _, tt := range tests {
+		result, err := Scan(dir, "", ScanOptions{Extensions: tt.exts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := result.KeyWarnings
+		for i := range got {
+			if got[i].Message == "" {
+				t.Errorf("%v: warning for %s has no message", tt.exts, got[i].Key)
+			}
+			got[i].Message = ""
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: key warnings = %+v, want %+v", tt.exts, got, tt.want)
+		}
+
+		keys := make(map[string]bool)
+		for _, v := range result.Videos {
+			if keys[v.Key] {
+				t.Errorf("%v: duplicate key %s", tt.exts, v.Key)
+			}
+			keys[v.Key] = true
+		}
+		for _, w := range tt.want {
+			if !keys[w.Key] {
+				t.Errorf("%v: no video keeps key %s", tt.exts, w.Key)
+			}
+		}
+	}
+}
+
+func TestListAnnotationKeys(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "intro.txt", "course/a.json", "course/notes.md",
+		".mp4label/history/intro/000001.rev", ".mp4label/history/intro/notes.txt", ".git/info/refs.txt")
+
+	want := map[string]bool{"intro": true, "course/a": true}
+	if got := listAnnotationKeys(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("listAnnotationKeys = %v, want %v", got, want)
+	}
+}
+
+func TestScanTaskFileLiteralNames(t *testing.T) {
+	// 没有 glob: 前缀的项是普通名称：lesson[1] 只匹配同名视频，不是字符集合
+	dir := t.TempDir()
+	createFiles(t, dir, "lesson[1].mp4", "lesson1.mp4", "lesson2.mp4", "!draft.mp4", "ep?.mp4", "ep1.mp4")
+	taskFile := filepath.Join(t.TempDir(), "tasks.txt")
+	if err := os.WriteFile(taskFile, []byte("lesson[1]\n!draft\nep?\nmissing*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Scan(dir, taskFile, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, v := range result.Videos {
+		keys = append(keys, v.Key)
+	}
+	sort.Strings(keys)
+	if want := []string{"!draft", "ep?", "lesson[1]"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("videos = %v, want %v", keys, want)
+	}
+	if len(result.TaskWarnings) != 1 || result.TaskWarnings[0].Kind != TaskMissing ||
+		!strings.Contains(result.TaskWarnings[0].Message, `"glob:missing*"`) {
+		t.Errorf("warnings = %+v, want a missing entry suggesting glob:", result.TaskWarnings)
+	}
+
+	if err := os.WriteFile(taskFile, []byte("glob:lesson?\n!glob:lesson2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	videos, err := ScanVideos(dir, taskFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 1 || videos[0].Key != "lesson1" {
+		t.Errorf("videos with glob: rules = %+v, want lesson1", videos)
+	}
+}
@@ -0,0 +1,109 @@
+package video
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// 任务拆分的均衡方式
+const (
+	SplitByCount    = "count"    // 每份视频数量尽量相同
+	SplitByDuration = "duration" // 每份视频总时长尽量相同
+)
+
+// SplitOptions 控制任务拆分
+type SplitOptions struct {
+	Parts   int     // 拆分份数（标注人数）
+	By      string  // 均衡方式：SplitByCount（默认）或 SplitByDuration
+	Seed    int64   // 随机种子，相同输入和种子得到相同结果
+	Overlap float64 // 重叠比例（0-100），这部分视频同时分给两个人，用于计算标注一致性
+}
+
+// TaskPart 表示拆分后的一份任务
+type TaskPart struct {
+	Videos     []VideoInfo // 按 key 排序
+	DurationMs int64       // 总时长
+	Overlap    int         // 其中与其他份重叠的视频数
+}
+
+// SplitTasks 将视频随机（按 Seed 确定）拆分为 opts.Parts 份：
+// 先按 Overlap 比例选出重叠视频，各分给当前负载最小的两份，再将其余视频依次分给负载最小的一份；
+// 按时长均衡时先分配长视频，使各份总时长尽量接近
+func SplitTasks(videos []VideoInfo, opts SplitOptions) ([]TaskPart, error) {
+	if opts.Parts < 1 {
+		return nil, fmt.Errorf("number of parts must be at least 1")
+	}
+	if opts.Overlap < 0 || opts.Overlap > 100 {
+		return nil, fmt.Errorf("overlap must be between 0 and 100")
+	}
+	if opts.Overlap > 0 && opts.Parts < 2 {
+		return nil, fmt.Errorf("overlap requires at least 2 parts")
+	}
+	byDuration := false
+	switch opts.By {
+	case "", SplitByCount:
+	case SplitByDuration:
+		byDuration = true
+	default:
+		return nil, fmt.Errorf("unknown split mode: %s", opts.By)
+	}
+
+	// 先按 key 排序，使结果只取决于视频集合和种子，与扫描顺序无关
+	shuffled := make([]VideoInfo, len(videos))
+	copy(shuffled, videos)
+	sort.Slice(shuffled, func(i, j int) bool { return shuffled[i].Key < shuffled[j].Key })
+	rng := rand.New(rand.NewSource(opts.Seed))
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	overlapCount := int(math.Round(float64(len(shuffled)) * opts.Overlap / 100))
+	overlapped, rest := shuffled[:overlapCount], shuffled[overlapCount:]
+	if byDuration {
+		byLength := func(list []VideoInfo) {
+			sort.SliceStable(list, func(i, j int) bool { return list[i].DurationMs > list[j].DurationMs })
+		}
+		byLength(overlapped)
+		byLength(rest)
+	}
+
+	parts := make([]TaskPart, opts.Parts)
+	load := func(i int) int64 {
+		if byDuration {
+			return parts[i].DurationMs
+		}
+		return int64(len(parts[i].Videos))
+	}
+	// lightest 返回负载最小的份（相同时取序号小的），跳过 except
+	lightest := func(except int) int {
+		best := -1
+		for i := range parts {
+			if i != except && (best < 0 || load(i) < load(best)) {
+				best = i
+			}
+		}
+		return best
+	}
+	assign := func(i int, v VideoInfo) {
+		parts[i].Videos = append(parts[i].Videos, v)
+		parts[i].DurationMs += v.DurationMs
+	}
+
+	for _, v := range overlapped {
+		first := lightest(-1)
+		assign(first, v)
+		second := lightest(first)
+		assign(second, v)
+		parts[first].Overlap++
+		parts[second].Overlap++
+	}
+	for _, v := range rest {
+		assign(lightest(-1), v)
+	}
+
+	for i := range parts {
+		list := parts[i].Videos
+		sort.Slice(list, func(a, b int) bool { return list[a].Key < list[b].Key })
+	}
+	return parts, nil
+}
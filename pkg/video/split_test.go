@@ -0,0 +1,151 @@
+package video
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testVideos 生成 n 个测试视频，第 i 个视频时长为 durations[i % len(durations)] 毫秒
+func testVideos(n int, durations ...int64) []VideoInfo {
+	videos := make([]VideoInfo, n)
+	for i := range videos {
+		videos[i].Key = fmt.Sprintf("v%02d", i)
+		if len(durations) > 0 {
+			videos[i].DurationMs = durations[i%len(durations)]
+		}
+	}
+	return videos
+}
+
+func TestSplitTasks(t *testing.T) {
+	tests := []struct {
+		name        string
+		videos      []VideoInfo
+		opts        SplitOptions
+		wantCounts  []int
+		wantOverlap int // 每份中重叠视频数的总和
+	}{
+		{"even count", testVideos(9), SplitOptions{Parts: 3}, []int{3, 3, 3}, 0},
+		{"uneven count", testVideos(10), SplitOptions{Parts: 3, By: SplitByCount}, []int{4, 3, 3}, 0},
+		{"more parts than videos", testVideos(2), SplitOptions{Parts: 3}, []int{1, 1, 0}, 0},
+		{"single part", testVideos(5), SplitOptions{Parts: 1}, []int{5}, 0},
+		// 重叠视频同时分给两份：10 个视频的 20% 即 2 个，共 12 份分配
+		{"overlap", testVideos(10), SplitOptions{Parts: 3, Overlap: 20}, []int{4, 4, 4}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := SplitTasks(tt.videos, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var counts []int
+			overlap := 0
+			seen := make(map[string]int)
+			for _, p := range parts {
+				counts = append(counts, len(p.Videos))
+				overlap += p.Overlap
+				for i, v := range p.Videos {
+					seen[v.Key]++
+					if i > 0 && p.Videos[i-1].Key >= v.Key {
+						t.Errorf("part videos not sorted by key: %s before %s", p.Videos[i-1].Key, v.Key)
+					}
+				}
+			}
+			if !reflect.DeepEqual(counts, tt.wantCounts) || overlap != tt.wantOverlap {
+				t.Errorf("counts = %v, overlap = %d; want %v, %d", counts, overlap, tt.wantCounts, tt.wantOverlap)
+			}
+			// 每个视频至少分配一次，重叠视频恰好分配两次
+			twice := 0
+			for _, v := range tt.videos {
+				switch seen[v.Key] {
+				case 1:
+				case 2:
+					twice++
+				default:
+					t.Errorf("video %s assigned %d times", v.Key, seen[v.Key])
+				}
+			}
+			if twice*2 != tt.wantOverlap {
+				t.Errorf("%d videos assigned twice, want %d", twice, tt.wantOverlap/2)
+			}
+		})
+	}
+}
+
+func TestSplitTasksByDuration(t *testing.T) {
+	videos := testVideos(6, 60000, 10000, 10000, 20000, 20000, 40000)
+	parts, err := SplitTasks(videos, SplitOptions{Parts: 2, By: SplitByDuration, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, p := range parts {
+		var sum int64
+		for _, v := range p.Videos {
+			sum += v.DurationMs
+		}
+		if sum != p.DurationMs {
+			t.Errorf("part duration = %d, sum of videos = %d", p.DurationMs, sum)
+		}
+		total += sum
+	}
+	if total != 160000 || parts[0].DurationMs != 80000 || parts[1].DurationMs != 80000 {
+		t.Errorf("durations = %d, %d; want 80000 each", parts[0].DurationMs, parts[1].DurationMs)
+	}
+}
+
+func TestSplitTasksDeterministic(t *testing.T) {
+	videos := testVideos(20)
+	reversed := make([]VideoInfo, len(videos))
+	for i, v := range videos {
+		reversed[len(videos)-1-i] = v
+	}
+
+	keys := func(parts []TaskPart) [][]string {
+		var out [][]string
+		for _, p := range parts {
+			var k []string
+			for _, v := range p.Videos {
+				k = append(k, v.Key)
+			}
+			out = append(out, k)
+		}
+		return out
+	}
+	split := func(videos []VideoInfo, seed int64) [][]string {
+		parts, err := SplitTasks(videos, SplitOptions{Parts: 4, Seed: seed, Overlap: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys(parts)
+	}
+
+	// 结果只取决于视频集合和种子，与输入顺序无关
+	if a, b := split(videos, 1), split(reversed, 1); !reflect.DeepEqual(a, b) {
+		t.Errorf("split depends on input order:\n%v\n%v", a, b)
+	}
+	if a, b := split(videos, 1), split(videos, 2); reflect.DeepEqual(a, b) {
+		t.Errorf("different seeds gave the same split: %v", a)
+	}
+}
+
+func TestSplitTasksErrors(t *testing.T) {
+	tests := []struct {
+		opts SplitOptions
+		want string
+	}{
+		{SplitOptions{Parts: 0}, "at least 1"},
+		{SplitOptions{Parts: 2, Overlap: -1}, "between 0 and 100"},
+		{SplitOptions{Parts: 2, Overlap: 101}, "between 0 and 100"},
+		{SplitOptions{Parts: 1, Overlap: 10}, "at least 2 parts"},
+		{SplitOptions{Parts: 2, By: "size"}, "unknown split mode"},
+	}
+	for _, tt := range tests {
+		_, err := SplitTasks(testVideos(4), tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SplitTasks(%+v) error = %v, want %q", tt.opts, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskInfo 表示任务文件为视频分配的信息
+type TaskInfo struct {
+	Assignee string `json:"assignee,omitempty"` // 负责的标注人员
+	Priority *int   `json:"priority,omitempty"` // 优先级，数字越小越优先，nil 表示未设置
+	Deadline string `json:"deadline,omitempty"` // 截止日期（YYYY-MM-DD）
+	Notes    string `json:"notes,omitempty"`    // 针对该视频的标注说明
+}
+
+// TaskEntry 表示任务文件中的一项
+type TaskEntry struct {
+	Video string `json:"video"` // 视频名称或相对视频目录的路径，可带扩展名
+	TaskInfo
+	Line int `json:"line,omitempty"` // 所在行号（JSON 格式为序号），用于报告问题
+}
+
+// TaskWarningKind 表示任务文件问题的类型
+type TaskWarningKind string
+
+const (
+	TaskMissing      TaskWarningKind = "missing"       // 没有对应的视频
+	TaskDuplicate    TaskWarningKind = "duplicate"     // 同一视频出现多次
+	TaskCaseMismatch TaskWarningKind = "case_mismatch" // 只有忽略大小写时才能匹配到视频
+
+	TaskUnmatchedPattern TaskWarningKind = "unmatched_pattern" // 规则没有匹配到任何视频
+)
+
+// TaskWarning 表示任务文件中的一个问题，用于在开始标注前检查任务文件
+type TaskWarning struct {
+	Kind       TaskWarningKind `json:"kind"`
+	Line       int             `json:"line"`                 // 所在行号（JSON 格式为序号）
+	Video      string          `json:"video"`                // 任务文件中写的名称
+	Message    string          `json:"message"`              // 说明
+	Suggestion string          `json:"suggestion,omitempty"` // 大小写不同的实际视频名称
+}
+
+// taskSet 表示加载后的任务文件：普通视频名称，以及按行号顺序求值的规则（见 Filter）
+type taskSet struct {
+	names    map[string]*TaskEntry // 视频名称（不含扩展名）-> 任务项
+	rules    Filter                // 排除、正则和 glob 规则
+	warnings []TaskWarning         // 加载时发现的问题（重复项、无效规则）
+
+	nameHits map[string]bool // 扫描中匹配到视频的名称
+	ruleHits []bool          // 扫描中匹配到视频的规则
+}
+
+// hasInclude 判断任务文件是否包含任何包含项（名称或包含规则）
+func (t *taskSet) hasInclude() bool {
+	return len(t.names) > 0 || t.rules.hasInclude
+}
+
+// lookup 判断视频是否在任务中，并返回对应的任务项（可能为 nil）
+// 名称和规则一起按任务文件中的顺序求值，后面的优先；最后匹配的是排除规则时视频被排除；
+// 没有任何匹配时，任务文件只有排除规则则保留视频
+func (t *taskSet) lookup(key, stem, relPath string) (*TaskEntry, bool) {
+	var best *TaskEntry
+	bestOrder, exclude, found := -1, false, false
+	for _, name := range []string{key, stem} {
+		if e := t.names[name]; e != nil {
+			t.nameHits[name] = true
+			if e.Line > bestOrder {
+				best, bestOrder, exclude, found = e, e.Line, false, true
+			}
+		}
+	}
+	for i := range t.rules.rules {
+		r := &t.rules.rules[i]
+		if !r.match(key, relPath) {
+			continue
+		}
+		t.ruleHits[i] = true
+		if r.order > bestOrder {
+			best, bestOrder, exclude, found = r.entry, r.order, r.exclude, true
+		}
+	}
+
+	if !found {
+		return nil, !t.hasInclude()
+	}
+	if exclude {
+		return nil, false
+	}
+	return best, true
+}
+
+// 优先级名称，与数字等价
+var priorityNames = map[string]int{
+	"urgent": 0,
+	"high":   1,
+	"medium": 2,
+	"low":    3,
+}
+
+// 表格任务文件中各列可用的列名（不区分大小写）
+var taskColumns = map[string]string{
+	"video":        "video",
+	"stem":         "video",
+	"path":         "video",
+	"file":         "video",
+	"filename":     "video",
+	"assignee":     "assignee",
+	"annotator":    "assignee",
+	"priority":     "priority",
+	"deadline":     "deadline",
+	"due":          "deadline",
+	"notes":        "notes",
+	"note":         "notes",
+	"instructions": "notes",
+}
+
+// LoadTaskFile 读取任务文件，按扩展名选择格式：
+//   - .csv / .tsv：带表头的表格，必须有 video 列，可选 assignee、priority、deadline、notes 列
+//   - .json：任务数组，或 {"tasks": [...]}，字段同上
+//   - 其他：每行一个视频名称（空行忽略）
+func LoadTaskFile(path string) ([]TaskEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return parseTaskTable(data, ',')
+	case ".tsv":
+		return parseTaskTable(data, '\t')
+	case ".json":
+		return parseTaskJSON(data)
+	default:
+		return parseTaskList(data)
+	}
+}
+
+// parseTaskList 解析纯文本任务文件：每行一个视频名称
+func parseTaskList(data []byte) ([]TaskEntry, error) {
+	var entries []TaskEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		// 跳过空行
+		if text == "" {
+			continue
+		}
+		entries = append(entries, TaskEntry{Video: text, Line: line})
+	}
+	return entries, scanner.Err()
+}
+
+// parseTaskTable 解析 CSV/TSV 任务文件，第一行为表头
+func parseTaskTable(data []byte, comma rune) ([]TaskEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if comma == '\t' {
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if field, ok := taskColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["video"]; !ok {
+		return nil, fmt.Errorf("missing video column in header")
+	}
+
+	var entries []TaskEntry
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := TaskEntry{
+			Video: cell("video"),
+			TaskInfo: TaskInfo{
+				Assignee: cell("assignee"),
+				Deadline: cell("deadline"),
+				Notes:    cell("notes"),
+			},
+			Line: line,
+		}
+		if entry.Video == "" {
+			continue // 跳过空行
+		}
+		if entry.Priority, err = parsePriority(cell("priority")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := validateDeadline(entry.Deadline); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// taskRecord 表示 JSON 任务文件中的一项，priority 可以是数字或名称
+type taskRecord struct {
+	Video    string      `json:"video"`
+	Stem     string      `json:"stem"`
+	Assignee string      `json:"assignee"`
+	Priority interface{} `json:"priority"`
+	Deadline string      `json:"deadline"`
+	Notes    string      `json:"notes"`
+}
+
+// parseTaskJSON 解析 JSON 任务文件
+func parseTaskJSON(data []byte) ([]TaskEntry, error) {
+	var records []taskRecord
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Tasks []taskRecord `json:"tasks"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("failed to parse task file: %w", err)
+		}
+		records = wrapper.Tasks
+	} else if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse task file: %w", err)
+	}
+
+	entries := make([]TaskEntry, 0, len(records))
+	for i, r := range records {
+		entry := TaskEntry{
+			Video: strings.TrimSpace(r.Video),
+			TaskInfo: TaskInfo{
+				Assignee: strings.TrimSpace(r.Assignee),
+				Deadline: strings.TrimSpace(r.Deadline),
+				Notes:    strings.TrimSpace(r.Notes),
+			},
+			Line: i + 1,
+		}
+		if entry.Video == "" {
+			entry.Video = strings.TrimSpace(r.Stem)
+		}
+		if entry.Video == "" {
+			return nil, fmt.Errorf("task %d: missing video", i+1)
+		}
+
+		var err error
+		switch p := r.Priority.(type) {
+		case nil:
+		case float64:
+			if p != math.Trunc(p) {
+				err = fmt.Errorf("invalid priority %v, should be a non-negative integer or urgent/high/medium/low", p)
+			} else {
+				entry.Priority, err = parsePriority(strconv.Itoa(int(p)))
+			}
+		case string:
+			entry.Priority, err = parsePriority(p)
+		default:
+			err = fmt.Errorf("invalid priority %v, should be a non-negative integer or urgent/high/medium/low", p)
+		}
+		if err == nil {
+			err = validateDeadline(entry.Deadline)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parsePriority 解析优先级：非负整数（数字越小越优先，可写作 P0、P1）或 urgent/high/medium/low，
+// 空字符串表示未设置，返回 nil
+func parsePriority(s string) (*int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil, nil
+	}
+	if p, ok := priorityNames[s]; ok {
+		return &p, nil
+	}
+	p, err := strconv.Atoi(strings.TrimPrefix(s, "p"))
+	if err != nil || p < 0 {
+		return nil, fmt.Errorf("invalid priority %q, should be a non-negative integer or urgent/high/medium/low", s)
+	}
+	return &p, nil
+}
+
+// validateDeadline 检查截止日期格式（YYYY-MM-DD），空字符串表示未设置
+func validateDeadline(s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return fmt.Errorf("invalid deadline %q, should be YYYY-MM-DD", s)
+	}
+	return nil
+}
+
+// ComparePriority 比较两个视频的任务优先级：优先级数字小的在前（未设置的排最后），
+// 相同时截止日期早的在前（未设置的排最后）；返回负数表示 a 排在 b 前面
+func ComparePriority(a, b *TaskInfo) int {
+	pa, pb := math.MaxInt, math.MaxInt
+	if a.Priority != nil {
+		pa = *a.Priority
+	}
+	if b.Priority != nil {
+		pb = *b.Priority
+	}
+	if pa != pb {
+		if pa < pb {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a.Deadline == b.Deadline:
+		return 0
+	case a.Deadline == "":
+		return 1
+	case b.Deadline == "":
+		return -1
+	}
+	return strings.Compare(a.Deadline, b.Deadline)
+}
+
+// WriteTaskFile 按扩展名写出任务文件（格式同 LoadTaskFile），纯文本格式只写视频名称
+// 含有规则字符（如 [ 或 *）的名称写为精确匹配的正则规则，读回时仍只匹配该视频
+func WriteTaskFile(path string, entries []TaskEntry) error {
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		writer := csv.NewWriter(&buf)
+		if strings.EqualFold(filepath.Ext(path), ".tsv") {
+			writer.Comma = '\t'
+		}
+		writer.Write([]string{"video", "assignee", "priority", "deadline", "notes"})
+		for _, e := range entries {
+			priority := ""
+			if e.Priority != nil {
+				priority = strconv.Itoa(*e.Priority)
+			}
+			writer.Write([]string{taskName(e.Video), e.Assignee, priority, e.Deadline, e.Notes})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	case ".json":
+		tasks := make([]TaskEntry, len(entries))
+		for i, e := range entries {
+			tasks[i] = TaskEntry{Video: taskName(e.Video), TaskInfo: e.TaskInfo}
+		}
+		data, err := json.MarshalIndent(tasks, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	default:
+		for _, e := range entries {
+			buf.WriteString(taskName(e.Video) + "\n")
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// taskName 返回写入任务文件的名称，会被当作规则的名称转为精确匹配的正则
+func taskName(video string) string {
+	if IsPattern(video) {
+		return "re:^" + regexp.QuoteMeta(video) + "$"
+	}
+	return video
+}
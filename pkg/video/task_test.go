@@ -0,0 +1,132 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestLoadTaskFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []TaskEntry
+	}{
+		{"list", "tasks.txt", "intro\n\n  course/a.mp4  \n", []TaskEntry{
+			{Video: "intro", Line: 1},
+			{Video: "course/a.mp4", Line: 3},
+		}},
+		{"csv", "tasks.csv", "\ufeffVideo,Annotator,Priority,Due,Notes\nintro,alice,high,2026-03-01,\"check, twice\"\n,,,,\nb,bob,P0,,\n", []TaskEntry{
+			{Video: "intro", TaskInfo: TaskInfo{Assignee: "alice", Priority: intPtr(1), Deadline: "2026-03-01", Notes: "check, twice"}, Line: 2},
+			{Video: "b", TaskInfo: TaskInfo{Assignee: "bob", Priority: intPtr(0)}, Line: 4},
+		}},
+		{"tsv", "tasks.tsv", "notes\tstem\nsay \"hi\"\tintro\n", []TaskEntry{
+			{Video: "intro", TaskInfo: TaskInfo{Notes: `say "hi"`}, Line: 2},
+		}},
+		{"json array", "tasks.json", `[{"video":"intro","priority":2},{"stem":"b","priority":"low","deadline":"2026-01-31"}]`, []TaskEntry{
+			{Video: "intro", TaskInfo: TaskInfo{Priority: intPtr(2)}, Line: 1},
+			{Video: "b", TaskInfo: TaskInfo{Priority: intPtr(3), Deadline: "2026-01-31"}, Line: 2},
+		}},
+		{"json object", "tasks.JSON", `{"tasks":[{"video":"intro","assignee":" alice "}]}`, []TaskEntry{
+			{Video: "intro", TaskInfo: TaskInfo{Assignee: "alice"}, Line: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadTaskFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadTaskFile = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTaskFileErrors(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{"tasks.csv", "", "failed to read header"},
+		{"tasks.csv", "assignee\nalice\n", "missing video column"},
+		{"tasks.csv", "video,priority\nintro,soon\n", `line 2: invalid priority "soon"`},
+		{"tasks.csv", "video,deadline\nintro,03/01/2026\n", `line 2: invalid deadline "03/01/2026"`},
+		{"tasks.json", `[{"video":"a","priority":1.5}]`, "task 1: invalid priority 1.5"},
+		{"tasks.json", `[{"video":"a"},{"assignee":"bob"}]`, "task 2: missing video"},
+		{"tasks.json", `{"tasks":`, "failed to parse task file"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.file)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadTaskFile(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadTaskFile(%s %q) error = %v, want %q", tt.file, tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestComparePriority(t *testing.T) {
+	tests := []struct {
+		a, b TaskInfo
+		want int
+	}{
+		{TaskInfo{Priority: intPtr(0)}, TaskInfo{Priority: intPtr(1)}, -1},
+		{TaskInfo{Priority: intPtr(2)}, TaskInfo{}, -1},
+		{TaskInfo{}, TaskInfo{Priority: intPtr(5)}, 1},
+		{TaskInfo{Priority: intPtr(1), Deadline: "2026-01-02"}, TaskInfo{Priority: intPtr(1), Deadline: "2026-01-01"}, 1},
+		{TaskInfo{Deadline: "2026-01-02"}, TaskInfo{}, -1},
+		{TaskInfo{}, TaskInfo{}, 0},
+	}
+	for _, tt := range tests {
+		if got := ComparePriority(&tt.a, &tt.b); got != tt.want {
+			t.Errorf("ComparePriority(%+v, %+v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTaskFile(t *testing.T) {
+	entries := []TaskEntry{
+		{Video: "intro", TaskInfo: TaskInfo{Assignee: "alice", Priority: intPtr(1), Deadline: "2026-03-01", Notes: "a, b"}},
+		{Video: "course/[draft] a", TaskInfo: TaskInfo{Assignee: "bob"}},
+		{Video: "glob:odd"},
+	}
+	for _, file := range []string{"tasks.csv", "tasks.tsv", "tasks.json", "tasks.txt"} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), file)
+			if err := WriteTaskFile(path, entries); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadTaskFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(entries) {
+				t.Fatalf("got %d entries, want %d", len(got), len(entries))
+			}
+			// 含 glob 字符的名称按原样写入，会被读作规则的名称写为精确匹配的正则
+			if want := "course/[draft] a"; got[1].Video != want {
+				t.Errorf("name with brackets written as %q, want %q", got[1].Video, want)
+			}
+			if want := `re:^glob:odd$`; got[2].Video != want {
+				t.Errorf("pattern-like name written as %q, want %q", got[2].Video, want)
+			}
+			if file != "tasks.txt" && !reflect.DeepEqual(got[0].TaskInfo, entries[0].TaskInfo) {
+				t.Errorf("task info = %+v, want %+v", got[0].TaskInfo, entries[0].TaskInfo)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package video
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xd/mp4label/pkg/annotation"
+)
+
+// ChangeKind 表示目录变化的类型
+type ChangeKind string
+
+const (
+	VideoAdded        ChangeKind = "video_added"
+	VideoRemoved      ChangeKind = "video_removed"
+	AnnotationCreated ChangeKind = "annotation_created"
+	AnnotationUpdated ChangeKind = "annotation_updated"
+	AnnotationDeleted ChangeKind = "annotation_deleted"
+)
+
+// 变化来源目录
+const (
+	SourceVideo           = "video"
+	SourcePreAnnotation   = "pre_annotation"
+	SourceOutput          = "output"
+	SourceModelAnnotation = "model_annotation"
+)
+
+// ChangeEvent 表示一个视频或标注文件的变化
+type ChangeEvent struct {
+	Type   ChangeKind `json:"type"`
+	Source string     `json:"source"` // 来源目录，见 Source* 常量
+	Key    string     `json:"key"`    // 视频或标注的 key（相对路径，不含扩展名，以 / 分隔）
+	Path   string     `json:"path"`   // 文件完整路径
+}
+
+// DiffVideos 比较两次扫描结果，返回新增和移除的视频（按 key 排序）
+func DiffVideos(before, after []VideoInfo) []ChangeEvent {
+	old := make(map[string]bool, len(before))
+	for _, v := range before {
+		old[v.Path] = true
+	}
+	current := make(map[string]bool, len(after))
+	var events []ChangeEvent
+	for _, v := range after {
+		current[v.Path] = true
+		if !old[v.Path] {
+			events = append(events, ChangeEvent{Type: VideoAdded, Source: SourceVideo, Key: v.Key, Path: v.Path})
+		}
+	}
+	for _, v := range before {
+		if !current[v.Path] {
+			events = append(events, ChangeEvent{Type: VideoRemoved, Source: SourceVideo, Key: v.Key, Path: v.Path})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+	return events
+}
+
+// AnnotationWatcher 轮询标注目录，通过比较文件大小和修改时间发现新建、修改和删除的标注文件
+// 不依赖平台的文件系统通知，网络盘上同样可用；非并发安全，应在同一个 goroutine 中调用 Poll
+type AnnotationWatcher struct {
+	snapshots map[string]annotationSnapshot // 来源 -> 上次轮询的结果
+}
+
+// annotationSnapshot 表示一个标注目录在某次轮询时的状态
+type annotationSnapshot struct {
+	dir   string
+	files map[string]fileState // 相对路径（含扩展名，以 / 分隔） -> 状态
+}
+
+// fileState 表示用于判断文件是否变化的属性
+type fileState struct {
+	size    int64
+	modTime int64
+}
+
+// NewAnnotationWatcher 创建标注目录轮询器
+func NewAnnotationWatcher() *AnnotationWatcher {
+	return &AnnotationWatcher{snapshots: make(map[string]annotationSnapshot)}
+}
+
+// Poll 扫描 dirs（来源 -> 目录，目录为空表示未配置），返回自上次调用以来的变化
+// 某个来源首次出现或目录被修改时只建立基线，不产生事件
+func (w *AnnotationWatcher) Poll(dirs map[string]string) []ChangeEvent {
+	var events []ChangeEvent
+	for source, dir := range dirs {
+		prev, known := w.snapshots[source]
+		if dir == "" {
+			delete(w.snapshots, source)
+			continue
+		}
+
+		files := snapshotAnnotationDir(dir)
+		w.snapshots[source] = annotationSnapshot{dir: dir, files: files}
+		if !known || prev.dir != dir {
+			continue
+		}
+
+		for rel, state := range files {
+			old, existed := prev.files[rel]
+			switch {
+			case !existed:
+				events = append(events, annotationEvent(AnnotationCreated, source, dir, rel))
+			case old != state:
+				events = append(events, annotationEvent(AnnotationUpdated, source, dir, rel))
+			}
+		}
+		for rel := range prev.files {
+			if _, ok := files[rel]; !ok {
+				events = append(events, annotationEvent(AnnotationDeleted, source, dir, rel))
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Source != events[j].Source {
+			return events[i].Source < events[j].Source
+		}
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
+
+// annotationEvent 根据相对路径生成标注变化事件
+func annotationEvent(kind ChangeKind, source, dir, rel string) ChangeEvent {
+	return ChangeEvent{
+		Type:   kind,
+		Source: source,
+		Key:    strings.TrimSuffix(rel, filepath.Ext(rel)),
+		Path:   filepath.Join(dir, filepath.FromSlash(rel)),
+	}
+}
+
+// snapshotAnnotationDir 递归记录目录中所有标注文件的大小和修改时间，目录不存在时返回空
+// 修订历史和 .git 目录不遍历（见 annotation.SkipDir）
+func snapshotAnnotationDir(dir string) map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // 跳过无法读取的子目录
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if annotation.SkipDir(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !annotation.IsAnnotationFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[rel] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		return nil
+	})
+	return files
+}
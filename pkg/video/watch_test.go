@@ -0,0 +1,107 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSnapshotAnnotationDirSkipsHistoryAndGit(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "intro.txt", "course/a.json", "course/notes.md",
+		".git/logs/HEAD.txt", "course/.git/info.json", ".mp4label/history/intro/000001.rev", ".mp4label/history/intro/draft.txt")
+
+	var got []string
+	for rel := range snapshotAnnotationDir(dir) {
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	if want := []string{"course/a.json", "intro.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot = %v, want %v", got, want)
+	}
+	if files := snapshotAnnotationDir(filepath.Join(dir, "missing")); len(files) != 0 {
+		t.Errorf("snapshot of a missing directory = %v", files)
+	}
+}
+
+func TestDiffVideos(t *testing.T) {
+	video := func(key string) VideoInfo { return VideoInfo{Key: key, Path: "/v/" + key + ".mp4"} }
+	before := []VideoInfo{video("a"), video("c"), video("d")}
+	after := []VideoInfo{video("b"), video("c"), video("a"), video("e")}
+
+	var got []string
+	for _, ev := range DiffVideos(before, after) {
+		if ev.Source != SourceVideo || ev.Path != "/v/"+ev.Key+".mp4" {
+			t.Errorf("event = %+v", ev)
+		}
+		got = append(got, string(ev.Type)+" "+ev.Key)
+	}
+	want := []string{"video_added b", "video_removed d", "video_added e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffVideos = %v, want %v", got, want)
+	}
+	if events := DiffVideos(after, after); len(events) != 0 {
+		t.Errorf("DiffVideos of the same list = %v", events)
+	}
+}
+
+func TestAnnotationWatcherPoll(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	w := NewAnnotationWatcher()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	poll := func(dirs map[string]string) []string {
+		t.Helper()
+		var got []string
+		for _, ev := range w.Poll(dirs) {
+			if ev.Path != filepath.Join(dirs[ev.Source], filepath.FromSlash(ev.Key)+filepath.Ext(ev.Path)) {
+				t.Errorf("event path = %s", ev.Path)
+			}
+			got = append(got, string(ev.Type)+" "+ev.Source+" "+ev.Key)
+		}
+		return got
+	}
+	dirs := map[string]string{SourceOutput: dir, SourcePreAnnotation: ""}
+
+	write("intro.txt", "a")
+	if got := poll(dirs); len(got) != 0 {
+		t.Errorf("first poll = %v, want a baseline without events", got)
+	}
+
+	write("course/a.json", "{}")
+	write("intro.txt", "ab")
+	write("notes.md", "ignored")
+	if got, want := poll(dirs), []string{"annotation_created output course/a", "annotation_updated output intro"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("poll = %v, want %v", got, want)
+	}
+	if got := poll(dirs); len(got) != 0 {
+		t.Errorf("poll without changes = %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "intro.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := poll(dirs), []string{"annotation_deleted output intro"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("poll = %v, want %v", got, want)
+	}
+
+	// 目录被修改或新配置时重新建立基线
+	createFiles(t, other, "x.txt")
+	if got := poll(map[string]string{SourceOutput: other, SourcePreAnnotation: dir}); len(got) != 0 {
+		t.Errorf("poll after changing directories = %v", got)
+	}
+	createFiles(t, other, "y.txt")
+	if got, want := poll(map[string]string{SourceOutput: other, SourcePreAnnotation: dir}), []string{"annotation_created output y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("poll = %v, want %v", got, want)
+	}
+}
@@ -1,5 +1,5 @@
 // 全局状态
-let currentVideo = null;
+let currentVideo = null; // 当前视频相对视频目录的路径（rel_path），子目录中可能存在同名视频
 let currentAnnotation = null;
 let currentModelAnnotation = null; // 模型标注数据
 let videos = [];
@@ -10,8 +10,22 @@ let selectedStepIndex = -1; // 当前选中的步骤索引，-1 表示没有选
 let currentFilter = 'all'; // 当前筛选状态
 let autoSaveTimer = null; // 自动保存定时器
 let lastSavedAnnotationJSON = null; // 上次保存的标注JSON，用于检测变化
+let currentDiagnostics = []; // 当前标注文件的解析诊断信息
+let currentKeyframes = []; // 当前视频的关键帧时间（秒），用于 [ ] 快捷键跳转
+let videoReloadTimer = null; // 收到视频增减事件后延迟刷新列表的定时器
+let assignees = []; // 任务文件中的负责人，用于筛选
+let authMode = ''; // 认证模式：''（单用户）、local 或 proxy
+let currentUser = null; // 当前登录的标注人员（多用户模式）
+let eventSource = null; // /api/events 连接
+let currentETag = null; // 当前标注在服务器上的版本号，保存和删除时通过 If-Match 发送
+let pendingConflict = null; // 正在处理的编辑冲突 { data, action }，期间暂停自动保存
+let editLock = null; // 自己持有的当前视频编辑锁（多用户模式）
+let editLockedBy = null; // 当前视频被其他人锁定时对方的锁，期间编辑器只读
+let lockRenewTimer = null; // 编辑锁续期定时器
+let selectedRevision = null; // 历史对话框中选中的修订号
 
 const AUTO_SAVE_DELAY = 1500; // 自动保存延迟（毫秒）
+const TIMESTAMP_PATTERN = /^(\d{1,2}:)?\d{2}:\d{2}(\.\d{3})?$/; // mm:ss(.SSS) 或 hh:mm:ss(.SSS)
 
 // DOM 元素
 const videoList = document.getElementById('videoList');
@@ -33,17 +47,129 @@ const saveConfigBtn = document.getElementById('saveConfigBtn');
 const cancelConfigBtn = document.getElementById('cancelConfigBtn');
 const insertTimestampBtn = document.getElementById('insertTimestampBtn');
 const playbackRate = document.getElementById('playbackRate');
+const assigneeFilter = document.getElementById('assigneeFilter');
+const sortSelect = document.getElementById('sortSelect');
+const taskNotes = document.getElementById('taskNotes');
+const loginModal = document.getElementById('loginModal');
+const loginForm = document.getElementById('loginForm');
+const loginError = document.getElementById('loginError');
+const currentUserLabel = document.getElementById('currentUser');
+const logoutBtn = document.getElementById('logoutBtn');
+const conflictModal = document.getElementById('conflictModal');
+const lockBanner = document.getElementById('lockBanner');
+const historyModal = document.getElementById('historyModal');
+const historyRevertBtn = document.getElementById('historyRevertBtn');
 
 
 // 初始化
-document.addEventListener('DOMContentLoaded', () => {
+document.addEventListener('DOMContentLoaded', async () => {
     initVideoPlayer();
-    loadConfig();
-    loadVideos();
     setupEventListeners();
     setupResizableHandles();
+    if (await checkLogin()) {
+        startSession();
+    }
 });
 
+// 加载配置、视频列表并订阅目录变化；多用户模式下登录后调用
+function startSession() {
+    loadConfig();
+    loadVideos();
+    subscribeServerEvents();
+}
+
+// 请求接口；多用户模式下会话过期（401）时弹出登录框
+async function apiFetch(url, options) {
+    const response = await fetch(url, options);
+    if (response.status === 401 && authMode === 'local') {
+        showLoginModal();
+    }
+    return response;
+}
+
+// 查询认证模式和当前用户，返回是否可以开始使用；需要登录时显示登录框
+async function checkLogin() {
+    try {
+        const response = await fetch('/api/me');
+        const data = await response.json();
+        authMode = data.auth_mode || '';
+        setCurrentUser(data.user);
+    } catch (error) {
+        console.error('Failed to check login:', error);
+        return true;
+    }
+
+    if (authMode === '' || currentUser) {
+        return true;
+    }
+    if (authMode === 'local') {
+        showLoginModal();
+    } else {
+        videoList.innerHTML = '<div class="loading">Not signed in: the reverse proxy did not provide a user name</div>';
+    }
+    return false;
+}
+
+// 显示当前标注人员；非管理员不能修改配置
+function setCurrentUser(user) {
+    currentUser = user || null;
+    if (currentUser) {
+        currentUserLabel.textContent = currentUser.name ? `${currentUser.name} (${currentUser.id})` : currentUser.id;
+        currentUserLabel.style.display = '';
+    } else {
+        currentUserLabel.style.display = 'none';
+    }
+    logoutBtn.style.display = currentUser && authMode === 'local' ? '' : 'none';
+    configBtn.style.display = !currentUser || currentUser.admin ? '' : 'none';
+}
+
+// 显示登录框
+function showLoginModal() {
+    loginError.style.display = 'none';
+    loginModal.style.display = 'block';
+    document.getElementById('loginId').focus();
+}
+
+// 提交登录
+async function submitLogin(e) {
+    e.preventDefault();
+    const id = document.getElementById('loginId').value.trim();
+    const password = document.getElementById('loginPassword').value;
+    try {
+        const response = await fetch('/api/login', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ id, password })
+        });
+        if (!response.ok) {
+            loginError.textContent = response.status === 401 ? 'Invalid user name or password' : await response.text();
+            loginError.style.display = 'block';
+            return;
+        }
+        const data = await response.json();
+        document.getElementById('loginPassword').value = '';
+        loginModal.style.display = 'none';
+        setCurrentUser(data.user);
+        startSession();
+    } catch (error) {
+        loginError.textContent = 'Failed to log in: ' + error.message;
+        loginError.style.display = 'block';
+    }
+}
+
+// 退出登录
+async function logout() {
+    await releaseEditLock();
+    try {
+        await fetch('/api/logout', { method: 'POST' });
+    } catch (error) {
+        console.error('Failed to log out:', error);
+    }
+    window.location.reload();
+}
+
 // 初始化 Video.js 播放器
 function initVideoPlayer() {
     player = videojs('videoPlayer', {
@@ -109,6 +235,10 @@ function setupEventListeners() {
     // 搜索
     searchInput.addEventListener('input', filterVideos);
 
+    // 负责人筛选和排序由服务器完成
+    assigneeFilter.addEventListener('change', loadVideos);
+    sortSelect.addEventListener('change', loadVideos);
+
     // 添加步骤
     addStepBtn.addEventListener('click', addStep);
 
@@ -179,6 +309,14 @@ function setupEventListeners() {
                 e.preventDefault();
                 insertCurrentTimestamp();
                 break;
+            case 219: // [ 键：跳到上一个关键帧
+                e.preventDefault();
+                seekToKeyframe(-1);
+                break;
+            case 221: // ] 键：跳到下一个关键帧
+                e.preventDefault();
+                seekToKeyframe(1);
+                break;
         }
     });
 
@@ -227,6 +365,26 @@ function setupEventListeners() {
 
     saveConfigBtn.addEventListener('click', saveConfig);
 
+    // 编辑冲突对话框
+    document.getElementById('conflictTheirsBtn').addEventListener('click', () => resolveConflict('theirs'));
+    document.getElementById('conflictMineBtn').addEventListener('click', () => resolveConflict('mine'));
+    document.getElementById('conflictCancelBtn').addEventListener('click', () => resolveConflict('cancel'));
+
+    // 修订历史对话框
+    document.getElementById('historyBtn').addEventListener('click', showHistory);
+    historyRevertBtn.addEventListener('click', revertToRevision);
+    document.getElementById('historyCloseBtn').addEventListener('click', () => {
+        historyModal.style.display = 'none';
+    });
+
+    // 登录和退出（多用户模式）
+    loginForm.addEventListener('submit', submitLogin);
+    logoutBtn.addEventListener('click', logout);
+
+    // 编辑锁：管理员强制解除；关闭页面时释放
+    document.getElementById('forceUnlockBtn').addEventListener('click', forceUnlock);
+    window.addEventListener('pagehide', () => releaseEditLock(true));
+
     // 禁止点击模态框外部关闭（用户必须点击保存或取消）
     window.addEventListener('click', (e) => {
         if (e.target === configModal) {
@@ -242,7 +400,7 @@ function setupEventListeners() {
 // 加载配置
 async function loadConfig() {
     try {
-        const response = await fetch('/api/config');
+        const response = await apiFetch('/api/config');
         config = await response.json();
         updateModelPanelVisibility();
     } catch (error) {
@@ -283,7 +441,7 @@ async function saveConfig() {
     }
 
     try {
-        const response = await fetch('/api/config', {
+        const response = await apiFetch('/api/config', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
@@ -316,13 +474,23 @@ async function saveConfig() {
 // 加载视频列表
 async function loadVideos() {
     try {
-        const response = await fetch('/api/videos');
+        const params = new URLSearchParams();
+        if (assigneeFilter.value) {
+            params.set('assignee', assigneeFilter.value);
+        }
+        if (sortSelect.value) {
+            params.set('sort', sortSelect.value);
+        }
+        const response = await apiFetch('/api/videos?' + params.toString());
         const data = await response.json();
         
         // 处理新的响应格式
         if (data.videos) {
             videos = data.videos;
             videoStats = data.stats;
+            assignees = data.assignees || [];
+            updateTaskFilters();
+            renderTaskWarnings(data.task_warnings || [], data.key_warnings || []);
         } else {
             // 兼容旧格式
             videos = data;
@@ -358,6 +526,126 @@ function updateStatsDisplay() {
     statsPanel.style.display = 'block';
 }
 
+// 更新负责人下拉框，任务文件没有分配信息时隐藏
+function updateTaskFilters() {
+    const selected = assigneeFilter.value;
+    assigneeFilter.innerHTML = '<option value="">All assignees</option>';
+    assignees.forEach(name => assigneeFilter.add(new Option(name, name)));
+    assigneeFilter.value = assignees.includes(selected) ? selected : '';
+
+    const hasTasks = assignees.length > 0 || videos.some(v => v.priority !== undefined || v.deadline);
+    document.getElementById('taskFilters').style.display = hasTasks || sortSelect.value ? 'flex' : 'none';
+}
+
+// 显示任务文件中的问题（缺失、重复、大小写不一致的视频名称）和只差扩展名的视频，详情在悬停提示中
+function renderTaskWarnings(warnings, keyWarnings) {
+    const panel = document.getElementById('taskWarnings');
+    const details = [
+        ...warnings.map(w => `Line ${w.line}: ${w.message}`),
+        ...keyWarnings.map(w => w.message)
+    ];
+    if (details.length === 0) {
+        panel.style.display = 'none';
+        return;
+    }
+    const parts = [];
+    if (warnings.length > 0) {
+        parts.push(`${warnings.length} task file problem${warnings.length > 1 ? 's' : ''}`);
+    }
+    if (keyWarnings.length > 0) {
+        parts.push(`${keyWarnings.length} video${keyWarnings.length > 1 ? 's' : ''} sharing a name with another format`);
+    }
+    panel.textContent = `⚠ ${parts.join(', ')} (hover for details)`;
+    panel.title = details.join('\n');
+    panel.style.display = 'block';
+}
+
+// 显示当前视频的任务说明（负责人、截止日期、标注说明）
+function renderTaskNotes(video) {
+    const parts = [];
+    if (video && video.assignee) {
+        parts.push(`Assignee: ${escapeHtml(video.assignee)}`);
+    }
+    if (video && video.deadline) {
+        parts.push(`Deadline: ${escapeHtml(video.deadline)}`);
+    }
+    if (video && video.notes) {
+        parts.push(escapeHtml(video.notes));
+    }
+    taskNotes.innerHTML = parts.join('\n');
+    taskNotes.style.display = parts.length > 0 ? 'block' : 'none';
+}
+
+// 根据列表中的标注状态重新计算统计数量（时长统计保持不变）
+function recountStats() {
+    if (!videoStats) {
+        return;
+    }
+    videoStats.total = videos.length;
+    videoStats.annotated = videos.filter(v => v.has_annotation).length;
+    videoStats.pre_annotated = videos.filter(v => !v.has_annotation && v.has_pre_annotation).length;
+    videoStats.unannotated = videoStats.total - videoStats.annotated - videoStats.pre_annotated;
+}
+
+// 订阅服务器推送的目录变化事件，实时更新列表状态（断线后浏览器会自动重连）
+function subscribeServerEvents() {
+    if (!window.EventSource) {
+        return;
+    }
+    // 重新登录后会再次调用，先关闭旧连接
+    if (eventSource) {
+        eventSource.close();
+    }
+    const source = new EventSource('/api/events');
+    eventSource = source;
+    source.onmessage = (e) => {
+        try {
+            handleServerEvent(JSON.parse(e.data));
+        } catch (error) {
+            console.error('Failed to handle server event:', error);
+        }
+    };
+}
+
+// 处理一条目录变化事件
+function handleServerEvent(event) {
+    // 视频增减：短时间内可能有多条，合并后刷新一次列表
+    if (event.type === 'video_added' || event.type === 'video_removed') {
+        clearTimeout(videoReloadTimer);
+        videoReloadTimer = setTimeout(loadVideos, 500);
+        return;
+    }
+
+    if (event.type === 'video_locked' || event.type === 'video_unlocked') {
+        handleLockEvent(event);
+        return;
+    }
+
+    if (!event.status) {
+        return;
+    }
+    const video = videos.find(v => v.key === event.status.key);
+    if (!video) {
+        return;
+    }
+
+    if (event.source === 'model_annotation') {
+        if (video.rel_path === currentVideo && config && config.model_annotation_dir) {
+            loadModelAnnotation(currentVideo);
+        }
+        return;
+    }
+
+    if (video.has_annotation !== event.status.has_annotation ||
+        video.has_pre_annotation !== event.status.has_pre_annotation) {
+        video.has_annotation = event.status.has_annotation;
+        video.has_pre_annotation = event.status.has_pre_annotation;
+        recountStats();
+        renderVideoList();
+        updateStatsDisplay();
+    }
+}
+
 // 渲染视频列表
 function renderVideoList() {
     if (videos.length === 0) {
@@ -383,17 +671,38 @@ function renderVideoList() {
         if (!video.has_pre_annotation && !video.has_annotation) {
             statusBadges.push('<span class="status-badge none">未标注</span>');
         }
+        if (!video.playable) {
+            statusBadges.push(`<span class="status-badge unplayable" title="Browsers cannot play ${video.container} files; convert to MP4 to annotate">无法播放</span>`);
+        }
+        if (video.priority !== undefined) {
+            statusBadges.push(`<span class="status-badge priority" title="Task priority (lower is more urgent)">P${video.priority}</span>`);
+        }
+        if (video.assignee) {
+            statusBadges.push(`<span class="status-badge assignee">${escapeHtml(video.assignee)}</span>`);
+        }
+        if (video.deadline && !video.has_annotation && video.deadline < new Date().toISOString().slice(0, 10)) {
+            statusBadges.push(`<span class="status-badge overdue" title="Deadline ${escapeHtml(video.deadline)}">逾期</span>`);
+        }
+        if (video.lock && !lockIsMine(video.lock) && new Date(video.lock.expires_at) > new Date()) {
+            statusBadges.push(`<span class="status-badge locked" title="Being edited by ${escapeHtml(lockOwner(video.lock))}">🔒 ${escapeHtml(video.lock.owner_name || video.lock.owner)}</span>`);
+        }
+        if (video.stem_collision) {
+            statusBadges.push(`<span class="status-badge collision" title="Other folders contain a video with the same name; annotations are stored under ${escapeHtml(video.key)}">同名</span>`);
+        }
+        if (video.duration_ms) {
+            statusBadges.push(`<span class="video-duration" title="${video.width || '?'}x${video.height || '?'} ${video.codec || ''}">${formatTimestamp(video.duration_ms / 1000).replace(/\.\d{3}$/, '')}</span>`);
+        }
 
         // 使用视频在原始列表中的索引作为编号
         const videoNumber = String(video.index + 1).padStart(4, '0');
         // 保持当前选中视频的高亮状态
-        const isActive = video.filename === currentVideo;
+        const isActive = video.rel_path === currentVideo;
 
         return `
-            <div class="video-item${isActive ? ' active' : ''}" data-filename="${video.filename}">
+            <div class="video-item${isActive ? ' active' : ''}" data-path="${escapeHtml(video.rel_path)}">
                 <div class="video-item-name">
                     <span class="video-number">#${videoNumber}</span>
-                    <span>${video.filename}</span>
+                    <span>${escapeHtml(video.rel_path)}</span>
                 </div>
                 <div class="video-item-status">${statusBadges.join('')}</div>
             </div>
@@ -403,8 +712,7 @@ function renderVideoList() {
     // 添加点击事件
     document.querySelectorAll('.video-item').forEach(item => {
         item.addEventListener('click', () => {
-            const filename = item.dataset.filename;
-            selectVideo(filename);
+            selectVideo(item.dataset.path);
         });
     });
 }
@@ -415,7 +723,7 @@ function filterVideos() {
     
     let filtered = videos.filter(video => {
         // 搜索筛选
-        if (searchTerm && !video.filename.toLowerCase().includes(searchTerm)) {
+        if (searchTerm && !video.rel_path.toLowerCase().includes(searchTerm)) {
             return false;
         }
         
@@ -444,6 +752,7 @@ async function selectVideo(filename) {
     // 切换视频前，立即保存当前标注（如果有未保存的更改）
     if (currentVideo && currentVideo !== filename) {
         await flushAutoSave();
+        await releaseEditLock();
     }
 
     currentVideo = filename;
@@ -451,23 +760,32 @@ async function selectVideo(filename) {
     // 更新列表选中状态
     document.querySelectorAll('.video-item').forEach(item => {
         item.classList.remove('active');
-        if (item.dataset.filename === filename) {
+        if (item.dataset.path === filename) {
             item.classList.add('active');
         }
     });
 
     // 加载视频到 Video.js 播放器
+    const video = videos.find(v => v.rel_path === filename);
     if (player) {
         player.src({
-            type: 'video/mp4',
-            src: `/api/video/${encodeURIComponent(filename)}`
+            type: (video && video.mime_type) || 'video/mp4',
+            src: `/api/video/${encodePath(filename)}`
         });
         player.load();
     }
     currentVideoName.textContent = filename;
+    if (video && !video.playable) {
+        currentVideoName.textContent += ` (${video.container}: may not play in this browser)`;
+    }
+    renderTaskNotes(video);
 
-    // 加载标注
+    // 关键帧在后台加载，不阻塞标注显示
+    loadKeyframes(filename);
+
+    // 加载标注，并获取编辑锁（多用户模式），被其他人锁定时只读
     await loadAnnotation(filename);
+    await acquireEditLock(filename);
     
     // 加载模型标注（如果已配置）
     if (config && config.model_annotation_dir && config.model_annotation_dir !== '') {
@@ -478,12 +796,19 @@ async function selectVideo(filename) {
 // 加载标注
 async function loadAnnotation(filename) {
     try {
-        const stem = filename.replace(/\.mp4$/, '');
-        const response = await fetch(`/api/annotation/${stem}.txt`);
-        currentAnnotation = await response.json();
+        const stem = videoStem(filename);
+        const response = await apiFetch(`/api/annotation/${stem}.txt`);
+        const data = await response.json();
+        currentETag = response.headers.get('ETag');
+        currentDiagnostics = data.diagnostics || [];
+        delete data.diagnostics;
+        delete data.meta;
+        currentAnnotation = data;
         renderEditor();
     } catch (error) {
         console.error('Failed to load annotation:', error);
+        currentETag = null;
+        currentDiagnostics = [];
         currentAnnotation = {
             title: '',
             is_tutorial: true,
@@ -491,11 +816,30 @@ async function loadAnnotation(filename) {
         };
         renderEditor();
     }
+    renderDiagnostics();
     // 重置自动保存状态，加载后的数据视为已保存
     lastSavedAnnotationJSON = JSON.stringify(currentAnnotation);
     updateAutoSaveStatus('');
 }
 
+// 渲染解析诊断信息（被拒绝的预标注行等）
+function renderDiagnostics() {
+    const panel = document.getElementById('parseDiagnostics');
+    if (!currentDiagnostics || currentDiagnostics.length === 0) {
+        panel.style.display = 'none';
+        panel.innerHTML = '';
+        return;
+    }
+
+    let html = '<div class="parse-diagnostics-title">Some lines could not be loaded:</div><ul>';
+    currentDiagnostics.forEach(d => {
+        html += `<li class="${escapeHtml(d.severity)}">Line ${d.line}:${d.column} ${escapeHtml(d.reason)}<code>${escapeHtml(d.text)}</code></li>`;
+    });
+    html += '</ul>';
+    panel.innerHTML = html;
+    panel.style.display = 'block';
+}
+
 // 渲染编辑器
 function renderEditor() {
     if (!currentAnnotation) {
@@ -539,7 +883,7 @@ function renderEditor() {
 
 // 添加步骤
 function addStep() {
-    if (!currentAnnotation || !currentAnnotation.is_tutorial) {
+    if (!currentAnnotation || !currentAnnotation.is_tutorial || editLockedBy) {
         return;
     }
 
@@ -559,6 +903,14 @@ function addStep() {
 }
 
 // 添加步骤元素
+// 步骤时间戳输入框的提示：帧序号和结束时间（如有）
+function stepTimestampTitle(step) {
+    const info = [];
+    if (step.frame !== undefined) info.push(`Frame ${step.frame}`);
+    if (step.end) info.push(`${info.length ? 'ends' : 'Ends'} at ${step.end}`);
+    return info.length ? `${info.join(', ')} - click to seek to this time` : 'Click to seek to this time';
+}
+
 // 选中步骤
 function selectStep(index) {
     selectedStepIndex = index;
@@ -583,7 +935,7 @@ function addStepElement(step, index) {
             <span class="step-drag-handle" title="Drag to reorder">⋮⋮</span>
             <span class="step-number">Step ${step.number}</span>
             <input type="text" class="step-timestamp clickable" value="${step.timestamp}" 
-                   placeholder="00:00.000" data-index="${index}" title="Click to seek to this time">
+                   placeholder="00:00.000" data-index="${index}" title="${stepTimestampTitle(step)}">
         </div>
         <textarea class="step-description" data-index="${index}" 
                   placeholder="Enter step description...">${step.description || ''}</textarea>
@@ -617,8 +969,15 @@ function addStepElement(step, index) {
     
     timestampInput.addEventListener('change', (e) => {
         const idx = parseInt(e.target.dataset.index);
-        if (currentAnnotation.steps[idx]) {
-            currentAnnotation.steps[idx].timestamp = e.target.value;
+        const step = currentAnnotation.steps[idx];
+        if (step) {
+            step.timestamp = e.target.value;
+            delete step.frame; // 时间已修改，帧序号失效
+            // 结束时间不在界面中编辑，新的开始时间不早于它时去掉，否则无法保存
+            if (step.end && parseTimestamp(step.end) <= parseTimestamp(step.timestamp)) {
+                delete step.end;
+            }
+            e.target.title = stepTimestampTitle(step);
         }
         scheduleAutoSave();
     });
@@ -628,6 +987,7 @@ function addStepElement(step, index) {
         const idx = parseInt(e.target.dataset.index);
         if (currentAnnotation.steps[idx]) {
             currentAnnotation.steps[idx].timestamp = e.target.value;
+            delete currentAnnotation.steps[idx].frame; // 时间已修改，帧序号失效
         }
         scheduleAutoSave();
     });
@@ -763,6 +1123,11 @@ async function saveAnnotation() {
         return;
     }
 
+    if (editLockedBy) {
+        alert(`This video is being edited by ${lockOwner(editLockedBy)}`);
+        return;
+    }
+
     // 更新标注数据
     if (currentAnnotation.is_tutorial) {
         currentAnnotation.title = tutorialTitle.value.trim();
@@ -780,9 +1145,9 @@ async function saveAnnotation() {
         }
         // 验证时间戳格式
         for (const step of currentAnnotation.steps) {
-            // 支持两种格式：mm:ss.SSS 或 mm:ss
-            if (!/^\d{2}:\d{2}(\.\d{3})?$/.test(step.timestamp)) {
-                alert(`Step ${step.number} has invalid timestamp format. Should be mm:ss.SSS (e.g., 12:32.766)`);
+            // 支持格式：mm:ss(.SSS) 或 hh:mm:ss(.SSS)
+            if (!TIMESTAMP_PATTERN.test(step.timestamp)) {
+                alert(`Step ${step.number} has invalid timestamp format. Should be mm:ss.SSS or hh:mm:ss.SSS (e.g., 12:32.766)`);
                 return;
             }
             if (!step.description.trim()) {
@@ -793,22 +1158,37 @@ async function saveAnnotation() {
     }
 
     try {
-        const stem = currentVideo.replace(/\.mp4$/, '');
-        const response = await fetch(`/api/annotation/${stem}.txt`, {
+        const stem = videoStem(currentVideo);
+        const response = await apiFetch(`/api/annotation/${stem}.txt`, {
             method: 'POST',
-            headers: {
+            headers: annotationHeaders({
                 'Content-Type': 'application/json'
-            },
+            }),
             body: JSON.stringify(currentAnnotation)
         });
 
+        if (response.status === 409) {
+            updateAutoSaveStatus('conflict');
+            showConflictDialog(await response.json(), 'save');
+            return;
+        }
+        if (response.status === 423) {
+            updateAutoSaveStatus('error');
+            setEditLockedBy((await response.json()).lock);
+            alert(`This video is being edited by ${lockOwner(editLockedBy)}`);
+            return;
+        }
         if (response.ok) {
+            const data = await response.json();
+            currentETag = response.headers.get('ETag');
+            applySavedTimestamps(data.annotation);
             lastSavedAnnotationJSON = JSON.stringify(currentAnnotation);
             updateAutoSaveStatus('saved');
+            showValidationReport(data.report);
             alert('Saved successfully');
             loadVideos(); // 刷新列表状态
         } else {
-            const error = await response.text();
+            const error = await readSaveError(response);
             alert('Failed to save: ' + error);
         }
     } catch (error) {
@@ -817,6 +1197,74 @@ async function saveAnnotation() {
     }
 }
 
+// 采用服务器返回的步骤时间（开启 snap_to_frames 时已对齐到帧），只更新时间和帧序号
+function applySavedTimestamps(saved) {
+    if (!saved || !saved.steps || !currentAnnotation.steps) return;
+
+    saved.steps.forEach((savedStep, idx) => {
+        const step = currentAnnotation.steps[idx];
+        if (!step) return;
+        step.timestamp = savedStep.timestamp;
+        if (savedStep.end) step.end = savedStep.end;
+        if (savedStep.frame !== undefined) step.frame = savedStep.frame;
+
+        const input = document.querySelector(`.step-timestamp[data-index="${idx}"]`);
+        if (input && document.activeElement !== input) {
+            input.value = savedStep.timestamp;
+            input.title = stepTimestampTitle(savedStep);
+        }
+    });
+}
+
+// 读取保存失败的响应；校验失败时返回汇总信息，highlight 为 true 时在编辑器中标出所有问题行
+// （回滚失败时报告针对的是历史修订，与编辑器中的步骤不对应，不标出）
+async function readSaveError(response, highlight = true) {
+    const contentType = response.headers.get('Content-Type') || '';
+    if (!contentType.includes('application/json')) {
+        return await response.text();
+    }
+
+    const data = await response.json();
+    if (!data.report) {
+        return data.error || JSON.stringify(data);
+    }
+
+    if (highlight) {
+        showValidationReport(data.report);
+    }
+    const messages = data.report.errors.map(issue =>
+        issue.index >= 0 ? `Step ${issue.index + 1}: ${issue.message}` : issue.message
+    );
+    return messages.join('\n');
+}
+
+// 在编辑器中高亮校验报告中的问题（report 为 null 时清除高亮）
+function showValidationReport(report) {
+    const stepItems = stepsContainer.querySelectorAll('.step-item');
+    stepItems.forEach(item => {
+        item.classList.remove('invalid', 'warning');
+        item.removeAttribute('data-issues');
+    });
+    tutorialTitle.classList.remove('invalid');
+
+    if (!report) return;
+
+    const issues = [...report.errors, ...(report.warnings || [])];
+    issues.forEach(issue => {
+        if (issue.index < 0) {
+            if (issue.field === 'title' && issue.severity === 'error') {
+                tutorialTitle.classList.add('invalid');
+            }
+            return;
+        }
+        const item = stepItems[issue.index];
+        if (!item) return;
+        item.classList.add(issue.severity === 'error' ? 'invalid' : 'warning');
+        const previous = item.getAttribute('data-issues');
+        item.setAttribute('data-issues', previous ? `${previous}\n${issue.message}` : issue.message);
+    });
+}
+
 // 删除标注
 async function deleteAnnotation() {
     if (!currentVideo) {
@@ -824,17 +1272,36 @@ async function deleteAnnotation() {
         return;
     }
 
+    if (editLockedBy) {
+        alert(`This video is being edited by ${lockOwner(editLockedBy)}`);
+        return;
+    }
+
     // 确认删除
     if (!confirm('Are you sure you want to delete the saved annotation?\n\nPre-annotation will be reloaded if available.')) {
         return;
     }
+    await performDelete();
+}
 
+// 执行删除（已确认）
+async function performDelete() {
     try {
-        const stem = currentVideo.replace(/\.mp4$/, '');
-        const response = await fetch(`/api/annotation/${stem}.txt`, {
-            method: 'DELETE'
+        const stem = videoStem(currentVideo);
+        const response = await apiFetch(`/api/annotation/${stem}.txt`, {
+            method: 'DELETE',
+            headers: annotationHeaders({})
         });
 
+        if (response.status === 409) {
+            showConflictDialog(await response.json(), 'delete');
+            return;
+        }
+        if (response.status === 423) {
+            setEditLockedBy((await response.json()).lock);
+            alert(`This video is being edited by ${lockOwner(editLockedBy)}`);
+            return;
+        }
         if (response.ok) {
             alert('Annotation deleted');
             // 重新加载标注（会自动从预标注加载）
@@ -851,44 +1318,242 @@ async function deleteAnnotation() {
     }
 }
 
+// 保存和删除请求头：带上加载时的版本号，服务器上的标注已被他人修改时返回 409
+function annotationHeaders(headers) {
+    if (currentETag) {
+        headers['If-Match'] = currentETag;
+    }
+    return headers;
+}
+
+// 显示编辑冲突对话框：data 为 409 响应（current 为服务器上的当前标注，已被删除时为 null），action 为 save 或 delete
+function showConflictDialog(data, action) {
+    pendingConflict = { data, action };
+    const current = data.current;
+    let message = 'This annotation was deleted on the server after you opened it.';
+    if (current) {
+        const meta = current.meta;
+        const who = meta && meta.annotator
+            ? `${meta.annotator} (${new Date(meta.updated_at).toLocaleString()})`
+            : 'someone else';
+        message = `This annotation was changed by ${who} after you opened it.`;
+    }
+    document.getElementById('conflictMessage').textContent = message;
+    document.getElementById('conflictMineBtn').textContent = action === 'delete' ? 'Delete anyway' : 'Overwrite with mine';
+    renderConflictDiff(action === 'delete' ? null : currentAnnotation, current);
+    conflictModal.style.display = 'block';
+}
+
+// 左右对比自己的版本和服务器版本，逐行标出不同之处
+function renderConflictDiff(mine, theirs) {
+    const mineLines = conflictLines(mine, '(deleted by you)');
+    const theirLines = conflictLines(theirs, '(deleted)');
+    const column = (title, lines, other) => {
+        let html = `<div class="conflict-column"><h3>${title}</h3>`;
+        lines.forEach((line, i) => {
+            const changed = line !== other[i] ? ' changed' : '';
+            html += `<div class="conflict-line${changed}">${escapeHtml(line)}</div>`;
+        });
+        return html + '</div>';
+    };
+    document.getElementById('conflictDiff').innerHTML =
+        column('Yours', mineLines, theirLines) + column('Server', theirLines, mineLines);
+}
+
+// 将标注转换为对比用的文本行（标题和每个步骤一行）
+function conflictLines(ann, emptyText) {
+    if (!ann) return [emptyText];
+    if (!ann.is_tutorial) return ['(non-tutorial video)'];
+    const lines = [ann.title || '(no title)'];
+    (ann.steps || []).forEach(step => {
+        const time = step.end ? `${step.timestamp}-${step.end}` : step.timestamp;
+        lines.push(`${step.number}) ${time} ${step.description}`);
+    });
+    return lines;
+}
+
+// 处理编辑冲突：theirs 加载服务器版本，mine 以服务器当前版本为基础重新保存或删除，cancel 暂不处理
+async function resolveConflict(choice) {
+    const conflict = pendingConflict;
+    conflictModal.style.display = 'none';
+    pendingConflict = null;
+    if (!conflict || choice === 'cancel') {
+        return;
+    }
+
+    if (choice === 'theirs') {
+        await loadAnnotation(currentVideo);
+        loadVideos();
+        return;
+    }
+
+    currentETag = conflict.data.etag;
+    if (conflict.action === 'delete') {
+        await performDelete();
+    } else {
+        await saveAnnotation();
+    }
+}
+
+// 显示当前标注的修订历史（先保存未保存的修改，使其也出现在历史中）
+async function showHistory() {
+    if (!currentVideo) {
+        alert('Please select a video first');
+        return;
+    }
+    await flushAutoSave();
+
+    selectedRevision = null;
+    historyRevertBtn.disabled = true;
+    document.getElementById('historyPreview').innerHTML = '';
+    const list = document.getElementById('historyList');
+    list.innerHTML = '<div class="loading">Loading...</div>';
+    historyModal.style.display = 'block';
+    try {
+        const response = await apiFetch(`/api/annotation/${videoStem(currentVideo)}/history`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        renderHistoryList((await response.json()).revisions);
+    } catch (error) {
+        list.innerHTML = `<div class="loading">Failed to load history: ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+// 渲染修订列表（最新的在前）
+function renderHistoryList(revisions) {
+    const list = document.getElementById('historyList');
+    if (revisions.length === 0) {
+        list.innerHTML = '<div class="loading">No revisions yet</div>';
+        return;
+    }
+    list.innerHTML = revisions.map(r => {
+        let action = 'saved';
+        if (r.action === 'delete') {
+            action = 'deleted';
+        } else if (r.action === 'revert') {
+            action = `restored #${r.reverted_from}`;
+        }
+        let summary = '';
+        if (r.action !== 'delete') {
+            summary = r.is_tutorial ? ` · ${escapeHtml(r.title || '(no title)')} · ${r.steps} steps` : ' · Non-tutorial video';
+        }
+        return `
+            <div class="history-item" data-rev="${r.rev}">
+                <div><strong>#${r.rev}</strong> ${action}${r.annotator ? ' by ' + escapeHtml(r.annotator) : ''}</div>
+                <div class="history-item-meta">${new Date(r.time).toLocaleString()}${summary}</div>
+            </div>
+        `;
+    }).join('');
+    list.querySelectorAll('.history-item').forEach(item => {
+        item.addEventListener('click', () => previewRevision(Number(item.dataset.rev)));
+    });
+}
+
+// 显示选中修订的内容
+async function previewRevision(rev) {
+    selectedRevision = rev;
+    historyRevertBtn.disabled = true;
+    document.querySelectorAll('.history-item').forEach(item => {
+        item.classList.toggle('active', Number(item.dataset.rev) === rev);
+    });
+    const preview = document.getElementById('historyPreview');
+    try {
+        const response = await apiFetch(`/api/annotation/${videoStem(currentVideo)}/history?rev=${rev}`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const data = await response.json();
+        if (selectedRevision !== rev) {
+            return; // 加载期间已选中其他修订
+        }
+        preview.innerHTML = conflictLines(data.annotation || null, '(annotation deleted)')
+            .map(line => `<div class="conflict-line">${escapeHtml(line)}</div>`)
+            .join('');
+        // 删除操作的修订没有内容，不能恢复；只读时也不能恢复
+        historyRevertBtn.disabled = !data.annotation || !!editLockedBy;
+    } catch (error) {
+        preview.innerHTML = `<div class="loading">Failed to load revision: ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+// 将标注恢复为选中的修订；当前内容仍保留在历史中，可以再次恢复
+async function revertToRevision() {
+    const rev = selectedRevision;
+    if (!rev || !confirm(`Restore revision #${rev}?\n\nThe current annotation stays in the history.`)) {
+        return;
+    }
+    try {
+        const response = await apiFetch(`/api/annotation/${videoStem(currentVideo)}/revert?rev=${rev}`, {
+            method: 'POST',
+            headers: annotationHeaders({})
+        });
+        if (response.status === 409) {
+            alert('The annotation was changed by someone else in the meantime. It will be reloaded; open the history again to restore.');
+            historyModal.style.display = 'none';
+            await loadAnnotation(currentVideo);
+            return;
+        }
+        if (response.status === 423) {
+            setEditLockedBy((await response.json()).lock);
+            alert(`This video is being edited by ${lockOwner(editLockedBy)}`);
+            return;
+        }
+        if (!response.ok) {
+            alert('Failed to restore: ' + await readSaveError(response, false));
+            return;
+        }
+        historyModal.style.display = 'none';
+        await loadAnnotation(currentVideo);
+        loadVideos();
+    } catch (error) {
+        console.error('Failed to restore revision:', error);
+        alert('Failed to restore: ' + error.message);
+    }
+}
+
 // 更新时间显示
 function updateTimeDisplay() {
     if (!player) return;
     currentTime.textContent = formatTimestamp(player.currentTime());
 }
 
-// 格式化时间为 mm:ss.SSS
+// 格式化时间为 mm:ss.SSS（超过一小时为 hh:mm:ss.SSS）
 // 使用 Math.round 转为整数毫秒后再拆分，避免浮点精度丢失导致的1ms偏差
 function formatTimestamp(timeInSeconds) {
     const totalMs = Math.round(timeInSeconds * 1000);
-    const minutes = Math.floor(totalMs / 60000);
+    const hours = Math.floor(totalMs / 3600000);
+    const minutes = Math.floor((totalMs % 3600000) / 60000);
     const seconds = Math.floor((totalMs % 60000) / 1000);
     const millis = totalMs % 1000;
-    return `${String(minutes).padStart(2, '0')}:${String(seconds).padStart(2, '0')}.${String(millis).padStart(3, '0')}`;
+    const mmss = `${String(minutes).padStart(2, '0')}:${String(seconds).padStart(2, '0')}.${String(millis).padStart(3, '0')}`;
+    // 不足一小时保持 mm:ss.SSS，与后端格式一致
+    return hours > 0 ? `${String(hours).padStart(2, '0')}:${mmss}` : mmss;
 }
 
 // 解析时间戳为秒数
 function parseTimestamp(timestamp) {
-    // 支持两种格式：mm:ss.SSS 和 mm:ss
+    // 支持格式：mm:ss(.SSS) 和 hh:mm:ss(.SSS)
     const parts = timestamp.split(':');
-    if (parts.length !== 2) return 0;
+    if (parts.length !== 2 && parts.length !== 3) return 0;
     
-    const minutes = parseInt(parts[0], 10);
-    if (isNaN(minutes)) return 0;
+    const hours = parts.length === 3 ? parseInt(parts[0], 10) : 0;
+    const minutes = parseInt(parts[parts.length - 2], 10);
+    if (isNaN(hours) || isNaN(minutes)) return 0;
     
     // 解析秒和毫秒
-    const secondsParts = parts[1].split('.');
+    const secondsParts = parts[parts.length - 1].split('.');
     const seconds = parseInt(secondsParts[0], 10);
     const millis = secondsParts.length > 1 ? parseInt(secondsParts[1].padEnd(3, '0'), 10) : 0;
     
     if (isNaN(seconds) || isNaN(millis)) return 0;
     
-    return minutes * 60 + seconds + millis / 1000;
+    return hours * 3600 + minutes * 60 + seconds + millis / 1000;
 }
 
 // 插入当前时间戳
 function insertCurrentTimestamp() {
-    if (!player || !currentAnnotation || !currentAnnotation.is_tutorial) {
+    if (!player || !currentAnnotation || !currentAnnotation.is_tutorial || editLockedBy) {
         return;
     }
 
@@ -947,6 +1612,51 @@ function insertCurrentTimestamp() {
     scheduleAutoSave();
 }
 
+// 返回视频对应的标注 key（相对路径，不含扩展名），已按路径段编码，可直接用于 URL
+function videoStem(filename) {
+    const video = videos.find(v => v.rel_path === filename);
+    return encodePath(video ? video.key : filename.replace(/\.[^.]+$/, ''));
+}
+
+// 按路径段编码，保留 / 分隔符
+function encodePath(path) {
+    return path.split('/').map(encodeURIComponent).join('/');
+}
+
+// 加载当前视频的关键帧
+async function loadKeyframes(filename) {
+    currentKeyframes = [];
+    try {
+        const response = await apiFetch(`/api/video/${encodePath(filename)}/keyframes`);
+        if (!response.ok) return;
+        const data = await response.json();
+        if (currentVideo === filename) {
+            currentKeyframes = data.keyframes_us.map(us => us / 1e6);
+        }
+    } catch (error) {
+        console.error('Failed to load keyframes:', error);
+    }
+}
+
+// 跳到上一个（direction = -1）或下一个（direction = 1）关键帧
+function seekToKeyframe(direction) {
+    if (!player || currentKeyframes.length === 0) return;
+
+    // 留出少量余量，避免停在当前关键帧上时重复跳到同一位置
+    const now = player.currentTime();
+    const epsilon = 0.001;
+    let target;
+    if (direction > 0) {
+        target = currentKeyframes.find(t => t > now + epsilon);
+    } else {
+        target = [...currentKeyframes].reverse().find(t => t < now - epsilon);
+    }
+    if (target === undefined) return;
+
+    player.currentTime(target);
+    player.pause();
+}
+
 // 跳转到指定时间戳
 function seekToTimestamp(timestamp) {
     if (!player) return;
@@ -1043,8 +1753,8 @@ function showCopyFeedback() {
 // 加载模型标注
 async function loadModelAnnotation(filename) {
     try {
-        const stem = filename.replace(/\.mp4$/, '');
-        const response = await fetch(`/api/model-annotation/${stem}.txt`);
+        const stem = videoStem(filename);
+        const response = await apiFetch(`/api/model-annotation/${stem}.txt`);
         const data = await response.json();
         
         if (data.available) {
@@ -1217,7 +1927,7 @@ function escapeHtml(text) {
 // Open native file/folder dialog and set the selected path.
 async function openBrowser(inputId, mode) {
     try {
-        const response = await fetch(`/api/dialog?mode=${encodeURIComponent(mode)}`);
+        const response = await apiFetch(`/api/dialog?mode=${encodeURIComponent(mode)}`);
         if (!response.ok) {
             const errorText = await response.text();
             alert(`Failed to open dialog: ${errorText}`);
@@ -1234,11 +1944,144 @@ async function openBrowser(inputId, mode) {
     }
 }
 
+// ========== 编辑锁（多用户模式） ==========
+
+// 获取当前视频的编辑锁；已被其他人持有时编辑器切换为只读
+async function acquireEditLock(filename) {
+    if (authMode === '' || !filename) return;
+    try {
+        const response = await apiFetch(`/api/lock/${videoStem(filename)}`, { method: 'POST' });
+        if (filename !== currentVideo) {
+            return; // 请求期间已切换到其他视频
+        }
+        const data = await response.json();
+        if (response.status === 409) {
+            editLock = null;
+            setEditLockedBy(data.lock);
+            return;
+        }
+        if (!response.ok) {
+            return;
+        }
+        editLock = data.lock;
+        setEditLockedBy(null);
+        scheduleLockRenewal(data.lease_seconds);
+    } catch (error) {
+        console.error('Failed to acquire edit lock:', error);
+    }
+}
+
+// 按租约的三分之一定期续期；锁已丢失（过期后被他人获取或被管理员解除）时重新获取
+function scheduleLockRenewal(leaseSeconds) {
+    clearInterval(lockRenewTimer);
+    lockRenewTimer = setInterval(async () => {
+        if (!editLock || !currentVideo) return;
+        try {
+            const response = await apiFetch(`/api/lock/${encodePath(editLock.key)}/renew`, { method: 'POST' });
+            if (response.ok) {
+                editLock = (await response.json()).lock;
+            } else if (response.status === 404) {
+                editLock = null;
+                await acquireEditLock(currentVideo);
+            }
+        } catch (error) {
+            console.error('Failed to renew edit lock:', error);
+        }
+    }, Math.max(leaseSeconds / 3, 5) * 1000);
+}
+
+// 释放自己持有的编辑锁；关闭页面时使用 keepalive，请求在页面卸载后仍会发出
+async function releaseEditLock(unloading) {
+    clearInterval(lockRenewTimer);
+    lockRenewTimer = null;
+    const lock = editLock;
+    editLock = null;
+    setEditLockedBy(null);
+    if (!lock) return;
+    try {
+        await fetch(`/api/lock/${encodePath(lock.key)}`, { method: 'DELETE', keepalive: !!unloading });
+    } catch (error) {
+        console.error('Failed to release edit lock:', error);
+    }
+}
+
+// 管理员强制解除其他人的编辑锁，然后自己获取
+async function forceUnlock() {
+    if (!editLockedBy || !confirm(`Force-unlock this video? ${lockOwner(editLockedBy)} will no longer be able to save their changes.`)) {
+        return;
+    }
+    try {
+        const response = await apiFetch(`/api/lock/${encodePath(editLockedBy.key)}?force=1`, { method: 'DELETE' });
+        if (!response.ok) {
+            alert('Failed to unlock: ' + await response.text());
+            return;
+        }
+        await loadAnnotation(currentVideo);
+        await acquireEditLock(currentVideo);
+    } catch (error) {
+        alert('Failed to unlock: ' + error.message);
+    }
+}
+
+// 设置当前视频的锁定状态：lock 为其他人的锁时显示提示并将编辑器设为只读，为 null 时恢复编辑
+function setEditLockedBy(lock) {
+    editLockedBy = lock || null;
+    document.getElementById('editorPanel').classList.toggle('read-only', !!editLockedBy);
+    saveBtn.disabled = !!editLockedBy;
+    deleteAnnotationBtn.disabled = !!editLockedBy;
+    insertTimestampBtn.disabled = !!editLockedBy;
+    if (!editLockedBy) {
+        lockBanner.style.display = 'none';
+        return;
+    }
+    if (autoSaveTimer) {
+        clearTimeout(autoSaveTimer);
+        autoSaveTimer = null;
+    }
+    document.getElementById('lockMessage').textContent =
+        `Read-only: ${lockOwner(editLockedBy)} is editing this video (lock expires ${new Date(editLockedBy.expires_at).toLocaleTimeString()} unless renewed).`;
+    document.getElementById('forceUnlockBtn').style.display = currentUser && currentUser.admin ? '' : 'none';
+    lockBanner.style.display = 'flex';
+}
+
+// 处理其他人获取或释放编辑锁的事件：更新列表标记；当前视频被释放时自动获取锁并重新加载标注
+async function handleLockEvent(event) {
+    const video = videos.find(v => v.key === event.key);
+    if (video) {
+        video.lock = event.type === 'video_locked' ? event.lock : undefined;
+        renderVideoList();
+    }
+
+    const current = videos.find(v => v.rel_path === currentVideo);
+    if (!current || current.key !== event.key) {
+        return;
+    }
+    if (event.type === 'video_locked' && !lockIsMine(event.lock)) {
+        // 自己的锁被解除后被其他人获取
+        clearInterval(lockRenewTimer);
+        editLock = null;
+        setEditLockedBy(event.lock);
+    } else if (event.type === 'video_unlocked' && editLockedBy) {
+        await loadAnnotation(currentVideo);
+        await acquireEditLock(currentVideo);
+    }
+}
+
+// 判断锁是否由当前登录的标注人员持有
+function lockIsMine(lock) {
+    return !!(lock && currentUser && lock.owner === currentUser.id);
+}
+
+// 返回锁持有者的显示名称
+function lockOwner(lock) {
+    return lock.owner_name ? `${lock.owner_name} (${lock.owner})` : lock.owner;
+}
+
 // ========== 自动保存功能 ==========
 
 // 调度自动保存（防抖）
 function scheduleAutoSave() {
-    if (!currentVideo || !currentAnnotation) return;
+    if (!currentVideo || !currentAnnotation || editLockedBy) return;
 
     updateAutoSaveStatus('unsaved');
 
@@ -1272,7 +2115,7 @@ function validateAnnotationSilently() {
     if (!currentAnnotation.steps || currentAnnotation.steps.length === 0) return false;
 
     for (const step of currentAnnotation.steps) {
-        if (!/^\d{2}:\d{2}(\.\d{3})?$/.test(step.timestamp)) return false;
+        if (!TIMESTAMP_PATTERN.test(step.timestamp)) return false;
         if (!step.description.trim()) return false;
     }
 
@@ -1281,7 +2124,7 @@ function validateAnnotationSilently() {
 
 // 执行自动保存
 async function performAutoSave() {
-    if (!currentVideo || !currentAnnotation) return;
+    if (!currentVideo || !currentAnnotation || pendingConflict || editLockedBy) return;
 
     syncAnnotationFromForm();
 
@@ -1298,18 +2141,38 @@ async function performAutoSave() {
     updateAutoSaveStatus('saving');
 
     try {
-        const stem = currentVideo.replace(/\.mp4$/, '');
-        const response = await fetch(`/api/annotation/${stem}.txt`, {
+        const stem = videoStem(currentVideo);
+        const response = await apiFetch(`/api/annotation/${stem}.txt`, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: annotationHeaders({ 'Content-Type': 'application/json' }),
             body: annotationJSON
         });
 
+        if (response.status === 409) {
+            updateAutoSaveStatus('conflict');
+            showConflictDialog(await response.json(), 'save');
+            return;
+        }
+        if (response.status === 423) {
+            updateAutoSaveStatus('error');
+            setEditLockedBy((await response.json()).lock);
+            return;
+        }
         if (response.ok) {
-            lastSavedAnnotationJSON = annotationJSON;
+            const data = await response.json();
+            currentETag = response.headers.get('ETag');
+            // 保存期间未继续编辑时，采用服务器对齐到帧后的时间
+            if (JSON.stringify(currentAnnotation) === annotationJSON) {
+                applySavedTimestamps(data.annotation);
+                lastSavedAnnotationJSON = JSON.stringify(currentAnnotation);
+            } else {
+                lastSavedAnnotationJSON = annotationJSON;
+            }
             updateAutoSaveStatus('saved');
+            showValidationReport(data.report); // 保存成功时仍标出警告
             loadVideos(); // 刷新列表状态，更新"已标注"标签
         } else {
+            await readSaveError(response);
             updateAutoSaveStatus('error');
         }
     } catch (error) {
@@ -1347,6 +2210,9 @@ function updateAutoSaveStatus(status) {
         case 'error':
             statusEl.textContent = '✕ Save failed';
             break;
+        case 'conflict':
+            statusEl.textContent = '⚠ Conflict';
+            break;
         default:
             statusEl.textContent = '';
     }